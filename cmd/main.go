@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/app"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
 	"log"
 	"os"
@@ -34,11 +35,33 @@ func main() {
 
 	port := fmt.Sprintf(":%v", common.Envs.Port)
 
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	store := common.NewStore(db)
-	api := app.NewAPIServer(port, store)
+	api := app.NewAPIServer(port, store, blobs)
 	err = api.Serve(ctx)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 }
+
+func newBlobStore() (blob.Store, error) {
+	switch common.Envs.BlobBackend {
+	case "fs":
+		return blob.NewFSStore(common.Envs.BlobDir)
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:  common.Envs.S3Endpoint,
+			Bucket:    common.Envs.S3Bucket,
+			Region:    common.Envs.S3Region,
+			AccessKey: common.Envs.S3AccessKey,
+			SecretKey: common.Envs.S3SecretKey,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", common.Envs.BlobBackend)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultActivityPage = 50
+	maxActivityPage     = 200
+)
+
+var errInvalidBefore = errors.New("Invalid 'before' parameter")
+
+// ActivityPage is one page of a task's activity feed, newest first.
+// NextBefore is set when older entries may follow and is passed back as
+// ?before=.
+type ActivityPage struct {
+	Activity   []*common.Activity `json:"activity"`
+	NextBefore *int64             `json:"next_before,omitempty"`
+}
+
+type ActivityService struct {
+	store common.Store
+}
+
+func NewActivityService(store common.Store) *ActivityService {
+	return &ActivityService{store: store}
+}
+
+func (s *ActivityService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/activity", auth.WithJWTAuth(s.handleGetActivity, s.store))
+}
+
+func (s *ActivityService) handleGetActivity(w http.ResponseWriter, r *http.Request) {
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, limit, err := pageParams(r, defaultActivityPage, maxActivityPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	before := 0
+	if v := r.URL.Query().Get("before"); v != "" {
+		before, err = strconv.Atoi(v)
+		if err != nil || before < 0 {
+			http.Error(w, errInvalidBefore.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	entries, err := s.store.GetTaskActivity(taskID, before, limit)
+	if err != nil {
+		http.Error(w, "Error getting activity", http.StatusInternalServerError)
+		return
+	}
+
+	page := ActivityPage{Activity: entries}
+	if len(entries) == limit {
+		page.NextBefore = &entries[len(entries)-1].ID
+	}
+	utils.WriteJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetActivityPage(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewActivityService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("GetTaskActivity", 1, 40, 2).
+		Return([]*common.Activity{{ID: 39, TaskID: 1}, {ID: 35, TaskID: 1}}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/activity?before=40&limit=2", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetActivity(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"next_before":35`)
+}
+
+func TestUpdateTaskClearsDueDate(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+	name := "Fix login timeout"
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("UpdateTask", 1, common.TaskPatch{Name: &name, ClearDueAt: true}, 3).
+		Return(&common.Task{ID: 1, Name: name}, nil)
+
+	req := authorizedRequest(http.MethodPatch, "/tasks/1", []byte(`{"name": "Fix login timeout", "due_at": null}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateTask(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestUpdateTaskRejectsInvalidPriority(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	req := authorizedRequest(http.MethodPatch, "/tasks/1", []byte(`{"priority": "P9"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateTask", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,19 +2,23 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type APIServer struct {
 	address string
 	store   common.Store
+	blobs   blob.Store
 }
 
-func NewAPIServer(address string, store common.Store) *APIServer {
-	return &APIServer{address: address, store: store}
+func NewAPIServer(address string, store common.Store, blobs blob.Store) *APIServer {
+	return &APIServer{address: address, store: store, blobs: blobs}
 }
 
 func (s *APIServer) Serve(ctx context.Context) error {
@@ -27,6 +31,89 @@ func (s *APIServer) Serve(ctx context.Context) error {
 	tasksService := NewTaskService(s.store)
 	tasksService.RegisterRoutes(router)
 
+	trashService := NewTrashService(s.store)
+	trashService.RegisterRoutes(router)
+
+	archiveService := NewArchiveService(s.store, s.blobs)
+	archiveService.RegisterRoutes(router)
+
+	bulkService := NewBulkService(s.store)
+	bulkService.RegisterRoutes(router)
+
+	projectsService := NewProjectsService(s.store)
+	projectsService.RegisterRoutes(router)
+
+	boardsService := NewBoardsService(s.store)
+	boardsService.RegisterRoutes(router)
+
+	sprintsService := NewSprintsService(s.store)
+	sprintsService.RegisterRoutes(router)
+
+	milestonesService := NewMilestonesService(s.store)
+	milestonesService.RegisterRoutes(router)
+
+	fieldsService := NewFieldsService(s.store)
+	fieldsService.RegisterRoutes(router)
+
+	subtasksService := NewSubtasksService(s.store)
+	subtasksService.RegisterRoutes(router)
+
+	dependenciesService := NewDependenciesService(s.store)
+	dependenciesService.RegisterRoutes(router)
+
+	assigneesService := NewAssigneesService(s.store)
+	assigneesService.RegisterRoutes(router)
+
+	checklistsService := NewChecklistsService(s.store)
+	checklistsService.RegisterRoutes(router)
+
+	templatesService := NewTemplatesService(s.store)
+	templatesService.RegisterRoutes(router)
+
+	recurrenceService := NewRecurrenceService(s.store)
+	recurrenceService.RegisterRoutes(router)
+
+	remindersService := NewRemindersService(s.store)
+	remindersService.RegisterRoutes(router)
+
+	worklogsService := NewWorklogsService(s.store)
+	worklogsService.RegisterRoutes(router)
+
+	searchService := NewSearchService(s.store)
+	searchService.RegisterRoutes(router)
+
+	teamsService := NewTeamsService(s.store)
+	teamsService.RegisterRoutes(router)
+
+	viewsService := NewViewsService(s.store)
+	viewsService.RegisterRoutes(router)
+
+	workspacesService := NewWorkspacesService(s.store)
+	workspacesService.RegisterRoutes(router)
+
+	labelsService := NewLabelsService(s.store)
+	labelsService.RegisterRoutes(router)
+
+	commentsService := NewCommentsService(s.store)
+	commentsService.RegisterRoutes(router)
+
+	activityService := NewActivityService(s.store)
+	activityService.RegisterRoutes(router)
+
+	mentionsService := NewMentionsService(s.store)
+	mentionsService.RegisterRoutes(router)
+
+	attachmentsService := NewAttachmentsService(s.store, s.blobs)
+	attachmentsService.RegisterRoutes(router)
+
+	cloneService := NewCloneService(s.store, s.blobs)
+	cloneService.RegisterRoutes(router)
+
+	go NewRecurrenceScheduler(s.store).Run(ctx)
+	go NewJobScheduler(s.store).Run(ctx)
+	go NewTrashPurger(s.store, s.blobs).Run(ctx)
+	go NewArchiver(s.store).Run(ctx)
+
 	server := &http.Server{
 		Addr:    s.address,
 		Handler: router,
@@ -53,3 +140,17 @@ func (s *APIServer) Serve(ctx context.Context) error {
 		return server.Shutdown(timeout)
 	}
 }
+
+// pathID reads a numeric path parameter such as {id}.
+func pathID(r *http.Request, name string) (int, error) {
+	value := r.PathValue(name)
+	if value == "" {
+		return 0, fmt.Errorf("Missing '%s' parameter", name)
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Invalid '%s' parameter", name)
+	}
+	return id, nil
+}
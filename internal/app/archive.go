@@ -0,0 +1,209 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ArchiveService struct {
+	store common.Store
+	blobs blob.Store
+}
+
+func NewArchiveService(store common.Store, blobs blob.Store) *ArchiveService {
+	return &ArchiveService{store: store, blobs: blobs}
+}
+
+func (s *ArchiveService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST /tasks/{id}/archive", auth.WithJWTAuth(s.handleArchive, s.store))
+	router.HandleFunc("POST /tasks/{id}/unarchive", auth.WithJWTAuth(s.handleUnarchive, s.store))
+	router.HandleFunc("GET /workspaces/{id}/archive", auth.WithJWTAuth(s.handleExport, s.store))
+	router.HandleFunc("GET /projects/{id}/archive", auth.WithJWTAuth(s.handleExportProject, s.store))
+	router.HandleFunc("DELETE /projects/{id}/archive", auth.WithJWTAuth(s.handlePurgeProject, s.store))
+}
+
+func (s *ArchiveService) handleArchive(w http.ResponseWriter, r *http.Request) {
+	s.setArchived(w, r, s.store.ArchiveTask)
+}
+
+func (s *ArchiveService) handleUnarchive(w http.ResponseWriter, r *http.Request) {
+	s.setArchived(w, r, s.store.UnarchiveTask)
+}
+
+func (s *ArchiveService) setArchived(w http.ResponseWriter, r *http.Request, apply func(id, actorID int) (*common.Task, error)) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err = apply(id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrTaskNotDone) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error archiving task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+// handleExport downloads the workspace's archived tasks as a JSON array or,
+// with ?format=csv, as CSV.
+func (s *ArchiveService) handleExport(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	export(w, r, fmt.Sprintf("workspace-%d-archive", workspaceID), func(fn func(*common.Task) error) error {
+		return s.store.ExportArchive(workspaceID, fn)
+	})
+}
+
+// handleExportProject downloads the project's archived tasks like
+// handleExport.
+func (s *ArchiveService) handleExportProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	export(w, r, fmt.Sprintf("%s-archive", project.Key), func(fn func(*common.Task) error) error {
+		return s.store.ExportProjectArchive(int(project.ID), fn)
+	})
+}
+
+// handlePurgeProject deletes the project's archived tasks for good, with
+// their files. Only workspace admins can, and they must repeat the project
+// key in ?confirm= since the tasks cannot be restored afterwards.
+func (s *ArchiveService) handlePurgeProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(project.WorkspaceID), common.WorkspaceRoleAdmin); !ok {
+		return
+	}
+	if r.URL.Query().Get("confirm") != project.Key {
+		http.Error(w, "Confirm the purge by passing the project key as confirm", http.StatusBadRequest)
+		return
+	}
+
+	purged, keys, err := s.store.PurgeArchive(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error purging archive", http.StatusInternalServerError)
+		return
+	}
+	// The rows are gone, so a failure here only leaves an unreachable blob.
+	for _, key := range keys {
+		if err := s.blobs.Delete(r.Context(), key); err != nil {
+			log.Println(err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// export writes the tasks each passes to fn as a JSON array or, with
+// ?format=csv, as CSV. The tasks are written as they are read, so the
+// status cannot change once the export has started; errors after that
+// point are only logged.
+func export(w http.ResponseWriter, r *http.Request, name string, each func(fn func(*common.Task) error) error) {
+	format := r.URL.Query().Get("format")
+	if format != "csv" {
+		format = "json"
+	}
+	filename := fmt.Sprintf("%s.%s", name, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var err error
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		err = exportCSV(w, each)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = exportJSON(w, each)
+	}
+	if err != nil {
+		log.Println("Error exporting archive:", err)
+	}
+}
+
+func exportJSON(w http.ResponseWriter, each func(fn func(*common.Task) error) error) error {
+	enc := json.NewEncoder(w)
+	sep := "["
+	err := each(func(task *common.Task) error {
+		if _, err := w.Write([]byte(sep)); err != nil {
+			return err
+		}
+		sep = ","
+		return enc.Encode(task)
+	})
+	if sep == "[" {
+		w.Write([]byte(sep))
+	}
+	w.Write([]byte("]\n"))
+	return err
+}
+
+func exportCSV(w http.ResponseWriter, each func(fn func(*common.Task) error) error) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "parent_id", "name", "description", "priority", "assigned_to_id", "due_at", "created_at",
+		"done_at", "archived_at"})
+	err := each(func(task *common.Task) error {
+		parentID := ""
+		if task.ParentID != nil {
+			parentID = strconv.FormatInt(*task.ParentID, 10)
+		}
+		return out.Write([]string{
+			strconv.FormatInt(task.ID, 10),
+			parentID,
+			csvSafe(task.Name),
+			csvSafe(task.Description),
+			task.Priority,
+			strconv.FormatInt(task.AssignedToID, 10),
+			csvTime(task.DueAt),
+			csvTime(&task.CreatedAt),
+			csvTime(task.DoneAt),
+			csvTime(task.ArchivedAt),
+		})
+	})
+	out.Flush()
+	return err
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
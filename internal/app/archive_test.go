@@ -0,0 +1,161 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestArchiveOpenTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3, Status: "TODO"}, nil)
+	mockStore.On("ArchiveTask", 1, 3).Return((*common.Task)(nil), common.ErrTaskNotDone)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/archive", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleArchive(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestExportArchiveCSV(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+	archivedAt := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("ExportArchive", 2, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*common.Task) error)
+		fn(&common.Task{ID: 7, Name: "=SUM(A1)", Priority: "P1", AssignedToID: 3, CreatedAt: createdAt, ArchivedAt: &archivedAt})
+	})
+
+	req := authorizedRequest(http.MethodGet, "/workspaces/2/archive?format=csv", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleExport(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,parent_id,name,description,priority,assigned_to_id,due_at,created_at,done_at,archived_at\n"+
+		"7,,'=SUM(A1),,P1,3,,2024-12-01T09:00:00Z,,2025-01-08T12:00:00Z\n", w.Body.String())
+}
+
+func TestExportArchiveJSON(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("ExportArchive", 2, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*common.Task) error)
+		fn(&common.Task{ID: 7})
+		fn(&common.Task{ID: 8})
+	})
+
+	req := authorizedRequest(http.MethodGet, "/workspaces/2/archive", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleExport(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tasks []common.Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 2)
+}
+
+func TestExportProjectArchive(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("ExportProjectArchive", 4, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*common.Task) error)
+		fn(&common.Task{ID: 7})
+	})
+
+	req := authorizedRequest(http.MethodGet, "/projects/4/archive", nil, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleExportProject(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="API-archive.json"`, w.Header().Get("Content-Disposition"))
+	var tasks []common.Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 1)
+}
+
+func TestPurgeProjectArchiveDeletesFiles(t *testing.T) {
+	mockStore := new(MockStore)
+	blobs, err := blob.NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	service := NewArchiveService(mockStore, blobs)
+	content := []byte("release notes")
+	assert.NoError(t, blobs.Put(context.Background(), "tasks/7/abc", bytes.NewReader(content), int64(len(content)), "text/plain"))
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleAdmin}, nil)
+	mockStore.On("PurgeArchive", 4).Return(2, []string{"tasks/7/abc"}, nil)
+
+	req := authorizedRequest(http.MethodDelete, "/projects/4/archive?confirm=API", nil, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handlePurgeProject(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"purged": 2}`, w.Body.String())
+	_, err = blobs.Get(context.Background(), "tasks/7/abc")
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestPurgeProjectArchiveRequiresAdmin(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodDelete, "/projects/4/archive?confirm=API", nil, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handlePurgeProject(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "PurgeArchive", mock.Anything)
+}
+
+func TestPurgeProjectArchiveRequiresConfirmation(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewArchiveService(mockStore, nil)
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleAdmin}, nil)
+
+	for _, url := range []string{"/projects/4/archive", "/projects/4/archive?confirm=WEB"} {
+		req := authorizedRequest(http.MethodDelete, url, nil, 3)
+		req.SetPathValue("id", "4")
+		w := httptest.NewRecorder()
+
+		service.handlePurgeProject(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+	mockStore.AssertNotCalled(t, "PurgeArchive", mock.Anything)
+}
@@ -0,0 +1,217 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+var errAssigneeRequired = errors.New("user_id is required")
+var errAssigneeNotMember = errors.New("assignees must be members of the task's workspace")
+var errUnknownAssignee = errors.New("assignee does not exist")
+var errAssigneeNotFound = errors.New("User is not assigned to this task")
+var errNotWatching = errors.New("You are not watching this task")
+
+type AssigneesService struct {
+	store common.Store
+}
+
+func NewAssigneesService(store common.Store) *AssigneesService {
+	return &AssigneesService{store: store}
+}
+
+func (s *AssigneesService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST /tasks/{id}/assignees", auth.WithJWTAuth(s.handleAddAssignee, s.store))
+	router.HandleFunc("DELETE /tasks/{id}/assignees/{userID}", auth.WithJWTAuth(s.handleRemoveAssignee, s.store))
+	router.HandleFunc("GET /tasks/{id}/watchers", auth.WithJWTAuth(s.handleGetWatchers, s.store))
+	router.HandleFunc("POST /tasks/{id}/watchers", auth.WithJWTAuth(s.handleWatch, s.store))
+	router.HandleFunc("DELETE /tasks/{id}/watchers", auth.WithJWTAuth(s.handleUnwatch, s.store))
+}
+
+func (s *AssigneesService) handleAddAssignee(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.UserID == 0 {
+		http.Error(w, errAssigneeRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+	if err := checkAssignees(s.store, task.WorkspaceID, []int64{int64(payload.UserID)}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actorID, _ := auth.GetUserIDFromRequest(r)
+	if err := s.store.AddTaskAssignee(id, payload.UserID, actorID); err != nil {
+		http.Error(w, "Error assigning task", http.StatusInternalServerError)
+		return
+	}
+
+	task, err = s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+func (s *AssigneesService) handleRemoveAssignee(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	actorID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.RemoveTaskAssignee(id, userID, actorID)
+	switch {
+	case errors.Is(err, common.ErrLastAssignee):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, common.ErrNotFound):
+		http.Error(w, errAssigneeNotFound.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Error unassigning task", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *AssigneesService) handleGetWatchers(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	watchers, err := s.store.GetTaskWatchers(id)
+	if err != nil {
+		http.Error(w, "Error getting watchers", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string][]int64{"user_ids": watchers})
+}
+
+// handleWatch subscribes the caller to notifications about the task.
+func (s *AssigneesService) handleWatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	if err := s.store.AddTaskWatcher(id, userID, userID); err != nil {
+		http.Error(w, "Error watching task", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *AssigneesService) handleUnwatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.store.RemoveTaskWatcher(id, userID, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errNotWatching.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error unwatching task", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkAssignees makes sure every user can be assigned to a task in the
+// workspace. Tasks outside a workspace can be assigned to any user.
+func checkAssignees(store common.Store, workspaceID *int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if workspaceID != nil {
+			if _, err := store.GetWorkspaceMember(int(*workspaceID), int(userID)); err != nil {
+				return errAssigneeNotMember
+			}
+			continue
+		}
+		if _, err := store.GetUserByID(int(userID)); err != nil {
+			return errUnknownAssignee
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddAssigneeOutsideWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewAssigneesService(mockStore)
+	workspaceID := int64(4)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 3).Return(&common.WorkspaceMember{WorkspaceID: 4, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 8).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/assignees", []byte(`{"user_id": 8}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddAssignee(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errAssigneeNotMember.Error())
+	mockStore.AssertNotCalled(t, "AddTaskAssignee", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateTaskAssignedToNonMember(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetWorkspaceMember", 4, 3).Return(&common.WorkspaceMember{WorkspaceID: 4, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 8).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks", []byte(`{"name": "Fix login", "workspace_id": 4, "assigned_to_id": 8}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errAssigneeNotMember.Error())
+	mockStore.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+}
+
+func TestCreateTaskAssignedToUnknownUser(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetUserByID", 99).Return((*common.User)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks", []byte(`{"name": "Fix login", "assigned_to_id": 99}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errUnknownAssignee.Error())
+	mockStore.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+}
+
+func TestRemoveLastAssignee(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewAssigneesService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("RemoveTaskAssignee", 1, 3, 3).Return(common.ErrLastAssignee)
+
+	req := authorizedRequest(http.MethodDelete, "/tasks/1/assignees/3", nil, 3)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userID", "3")
+	w := httptest.NewRecorder()
+
+	service.handleRemoveAssignee(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestWatchAddsCaller(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewAssigneesService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("AddTaskWatcher", 1, 3, 3).Return(nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/watchers", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleWatch(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestNonMemberCannotListWatchers(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewAssigneesService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 4, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/watchers", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetWatchers(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetTaskWatchers", mock.Anything)
+}
@@ -0,0 +1,359 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// multipartOverhead is allowed on top of the file size for part headers
+// and boundaries.
+const multipartOverhead = 64 << 10
+
+var errNotMultipart = errors.New("Expected a multipart/form-data body")
+var errMissingFile = errors.New("Missing 'file' part")
+var errEmptyFile = errors.New("File is empty")
+var errFileTooLarge = errors.New("File is too large")
+var errUnsupportedType = errors.New("File type is not allowed")
+var errAttachmentNotFound = errors.New("Attachment not found")
+var errNotUploader = errors.New("Only the uploader can delete an attachment")
+var errLinkExpired = errors.New("Download link has expired")
+var errInvalidSignature = errors.New("Invalid download link")
+
+type AttachmentsService struct {
+	store        common.Store
+	blobs        blob.Store
+	maxBytes     int64
+	allowedTypes map[string]bool
+	urlSecret    []byte
+	urlTTL       time.Duration
+	now          func() time.Time
+}
+
+func NewAttachmentsService(store common.Store, blobs blob.Store) *AttachmentsService {
+	allowed := make(map[string]bool)
+	for _, t := range common.Envs.AttachmentTypes {
+		allowed[strings.TrimSpace(t)] = true
+	}
+
+	return &AttachmentsService{
+		store:        store,
+		blobs:        blobs,
+		maxBytes:     common.Envs.AttachmentMaxBytes,
+		allowedTypes: allowed,
+		urlSecret:    []byte(common.Envs.AttachmentURLSecret),
+		urlTTL:       common.Envs.AttachmentURLTTL,
+		now:          time.Now,
+	}
+}
+
+func (s *AttachmentsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/attachments", auth.WithJWTAuth(s.handleGetAttachments, s.store))
+	router.HandleFunc("POST /tasks/{id}/attachments", auth.WithJWTAuth(s.handleUploadAttachment, s.store))
+	router.HandleFunc("DELETE /attachments/{id}", auth.WithJWTAuth(s.handleDeleteAttachment, s.store))
+	// Download links are authorized by their signature, so they work in
+	// <img> tags and browsers that do not send the JWT.
+	router.HandleFunc("GET /attachments/{id}/download", s.handleDownloadAttachment)
+}
+
+func (s *AttachmentsService) handleGetAttachments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	attachments, err := s.store.GetAttachments(taskID)
+	if err != nil {
+		http.Error(w, "Error getting attachments", http.StatusInternalServerError)
+		return
+	}
+
+	for _, a := range attachments {
+		a.URL = s.downloadURL(a.ID)
+	}
+	utils.WriteJSON(w, http.StatusOK, attachments)
+}
+
+// handleUploadAttachment reads the "file" part of a multipart body into a
+// temporary file, checks its size and sniffed content type, and stores it.
+// The content type sent by the client is not trusted.
+func (s *AttachmentsService) handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBytes+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, errNotMultipart.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var filename string
+	var tmp *os.File
+	var size int64
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		filename = sanitizeFilename(part.FileName())
+		tmp, err = os.CreateTemp("", "attachment-*")
+		if err != nil {
+			http.Error(w, "Error storing file", http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		size, err = io.Copy(tmp, io.LimitReader(part, s.maxBytes+1))
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		break
+	}
+
+	switch {
+	case tmp == nil:
+		http.Error(w, errMissingFile.Error(), http.StatusBadRequest)
+		return
+	case size == 0:
+		http.Error(w, errEmptyFile.Error(), http.StatusBadRequest)
+		return
+	case size > s.maxBytes:
+		http.Error(w, errFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, _ := tmp.ReadAt(head, 0)
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !s.allowedTypes[mediaType] {
+		http.Error(w, errUnsupportedType.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key := fmt.Sprintf("tasks/%d/%s", taskID, randomToken())
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Error storing file", http.StatusInternalServerError)
+		return
+	}
+	if err := s.blobs.Put(r.Context(), key, tmp, size, contentType); err != nil {
+		log.Println(err)
+		http.Error(w, "Error storing file", http.StatusInternalServerError)
+		return
+	}
+
+	attachment, err := s.store.CreateAttachment(&common.Attachment{
+		TaskID:      int64(taskID),
+		UploaderID:  int64(userID),
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	})
+	if err != nil {
+		if err := s.blobs.Delete(r.Context(), key); err != nil {
+			log.Println(err)
+		}
+		http.Error(w, "Error creating attachment", http.StatusInternalServerError)
+		return
+	}
+
+	attachment.URL = s.downloadURL(attachment.ID)
+	utils.WriteJSON(w, http.StatusCreated, attachment)
+}
+
+func (s *AttachmentsService) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(int64(id), expires))) {
+		http.Error(w, errInvalidSignature.Error(), http.StatusForbidden)
+		return
+	}
+	if s.now().Unix() > expires {
+		http.Error(w, errLinkExpired.Error(), http.StatusForbidden)
+		return
+	}
+
+	attachment, err := s.store.GetAttachment(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errAttachmentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting attachment", http.StatusInternalServerError)
+		return
+	}
+
+	content, err := s.blobs.Get(r.Context(), attachment.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		http.Error(w, errAttachmentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error reading attachment", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+func (s *AttachmentsService) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := s.store.GetAttachment(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errAttachmentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting attachment", http.StatusInternalServerError)
+		return
+	}
+	if attachment.UploaderID != int64(userID) {
+		http.Error(w, errNotUploader.Error(), http.StatusForbidden)
+		return
+	}
+
+	err = s.store.DeleteAttachment(id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errAttachmentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting attachment", http.StatusInternalServerError)
+		return
+	}
+
+	// The metadata is gone, so a failure here only leaves an unreachable blob.
+	if err := s.blobs.Delete(r.Context(), attachment.StorageKey); err != nil {
+		log.Println(err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// downloadURL returns a link to the attachment that is valid for urlTTL.
+func (s *AttachmentsService) downloadURL(id int64) string {
+	expires := s.now().Add(s.urlTTL).Unix()
+	return fmt.Sprintf("/attachments/%d/download?expires=%d&signature=%s", id, expires, s.sign(id, expires))
+}
+
+func (s *AttachmentsService) sign(id, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlSecret)
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, errFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Error reading upload", http.StatusBadRequest)
+}
+
+// sanitizeFilename keeps the base name of the uploaded file without control
+// characters, so it is safe to echo in a Content-Disposition header.
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[len(name)-255:], "")
+	}
+	return name
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"bytes"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestAttachmentsService(t *testing.T, store common.Store) *AttachmentsService {
+	blobs, err := blob.NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+
+	service := NewAttachmentsService(store, blobs)
+	service.maxBytes = 64
+	return service
+}
+
+func uploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", filename)
+	part.Write(content)
+	mw.Close()
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/attachments", body.Bytes(), 3)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("id", "1")
+	return req
+}
+
+func TestUploadAndDownloadAttachment(t *testing.T) {
+	mockStore := new(MockStore)
+	service := newTestAttachmentsService(t, mockStore)
+
+	var stored *common.Attachment
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("CreateAttachment", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*common.Attachment)
+		stored.ID = 5
+	}).Return(&common.Attachment{ID: 5}, nil)
+
+	w := httptest.NewRecorder()
+	service.handleUploadAttachment(w, uploadRequest(t, "../../crash.log", []byte("panic: runtime error\n")))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "crash.log", stored.Filename)
+	assert.Equal(t, "text/plain; charset=utf-8", stored.ContentType)
+	assert.Equal(t, int64(3), stored.UploaderID)
+
+	mockStore.On("GetAttachment", 5).Return(stored, nil)
+	req := httptest.NewRequest(http.MethodGet, service.downloadURL(5), nil)
+	req.SetPathValue("id", "5")
+	w = httptest.NewRecorder()
+	service.handleDownloadAttachment(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "panic: runtime error\n", w.Body.String())
+	assert.Equal(t, `attachment; filename=crash.log`, w.Header().Get("Content-Disposition"))
+}
+
+func TestNonMemberCannotUseAttachments(t *testing.T) {
+	mockStore := new(MockStore)
+	service := newTestAttachmentsService(t, mockStore)
+
+	workspaceID := int64(2)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/attachments", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	service.handleGetAttachments(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	service.handleUploadAttachment(w, uploadRequest(t, "crash.log", []byte("panic: runtime error\n")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockStore.AssertNotCalled(t, "GetAttachments", mock.Anything)
+	mockStore.AssertNotCalled(t, "CreateAttachment", mock.Anything)
+}
+
+func TestUploadAttachmentLimits(t *testing.T) {
+	mockStore := new(MockStore)
+	service := newTestAttachmentsService(t, mockStore)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+
+	w := httptest.NewRecorder()
+	service.handleUploadAttachment(w, uploadRequest(t, "big.txt", bytes.Repeat([]byte("a"), 65)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	service.handleUploadAttachment(w, uploadRequest(t, "page.png", []byte("<html><script>alert(1)</script></html>")))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	mockStore.AssertNotCalled(t, "CreateAttachment", mock.Anything)
+}
+
+func TestDownloadLinkIsSignedAndExpires(t *testing.T) {
+	mockStore := new(MockStore)
+	service := newTestAttachmentsService(t, mockStore)
+
+	link := service.downloadURL(5)
+
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req.SetPathValue("id", "6")
+	w := httptest.NewRecorder()
+	service.handleDownloadAttachment(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	service.now = func() time.Time { return time.Now().Add(time.Hour) }
+	req = httptest.NewRequest(http.MethodGet, link, nil)
+	req.SetPathValue("id", "5")
+	w = httptest.NewRecorder()
+	service.handleDownloadAttachment(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "expired")
+
+	mockStore.AssertNotCalled(t, "GetAttachment", mock.Anything)
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"slices"
+)
+
+var errInvalidBoardStatus = errors.New("status must be one of TODO, IN_PROGRESS, IN_TESTING, DONE")
+
+type BoardsService struct {
+	store common.Store
+}
+
+func NewBoardsService(store common.Store) *BoardsService {
+	return &BoardsService{store: store}
+}
+
+func (s *BoardsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /projects/{id}/board", auth.WithJWTAuth(s.handleGetBoard, s.store))
+	router.HandleFunc("POST /tasks/{id}/move", auth.WithJWTAuth(s.handleMoveTask, s.store))
+}
+
+type boardColumn struct {
+	Status   string         `json:"status"`
+	WIPLimit int            `json:"wip_limit,omitempty"`
+	Tasks    []*common.Task `json:"tasks"`
+}
+
+// handleGetBoard returns the project board: one column per status, in
+// workflow order, with the tasks of each column in board order.
+func (s *BoardsService) handleGetBoard(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.GetBoard(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error getting board", http.StatusInternalServerError)
+		return
+	}
+
+	columns := make([]boardColumn, len(common.Statuses))
+	for i, status := range common.Statuses {
+		columns[i] = boardColumn{Status: status, WIPLimit: project.WIPLimits[status], Tasks: []*common.Task{}}
+	}
+	for _, task := range tasks {
+		if i := slices.Index(common.Statuses, task.Status); i >= 0 {
+			columns[i].Tasks = append(columns[i].Tasks, task)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"project": project,
+		"columns": columns,
+	})
+}
+
+// handleMoveTask drags a task on its project board, to another column or
+// within its own, with `{"status": "IN_PROGRESS", "after_id": 12}`. The
+// task goes right after the task after_id, or to the top of the column
+// without it; a missing status keeps the task in its column.
+func (s *BoardsService) handleMoveTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Status  string `json:"status"`
+		AfterID *int64 `json:"after_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Status != "" && !slices.Contains(common.Statuses, payload.Status) {
+		http.Error(w, errInvalidBoardStatus.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if task.ProjectID == nil {
+		http.Error(w, common.ErrNotOnBoard.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err = s.store.MoveOnBoard(id, common.BoardMove{ActorID: int64(userID), Status: payload.Status, AfterID: payload.AfterID})
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrNotOnBoard) || errors.Is(err, common.ErrInvalidMove) || errors.Is(err, common.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, common.ErrWIPLimit) || errors.Is(err, common.ErrTaskBlocked) ||
+		errors.Is(err, common.ErrOpenSubtasks) || errors.Is(err, common.ErrOpenChecklist) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error moving task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
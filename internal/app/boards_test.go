@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetBoardGroupsTasksByStatus(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBoardsService(mockStore)
+
+	project := &common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API", WIPLimits: map[string]int{"IN_PROGRESS": 3}}
+	mockStore.On("GetProject", 4).Return(project, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetBoard", 4).Return([]*common.Task{
+		{ID: 9, Status: "IN_PROGRESS"},
+		{ID: 7, Status: "TODO"},
+		{ID: 8, Status: "IN_PROGRESS"},
+	}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/projects/4/board", nil, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleGetBoard(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Columns []struct {
+			Status   string `json:"status"`
+			WIPLimit int    `json:"wip_limit"`
+			Tasks    []struct {
+				ID int64 `json:"id"`
+			} `json:"tasks"`
+		} `json:"columns"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Columns, 4)
+	assert.Equal(t, "TODO", body.Columns[0].Status)
+	assert.Len(t, body.Columns[0].Tasks, 1)
+	assert.Equal(t, "IN_PROGRESS", body.Columns[1].Status)
+	assert.Equal(t, 3, body.Columns[1].WIPLimit)
+	assert.Equal(t, int64(9), body.Columns[1].Tasks[0].ID)
+	assert.Equal(t, int64(8), body.Columns[1].Tasks[1].ID)
+	assert.Empty(t, body.Columns[3].Tasks)
+}
+
+func TestMoveTaskOnBoardAtWIPLimit(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBoardsService(mockStore)
+
+	projectID, workspaceID := int64(4), int64(2)
+	afterID := int64(12)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, Status: "TODO", WorkspaceID: &workspaceID, ProjectID: &projectID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("MoveOnBoard", 9, common.BoardMove{ActorID: 3, Status: "IN_PROGRESS", AfterID: &afterID}).
+		Return((*common.Task)(nil), common.ErrWIPLimit)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/move", []byte(`{"status": "IN_PROGRESS", "after_id": 12}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleMoveTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestMoveTaskOutsideProject(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBoardsService(mockStore)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, Status: "TODO"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/move", []byte(`{"status": "DONE"}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleMoveTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "MoveOnBoard", mock.Anything, mock.Anything)
+}
+
+func TestUpdateProjectRejectsUnknownWIPColumn(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodPatch, "/projects/4", []byte(`{"wip_limits": {"REVIEW": 2}}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateProject(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateProject", mock.Anything)
+}
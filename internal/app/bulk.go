@@ -0,0 +1,312 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"log"
+	"net/http"
+)
+
+// maxBulkTasks bounds how many tasks one bulk request changes.
+const maxBulkTasks = 100
+
+var errInvalidBulkAction = errors.New("action must be one of transition, reassign, add_label, delete, move")
+var errBulkTarget = errors.New("either ids or query is required")
+var errTooManyBulkTasks = errors.New("a bulk request can change at most 100 tasks")
+var errInvalidBulkStatus = errors.New("status must be one of IN_PROGRESS, IN_TESTING, DONE")
+var errMoveTarget = errors.New("workspace_id or project_id is required")
+var errForceRequiresAdmin = errors.New("only workspace admins can force tasks to DONE")
+var errBulkForce = errors.New("force only applies to transition")
+var errTaskNotFound = errors.New("Task not found")
+
+type BulkService struct {
+	store common.Store
+}
+
+func NewBulkService(store common.Store) *BulkService {
+	return &BulkService{store: store}
+}
+
+func (s *BulkService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST /tasks/bulk", auth.WithJWTAuth(s.handleBulk, s.store))
+}
+
+type bulkRequest struct {
+	Action      string            `json:"action"`
+	IDs         []int64           `json:"ids"`
+	Query       string            `json:"query"`
+	Atomic      bool              `json:"atomic"`
+	Status      string            `json:"status"`
+	Force       bool              `json:"force"`
+	UserID      int64             `json:"user_id"`
+	LabelID     int64             `json:"label_id"`
+	WorkspaceID int64             `json:"workspace_id"`
+	ProjectID   *int64            `json:"project_id"`
+	StatusMap   map[string]string `json:"status_map"`
+}
+
+type bulkResult struct {
+	ID    int64  `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleBulk applies one action to a list of tasks, given by ids or by a
+// query in the syntax of GET /tasks?q=:
+//
+//	{"action": "transition", "ids": [1, 2, 3], "status": "DONE", "atomic": true}
+//
+// Every task gets its own result. With atomic set no task is changed
+// unless all of them can be; otherwise the tasks that can be changed are.
+func (s *BulkService) handleBulk(w http.ResponseWriter, r *http.Request) {
+	var payload bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	action := common.BulkAction{
+		Action:      payload.Action,
+		ActorID:     int64(userID),
+		Force:       payload.Force,
+		Status:      payload.Status,
+		UserID:      payload.UserID,
+		LabelID:     payload.LabelID,
+		WorkspaceID: payload.WorkspaceID,
+		ProjectID:   payload.ProjectID,
+		StatusMap:   payload.StatusMap,
+	}
+	if err := validateBulkAction(action); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids, ok := s.bulkTargets(w, r, &payload, userID)
+	if !ok {
+		return
+	}
+
+	// The label and the target workspace or project must be the caller's.
+	var label *common.Label
+	switch action.Action {
+	case common.BulkAddLabel:
+		var err error
+		label, err = s.store.GetLabel(int(action.LabelID))
+		if errors.Is(err, common.ErrNotFound) {
+			http.Error(w, errLabelNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error getting label", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := checkWorkspaceMember(s.store, w, r, int(label.WorkspaceID), common.WorkspaceRoleMember); !ok {
+			return
+		}
+	case common.BulkMove:
+		if action.ProjectID != nil {
+			project, err := s.store.GetProject(int(*action.ProjectID))
+			if errors.Is(err, common.ErrNotFound) {
+				http.Error(w, errProjectNotFound.Error(), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Error getting project", http.StatusInternalServerError)
+				return
+			}
+			if action.WorkspaceID != 0 && action.WorkspaceID != project.WorkspaceID {
+				http.Error(w, errProjectWorkspace.Error(), http.StatusBadRequest)
+				return
+			}
+			action.WorkspaceID = project.WorkspaceID
+		}
+		if _, ok := checkWorkspaceMember(s.store, w, r, int(action.WorkspaceID), common.WorkspaceRoleMember); !ok {
+			return
+		}
+	}
+
+	results := make([]bulkResult, len(ids))
+	var pending []int64
+	var pendingAt []int
+	for i, id := range ids {
+		results[i] = bulkResult{ID: id}
+		if err := s.checkBulkTask(action, label, id, userID); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		pending = append(pending, id)
+		pendingAt = append(pendingAt, i)
+	}
+
+	failed := len(ids) - len(pending)
+	if payload.Atomic && failed > 0 {
+		for _, i := range pendingAt {
+			results[i].Error = common.ErrBulkAborted.Error()
+		}
+		pending = nil
+	}
+
+	if len(pending) > 0 {
+		errs, err := s.store.ApplyBulk(action, pending, payload.Atomic)
+		if err != nil {
+			http.Error(w, "Error updating tasks", http.StatusInternalServerError)
+			return
+		}
+		for j, err := range errs {
+			i := pendingAt[j]
+			if err == nil {
+				results[i].OK = true
+				continue
+			}
+			results[i].Error = bulkError(err)
+		}
+	}
+
+	succeeded := 0
+	for _, res := range results {
+		if res.OK {
+			succeeded++
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
+
+func validateBulkAction(action common.BulkAction) error {
+	switch action.Action {
+	case common.BulkTransition:
+		switch action.Status {
+		case "IN_PROGRESS", "IN_TESTING", "DONE":
+		default:
+			return errInvalidBulkStatus
+		}
+	case common.BulkReassign:
+		if action.UserID <= 0 {
+			return errUserIDRequired
+		}
+	case common.BulkAddLabel:
+		if action.LabelID <= 0 {
+			return errLabelIDRequired
+		}
+	case common.BulkDelete:
+	case common.BulkMove:
+		if action.WorkspaceID <= 0 && action.ProjectID == nil {
+			return errMoveTarget
+		}
+		if err := validateStatusMap(action.StatusMap); err != nil {
+			return err
+		}
+	default:
+		return errInvalidBulkAction
+	}
+	if action.Force && action.Action != common.BulkTransition {
+		return errBulkForce
+	}
+	return nil
+}
+
+// bulkTargets returns the ids of the request, or those of the tasks its
+// query matches, without duplicates.
+func (s *BulkService) bulkTargets(w http.ResponseWriter, r *http.Request, payload *bulkRequest, userID int) ([]int64, bool) {
+	if (len(payload.IDs) == 0) == (payload.Query == "") {
+		http.Error(w, errBulkTarget.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	ids := payload.IDs
+	if payload.Query != "" {
+		q, err := query.Parse(payload.Query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		tasks, err := s.store.QueryTasks(q, userID)
+		var queryErr *query.Error
+		if errors.As(err, &queryErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		if err != nil {
+			http.Error(w, "Error querying tasks", http.StatusInternalServerError)
+			return nil, false
+		}
+		ids = make([]int64, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+	}
+
+	seen := make(map[int64]bool, len(ids))
+	unique := []int64{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > maxBulkTasks {
+		http.Error(w, errTooManyBulkTasks.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return unique, true
+}
+
+// checkBulkTask makes sure the caller may apply the action to the task.
+// Tasks in workspaces the caller does not belong to, and personal tasks
+// they are not assigned to, are reported as missing.
+func (s *BulkService) checkBulkTask(action common.BulkAction, label *common.Label, id int64, userID int) error {
+	task, err := s.store.GetTask(int(id))
+	if err != nil {
+		return errTaskNotFound
+	}
+
+	if task.WorkspaceID != nil {
+		member, err := s.store.GetWorkspaceMember(int(*task.WorkspaceID), userID)
+		if err != nil {
+			return errTaskNotFound
+		}
+		if action.Force && member.Role != common.WorkspaceRoleAdmin {
+			return errForceRequiresAdmin
+		}
+	} else if !isTaskAssignee(task, userID) {
+		return errTaskNotFound
+	} else if action.Force {
+		return errForceRequiresWorkspace
+	}
+
+	switch action.Action {
+	case common.BulkReassign:
+		return checkAssignees(s.store, task.WorkspaceID, []int64{action.UserID})
+	case common.BulkAddLabel:
+		if task.WorkspaceID == nil || *task.WorkspaceID != label.WorkspaceID {
+			return errLabelWorkspace
+		}
+	}
+	return nil
+}
+
+// bulkError is the message reported for a task the store failed to change.
+func bulkError(err error) string {
+	if errors.Is(err, common.ErrNotFound) {
+		return errTaskNotFound.Error()
+	}
+	for _, known := range []error{
+		common.ErrBulkAborted, common.ErrTaskDone, common.ErrInvalidTransition, common.ErrTaskBlocked,
+		common.ErrOpenSubtasks, common.ErrOpenChecklist, common.ErrMoveSubtask, common.ErrWIPLimit,
+	} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	log.Println("Error in bulk update:", err)
+	return "Error updating task"
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type bulkResponse struct {
+	Results   []bulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+func TestBulkBestEffortReportsEachTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBulkService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetTask", 4).Return(&common.Task{ID: 4, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	action := common.BulkAction{Action: common.BulkTransition, ActorID: 3, Status: "DONE"}
+	mockStore.On("ApplyBulk", action, []int64{1, 4}, false).Return([]error{nil, common.ErrOpenSubtasks}, nil)
+
+	body := []byte(`{"action": "transition", "ids": [1, 4, 1], "status": "DONE"}`)
+	req := authorizedRequest(http.MethodPost, "/tasks/bulk", body, 3)
+	w := httptest.NewRecorder()
+
+	service.handleBulk(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp bulkResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []bulkResult{
+		{ID: 1, OK: true},
+		{ID: 4, Error: common.ErrOpenSubtasks.Error()},
+	}, resp.Results)
+	assert.Equal(t, 1, resp.Succeeded)
+	assert.Equal(t, 1, resp.Failed)
+	mockStore.AssertExpectations(t)
+}
+
+func TestBulkAtomicStopsOnInaccessibleTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBulkService(mockStore)
+	workspaceID, otherWorkspaceID := int64(2), int64(5)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetTask", 6).Return(&common.Task{ID: 6, WorkspaceID: &otherWorkspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetWorkspaceMember", 5, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	body := []byte(`{"action": "delete", "ids": [1, 6], "atomic": true}`)
+	req := authorizedRequest(http.MethodPost, "/tasks/bulk", body, 3)
+	w := httptest.NewRecorder()
+
+	service.handleBulk(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp bulkResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []bulkResult{
+		{ID: 1, Error: common.ErrBulkAborted.Error()},
+		{ID: 6, Error: errTaskNotFound.Error()},
+	}, resp.Results)
+	assert.Equal(t, 2, resp.Failed)
+	mockStore.AssertNotCalled(t, "ApplyBulk", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBulkRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown action", `{"action": "close", "ids": [1]}`},
+		{"missing status", `{"action": "transition", "ids": [1]}`},
+		{"ids and query", `{"action": "delete", "ids": [1], "query": "status:TODO"}`},
+		{"no target", `{"action": "delete"}`},
+		{"force without transition", `{"action": "delete", "ids": [1], "force": true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockStore)
+			service := NewBulkService(mockStore)
+
+			req := authorizedRequest(http.MethodPost, "/tasks/bulk", []byte(tt.body), 3)
+			w := httptest.NewRecorder()
+
+			service.handleBulk(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockStore.AssertNotCalled(t, "ApplyBulk", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBulkDeleteOfStrangersPersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewBulkService(mockStore)
+
+	mockStore.On("GetTask", 7).Return(&common.Task{ID: 7, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+
+	body := []byte(`{"action": "delete", "ids": [7]}`)
+	req := authorizedRequest(http.MethodPost, "/tasks/bulk", body, 3)
+	w := httptest.NewRecorder()
+
+	service.handleBulk(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp bulkResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []bulkResult{{ID: 7, Error: errTaskNotFound.Error()}}, resp.Results)
+	assert.Equal(t, 1, resp.Failed)
+	mockStore.AssertNotCalled(t, "ApplyBulk", mock.Anything, mock.Anything, mock.Anything)
+}
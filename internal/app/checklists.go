@@ -0,0 +1,231 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxChecklistText = 500
+
+var errChecklistText = errors.New("text is required and must be at most 500 characters")
+var errChecklistItemNotFound = errors.New("Checklist item not found")
+
+type ChecklistsService struct {
+	store common.Store
+}
+
+func NewChecklistsService(store common.Store) *ChecklistsService {
+	return &ChecklistsService{store: store}
+}
+
+func (s *ChecklistsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/checklist", auth.WithJWTAuth(s.handleGetChecklist, s.store))
+	router.HandleFunc("POST /tasks/{id}/checklist", auth.WithJWTAuth(s.handleAddItem, s.store))
+	router.HandleFunc("PUT /tasks/{id}/checklist/order", auth.WithJWTAuth(s.handleReorderChecklist, s.store))
+	router.HandleFunc("PATCH /checklist/{id}", auth.WithJWTAuth(s.handleUpdateItem, s.store))
+	router.HandleFunc("DELETE /checklist/{id}", auth.WithJWTAuth(s.handleDeleteItem, s.store))
+}
+
+// checkTaskAccess makes sure the caller may change the task: it is outside a
+// workspace or the caller is a member of its workspace.
+func (s *ChecklistsService) checkTaskAccess(w http.ResponseWriter, r *http.Request, taskID int) bool {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return false
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return false
+	}
+	return true
+}
+
+// loadItem reads the checklist item from the path and checks access to its
+// task.
+func (s *ChecklistsService) loadItem(w http.ResponseWriter, r *http.Request) (*common.ChecklistItem, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	item, err := s.store.GetChecklistItem(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errChecklistItemNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting checklist item", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !s.checkTaskAccess(w, r, int(item.TaskID)) {
+		return nil, false
+	}
+	return item, true
+}
+
+func (s *ChecklistsService) handleGetChecklist(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.checkTaskAccess(w, r, id) {
+		return
+	}
+
+	items, err := s.store.GetChecklist(id)
+	if err != nil {
+		http.Error(w, "Error getting checklist", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, items)
+}
+
+// handleAddItem appends an item to the end of the task's checklist.
+func (s *ChecklistsService) handleAddItem(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Text string `json:"text"`
+		Done bool   `json:"done"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	text, ok := checklistText(payload.Text)
+	if !ok {
+		http.Error(w, errChecklistText.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.checkTaskAccess(w, r, id) {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	item, err := s.store.AddChecklistItem(&common.ChecklistItem{TaskID: int64(id), Text: text, Done: payload.Done}, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error adding checklist item", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, item)
+}
+
+// handleUpdateItem renames the item or toggles it with `{"done": true}`.
+func (s *ChecklistsService) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Text *string `json:"text"`
+		Done *bool   `json:"done"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	item, ok := s.loadItem(w, r)
+	if !ok {
+		return
+	}
+
+	if payload.Text != nil {
+		text, ok := checklistText(*payload.Text)
+		if !ok {
+			http.Error(w, errChecklistText.Error(), http.StatusBadRequest)
+			return
+		}
+		item.Text = text
+	}
+	if payload.Done != nil {
+		item.Done = *payload.Done
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	if err := s.store.UpdateChecklistItem(item, userID); err != nil {
+		http.Error(w, "Error updating checklist item", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, item)
+}
+
+func (s *ChecklistsService) handleReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !s.checkTaskAccess(w, r, id) {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.ReorderChecklist(id, payload.IDs, userID)
+	if errors.Is(err, common.ErrInvalidChecklistOrder) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reordering checklist", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := s.store.GetChecklist(id)
+	if err != nil {
+		http.Error(w, "Error getting checklist", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, items)
+}
+
+func (s *ChecklistsService) handleDeleteItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.loadItem(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	if err := s.store.DeleteChecklistItem(int(item.ID), userID); err != nil && !errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Error deleting checklist item", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func checklistText(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	return text, text != "" && utf8.RuneCountInString(text) <= maxChecklistText
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddChecklistItemTrimsText(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewChecklistsService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("AddChecklistItem", &common.ChecklistItem{TaskID: 1, Text: "Update changelog"}, 3).
+		Return(&common.ChecklistItem{ID: 5, TaskID: 1, Text: "Update changelog", Position: 1}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/checklist", []byte(`{"text": "  Update changelog "}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddItem(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestAddChecklistItemRequiresText(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewChecklistsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/checklist", []byte(`{"text": "  "}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddItem(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "AddChecklistItem", mock.Anything, mock.Anything)
+}
+
+func TestToggleChecklistItem(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewChecklistsService(mockStore)
+
+	mockStore.On("GetChecklistItem", 5).Return(&common.ChecklistItem{ID: 5, TaskID: 1, Text: "Update changelog"}, nil)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("UpdateChecklistItem", &common.ChecklistItem{ID: 5, TaskID: 1, Text: "Update changelog", Done: true}, 3).
+		Return(nil)
+
+	req := authorizedRequest(http.MethodPatch, "/checklist/5", []byte(`{"done": true}`), 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateItem(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"done":true`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestReorderChecklistRejectsPartialOrder(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewChecklistsService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("ReorderChecklist", 1, []int64{6}, 3).Return(common.ErrInvalidChecklistOrder)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/checklist/order", []byte(`{"ids": [6]}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleReorderChecklist(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateWorkspaceRequiresAdmin(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorkspacesService(mockStore)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).
+		Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodPatch, "/workspaces/2", []byte(`{"checklist_blocks_done": true}`), 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateWorkspace(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateWorkspace", mock.Anything)
+}
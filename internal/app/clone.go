@@ -0,0 +1,185 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"log"
+	"net/http"
+)
+
+var errMergeWorkspace = errors.New("a task can only be merged into a task of the same workspace")
+var errCloneConflict = errors.New("the task's attachments changed while it was cloned, try again")
+
+type CloneService struct {
+	store common.Store
+	blobs blob.Store
+}
+
+func NewCloneService(store common.Store, blobs blob.Store) *CloneService {
+	return &CloneService{store: store, blobs: blobs}
+}
+
+func (s *CloneService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST /tasks/{id}/clone", auth.WithJWTAuth(s.handleCloneTask, s.store))
+	router.HandleFunc("POST /tasks/{id}/merge-into/{target}", auth.WithJWTAuth(s.handleMergeTask, s.store))
+}
+
+// handleCloneTask copies a task next to the original. The body chooses
+// what comes along, such as
+// `{"subtasks": true, "checklist": true, "labels": true, "attachments": true}`,
+// and may rename the copy with "name". Attachment contents are copied in
+// blob storage, so deleting either task's files leaves the other's intact.
+func (s *CloneService) handleCloneTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Name        *string `json:"name"`
+		Subtasks    bool    `json:"subtasks"`
+		Checklist   bool    `json:"checklist"`
+		Labels      bool    `json:"labels"`
+		Attachments bool    `json:"attachments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Name != nil && *payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	opts := common.CloneOptions{
+		ActorID:   int64(userID),
+		Subtasks:  payload.Subtasks,
+		Checklist: payload.Checklist,
+		Labels:    payload.Labels,
+	}
+	if payload.Name != nil {
+		opts.Name = *payload.Name
+	}
+	var copied []string
+	if payload.Attachments {
+		// The contents are copied before the copied tasks exist, so the
+		// keys name the task each file was copied from.
+		opts.CopyAttachment = func(a *common.Attachment) (string, error) {
+			content, err := s.blobs.Get(r.Context(), a.StorageKey)
+			if err != nil {
+				return "", err
+			}
+			defer content.Close()
+
+			key := fmt.Sprintf("tasks/%d/%s", a.TaskID, randomToken())
+			if err := s.blobs.Put(r.Context(), key, content, a.Size, a.ContentType); err != nil {
+				return "", err
+			}
+			copied = append(copied, key)
+			return key, nil
+		}
+	}
+
+	clone, err := s.store.CloneTask(id, opts)
+	if err != nil {
+		// CloneTask fails only before its commit, so the copies made
+		// belong to no attachment.
+		for _, key := range copied {
+			if err := s.blobs.Delete(r.Context(), key); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errCloneConflict.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error cloning task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, clone)
+}
+
+// handleMergeTask closes the task in the path as a duplicate of the target
+// task and answers with the target, which now has the duplicate's comments
+// and watchers.
+func (s *CloneService) handleMergeTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	targetID, err := pathID(r, "target")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id == targetID {
+		http.Error(w, common.ErrInvalidMerge.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	target, err := s.store.GetTask(targetID)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if !sameWorkspace(task.WorkspaceID, target.WorkspaceID) {
+		http.Error(w, errMergeWorkspace.Error(), http.StatusBadRequest)
+		return
+	}
+	// Two personal tasks can belong to different users, so the caller
+	// needs access to both.
+	for _, t := range []*common.Task{task, target} {
+		if _, ok := checkTaskAccess(s.store, w, r, t, common.WorkspaceRoleMember); !ok {
+			return
+		}
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	target, err = s.store.MergeTask(id, targetID, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrTaskMerged) || errors.Is(err, common.ErrOpenSubtasks) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error merging task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, target)
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestCloneService(t *testing.T, store common.Store) (*CloneService, blob.Store) {
+	blobs, err := blob.NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, blobs.Put(context.Background(), "tasks/9/original", strings.NewReader("hello"), 5, "text/plain"))
+	return NewCloneService(store, blobs), blobs
+}
+
+func TestCloneTaskCopiesAttachmentContent(t *testing.T) {
+	mockStore := new(MockStore)
+	service, blobs := newTestCloneService(t, mockStore)
+
+	var key string
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, Name: "Fix login"}, nil)
+	mockStore.On("CloneTask", 9, mock.Anything).Run(func(args mock.Arguments) {
+		opts := args.Get(1).(common.CloneOptions)
+		assert.Equal(t, "Fix login again", opts.Name)
+		assert.True(t, opts.Subtasks)
+		assert.False(t, opts.Labels)
+		key, _ = opts.CopyAttachment(&common.Attachment{TaskID: 9, StorageKey: "tasks/9/original", Size: 5, ContentType: "text/plain"})
+	}).Return(&common.Task{ID: 12, Name: "Fix login again"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/clone", []byte(`{"name": "Fix login again", "subtasks": true, "attachments": true}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleCloneTask(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, strings.HasPrefix(key, "tasks/9/"))
+	assert.NotEqual(t, "tasks/9/original", key)
+	content, err := blobs.Get(context.Background(), key)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestCloneTaskFailureRemovesCopiedContent(t *testing.T) {
+	mockStore := new(MockStore)
+	service, blobs := newTestCloneService(t, mockStore)
+
+	var key string
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, Name: "Fix login"}, nil)
+	mockStore.On("CloneTask", 9, mock.Anything).Run(func(args mock.Arguments) {
+		opts := args.Get(1).(common.CloneOptions)
+		key, _ = opts.CopyAttachment(&common.Attachment{TaskID: 9, StorageKey: "tasks/9/original", Size: 5, ContentType: "text/plain"})
+	}).Return((*common.Task)(nil), errors.New("connection reset"))
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/clone", []byte(`{"attachments": true}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleCloneTask(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	_, err := blobs.Get(context.Background(), key)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestCloneTaskConflictRemovesCopiedContent(t *testing.T) {
+	mockStore := new(MockStore)
+	service, blobs := newTestCloneService(t, mockStore)
+
+	var key string
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, Name: "Fix login"}, nil)
+	mockStore.On("CloneTask", 9, mock.Anything).Run(func(args mock.Arguments) {
+		opts := args.Get(1).(common.CloneOptions)
+		key, _ = opts.CopyAttachment(&common.Attachment{TaskID: 9, StorageKey: "tasks/9/original", Size: 5, ContentType: "text/plain"})
+	}).Return((*common.Task)(nil), common.ErrConflict)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/clone", []byte(`{"attachments": true}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleCloneTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	_, err := blobs.Get(context.Background(), key)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestCloneTaskWithoutAttachments(t *testing.T) {
+	mockStore := new(MockStore)
+	service, _ := newTestCloneService(t, mockStore)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, Name: "Fix login"}, nil)
+	mockStore.On("CloneTask", 9, mock.MatchedBy(func(opts common.CloneOptions) bool {
+		return opts.CopyAttachment == nil && opts.Name == "" && opts.ActorID == 3
+	})).Return(&common.Task{ID: 12, Name: "Fix login"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/clone", []byte(`{"checklist": true}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleCloneTask(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestMergeTaskIntoAnotherWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCloneService(mockStore, nil)
+	workspaceID, otherWorkspaceID := int64(2), int64(4)
+
+	mockStore.On("GetTask", 8).Return(&common.Task{ID: 8, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, WorkspaceID: &otherWorkspaceID}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/8/merge-into/5", nil, 3)
+	req.SetPathValue("id", "8")
+	req.SetPathValue("target", "5")
+	w := httptest.NewRecorder()
+
+	service.handleMergeTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errMergeWorkspace.Error())
+	mockStore.AssertNotCalled(t, "MergeTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMergeTaskAlreadyMerged(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCloneService(mockStore, nil)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 8).Return(&common.Task{ID: 8, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("MergeTask", 8, 5, 3).Return((*common.Task)(nil), common.ErrTaskMerged)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/8/merge-into/5", nil, 3)
+	req.SetPathValue("id", "8")
+	req.SetPathValue("target", "5")
+	w := httptest.NewRecorder()
+
+	service.handleMergeTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMergeStrangersPersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCloneService(mockStore, nil)
+
+	mockStore.On("GetTask", 8).Return(&common.Task{ID: 8, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, AssignedToID: 3, AssigneeIDs: []int64{3}}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/8/merge-into/5", nil, 3)
+	req.SetPathValue("id", "8")
+	req.SetPathValue("target", "5")
+	w := httptest.NewRecorder()
+
+	service.handleMergeTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "MergeTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMergeIntoStrangersPersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCloneService(mockStore, nil)
+
+	mockStore.On("GetTask", 8).Return(&common.Task{ID: 8, AssignedToID: 3, AssigneeIDs: []int64{3}}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/8/merge-into/5", nil, 3)
+	req.SetPathValue("id", "8")
+	req.SetPathValue("target", "5")
+	w := httptest.NewRecorder()
+
+	service.handleMergeTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "MergeTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCloneStrangersPersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCloneService(mockStore, nil)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, Name: "Fix login", AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/9/clone", []byte(`{"attachments": true}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleCloneTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "CloneTask", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strconv"
+)
+
+const (
+	maxCommentLength    = 10000
+	defaultCommentsPage = 20
+	maxCommentsPage     = 100
+)
+
+var errBodyRequired = errors.New("body is required")
+var errBodyTooLong = errors.New("body must be at most 10000 characters")
+var errCommentNotFound = errors.New("Comment not found")
+var errParentNotFound = errors.New("Parent comment not found on this task")
+var errNotCommentAuthor = errors.New("Only the author can change a comment")
+
+type CommentsService struct {
+	store common.Store
+}
+
+// CommentsPage is one page of top-level comments. NextAfter is set when
+// more comments may follow and is passed back as ?after=.
+type CommentsPage struct {
+	Comments  []*common.Comment `json:"comments"`
+	NextAfter *int64            `json:"next_after,omitempty"`
+}
+
+func NewCommentsService(store common.Store) *CommentsService {
+	return &CommentsService{store: store}
+}
+
+func (s *CommentsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/comments", auth.WithJWTAuth(s.handleGetComments, s.store))
+	router.HandleFunc("POST /tasks/{id}/comments", auth.WithJWTAuth(s.handleCreateComment, s.store))
+	router.HandleFunc("PATCH /comments/{id}", auth.WithJWTAuth(s.handleUpdateComment, s.store))
+	router.HandleFunc("DELETE /comments/{id}", auth.WithJWTAuth(s.handleDeleteComment, s.store))
+	router.HandleFunc("GET /comments/{id}/revisions", auth.WithJWTAuth(s.handleGetRevisions, s.store))
+}
+
+func (s *CommentsService) handleGetComments(w http.ResponseWriter, r *http.Request) {
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	after, limit, err := pageParams(r, defaultCommentsPage, maxCommentsPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	comments, err := s.store.GetComments(taskID, after, limit)
+	if err != nil {
+		http.Error(w, "Error getting comments", http.StatusInternalServerError)
+		return
+	}
+
+	page := CommentsPage{Comments: comments}
+	if len(comments) == limit {
+		page.NextAfter = &comments[len(comments)-1].ID
+	}
+	utils.WriteJSON(w, http.StatusOK, page)
+}
+
+func (s *CommentsService) handleCreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload common.Comment
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validateCommentBody(payload.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	comment, err := s.store.CreateComment(&common.Comment{
+		TaskID:   int64(taskID),
+		AuthorID: int64(userID),
+		ParentID: payload.ParentID,
+		Body:     payload.Body,
+	})
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errParentNotFound.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating comment", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, comment)
+}
+
+func (s *CommentsService) handleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := s.loadOwnComment(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validateCommentBody(payload.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := s.store.UpdateComment(int(comment.ID), payload.Body)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errCommentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating comment", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (s *CommentsService) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	comment, ok := s.loadOwnComment(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.store.DeleteComment(int(comment.ID))
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errCommentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *CommentsService) handleGetRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := s.store.GetComment(id)
+	if err != nil {
+		http.Error(w, errCommentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	task, err := s.store.GetTask(int(comment.TaskID))
+	if err != nil {
+		http.Error(w, errCommentNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	revisions, err := s.store.GetCommentRevisions(id)
+	if err != nil {
+		http.Error(w, "Error getting revisions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, revisions)
+}
+
+// loadOwnComment returns the comment from the path if the caller wrote it
+// and it has not been deleted.
+func (s *CommentsService) loadOwnComment(w http.ResponseWriter, r *http.Request) (*common.Comment, bool) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	comment, err := s.store.GetComment(id)
+	if err != nil || comment.Deleted {
+		http.Error(w, errCommentNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if comment.AuthorID != int64(userID) {
+		http.Error(w, errNotCommentAuthor.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	return comment, true
+}
+
+func validateCommentBody(body string) error {
+	if body == "" {
+		return errBodyRequired
+	}
+	if len([]rune(body)) > maxCommentLength {
+		return errBodyTooLong
+	}
+	return nil
+}
+
+// pageParams reads the ?after= cursor and ?limit= page size.
+func pageParams(r *http.Request, defaultLimit, maxLimit int) (int, int, error) {
+	after, limit := 0, defaultLimit
+
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("Invalid 'after' parameter")
+		}
+		after = n
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("Invalid 'limit' parameter")
+		}
+		limit = min(n, maxLimit)
+	}
+
+	return after, limit, nil
+}
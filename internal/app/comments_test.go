@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateCommentUsesCallerAsAuthor(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCommentsService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("CreateComment", &common.Comment{TaskID: 1, AuthorID: 3, Body: "Looks good"}).
+		Return(&common.Comment{ID: 8, TaskID: 1, AuthorID: 3, Body: "Looks good"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/comments", []byte(`{"body":"Looks good","author_id":99}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleCreateComment(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestNonMemberCannotComment(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCommentsService(mockStore)
+
+	workspaceID := int64(2)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/comments", []byte(`{"body":"@alice look"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleCreateComment(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "CreateComment", mock.Anything)
+}
+
+func TestNonMemberCannotReadComments(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCommentsService(mockStore)
+
+	workspaceID := int64(2)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/comments", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetComments(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetComments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOnlyAuthorCanEditComment(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCommentsService(mockStore)
+
+	mockStore.On("GetComment", 8).Return(&common.Comment{ID: 8, TaskID: 1, AuthorID: 3, Body: "Looks good"}, nil)
+
+	req := authorizedRequest(http.MethodPatch, "/comments/8", []byte(`{"body":"Hijacked"}`), 4)
+	req.SetPathValue("id", "8")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateComment(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateComment", mock.Anything, mock.Anything)
+}
+
+func TestGetCommentsPagination(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewCommentsService(mockStore)
+
+	comments := []*common.Comment{{ID: 4, TaskID: 1, Body: "a"}, {ID: 6, TaskID: 1, Body: "b"}}
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("GetComments", 1, 3, 2).Return(comments, nil)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/comments?after=3&limit=2", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetComments(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page CommentsPage
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Len(t, page.Comments, 2)
+	assert.Equal(t, int64(6), *page.NextAfter)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"log"
 )
@@ -30,9 +31,81 @@ func (s *MySQLStorage) Init() (*sql.DB, error) {
 	if err := s.createUserTable(); err != nil {
 		return nil, err
 	}
+	if err := s.createWorkspacesTables(); err != nil {
+		return nil, err
+	}
 	if err := s.createTasksTable(); err != nil {
 		return nil, err
 	}
+	if err := s.migrateTasksTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createTaskSearchTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createTeamsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createViewsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createLabelsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createCommentsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createActivityTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createMentionsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createAttachmentsTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createDependenciesTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createAssigneesTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createRecurrencesTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createJobsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createWorklogsTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createChecklistTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createTemplatesTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createArchiveColumns(); err != nil {
+		return nil, err
+	}
+	if err := s.createProjectsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createBoardColumns(); err != nil {
+		return nil, err
+	}
+	if err := s.createSprintsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createMilestonesTable(); err != nil {
+		return nil, err
+	}
+	if err := s.createCustomFieldsTables(); err != nil {
+		return nil, err
+	}
+	if err := s.createDuplicatesColumn(); err != nil {
+		return nil, err
+	}
 
 	return s.db, nil
 }
@@ -69,3 +142,684 @@ func (s *MySQLStorage) createTasksTable() error {
 	`)
 	return err
 }
+
+// migrateTasksTable adds the columns introduced after the initial tasks
+// schema, so existing databases are upgraded in place.
+func (s *MySQLStorage) migrateTasksTable() error {
+	columns := []struct{ name, definition string }{
+		{"description", "TEXT NULL"},
+		{"priority", "ENUM('P0', 'P1', 'P2', 'P3') NOT NULL DEFAULT 'P2'"},
+		{"dueAt", "DATETIME NULL"},
+		{"workspaceID", "INT UNSIGNED NULL, ADD FOREIGN KEY (workspaceID) REFERENCES workspaces(id)"},
+		{"parentID", "INT UNSIGNED NULL, ADD FOREIGN KEY (parentID) REFERENCES tasks(id) ON DELETE CASCADE"},
+		{"position", "INT NOT NULL DEFAULT 0"},
+		{"deletedAt", "DATETIME NULL, ADD KEY (deletedAt)"},
+		{"deletedByID", "INT UNSIGNED NULL, ADD FOREIGN KEY (deletedByID) REFERENCES users(id) ON DELETE SET NULL"},
+	}
+	for _, c := range columns {
+		if err := s.ensureColumn("tasks", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MySQLStorage) createWorkspacesTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS workspaces (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    name VARCHAR(255) NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS workspace_members (
+		    workspaceID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    role ENUM('MEMBER', 'ADMIN') NOT NULL DEFAULT 'MEMBER',
+		    
+		    PRIMARY KEY (workspaceID, userID),
+		    KEY (userID),
+		    FOREIGN KEY (workspaceID) REFERENCES workspaces(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createTaskSearchTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_search (
+		    taskID INT UNSIGNED NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    description TEXT NOT NULL,
+		    comments MEDIUMTEXT NOT NULL,
+		    
+		    PRIMARY KEY (taskID),
+		    FULLTEXT KEY (name, description, comments),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createTeamsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS teams (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    name VARCHAR(255) NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS team_members (
+		    teamID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    role ENUM('MEMBER', 'LEAD') NOT NULL DEFAULT 'MEMBER',
+		    
+		    PRIMARY KEY (teamID, userID),
+		    KEY (userID),
+		    FOREIGN KEY (teamID) REFERENCES teams(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createViewsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS views (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    ownerID INT UNSIGNED NOT NULL,
+		    teamID INT UNSIGNED NULL,
+		    name VARCHAR(255) NOT NULL,
+		    filter TEXT NOT NULL,
+		    sortBy VARCHAR(255) NOT NULL DEFAULT '',
+		    columns VARCHAR(512) NOT NULL DEFAULT '',
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    updatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    FOREIGN KEY (ownerID) REFERENCES users(id),
+		    FOREIGN KEY (teamID) REFERENCES teams(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS view_pins (
+		    viewID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    
+		    PRIMARY KEY (viewID, userID),
+		    FOREIGN KEY (viewID) REFERENCES views(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createLabelsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS labels (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    workspaceID INT UNSIGNED NOT NULL,
+		    name VARCHAR(64) NOT NULL,
+		    color CHAR(7) NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (workspaceID, name),
+		    FOREIGN KEY (workspaceID) REFERENCES workspaces(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_labels (
+		    taskID INT UNSIGNED NOT NULL,
+		    labelID INT UNSIGNED NOT NULL,
+		    
+		    PRIMARY KEY (taskID, labelID),
+		    KEY (labelID),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (labelID) REFERENCES labels(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createCommentsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS comments (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    authorID INT UNSIGNED NOT NULL,
+		    parentID INT UNSIGNED NULL,
+		    body TEXT NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    editedAt TIMESTAMP NULL,
+		    deletedAt TIMESTAMP NULL,
+		    
+		    PRIMARY KEY (id),
+		    KEY (taskID, parentID, id),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (authorID) REFERENCES users(id),
+		    FOREIGN KEY (parentID) REFERENCES comments(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS comment_revisions (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    commentID INT UNSIGNED NOT NULL,
+		    body TEXT NOT NULL,
+		    replacedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (commentID),
+		    FOREIGN KEY (commentID) REFERENCES comments(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createActivityTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS activity (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    actorID INT UNSIGNED NULL,
+		    action VARCHAR(64) NOT NULL,
+		    details JSON NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (taskID, id),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (actorID) REFERENCES users(id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createMentionsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS mentions (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    userID INT UNSIGNED NOT NULL,
+		    taskID INT UNSIGNED NOT NULL,
+		    source ENUM('TASK', 'COMMENT') NOT NULL,
+		    sourceID INT UNSIGNED NOT NULL,
+		    authorID INT UNSIGNED NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (source, sourceID, userID),
+		    KEY (userID, id),
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE,
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (authorID) REFERENCES users(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS notifications (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    userID INT UNSIGNED NOT NULL,
+		    type VARCHAR(64) NOT NULL,
+		    taskID INT UNSIGNED NOT NULL,
+		    actorID INT UNSIGNED NULL,
+		    details JSON NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (userID, id),
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE,
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (actorID) REFERENCES users(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createAttachmentsTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS attachments (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    uploaderID INT UNSIGNED NOT NULL,
+		    filename VARCHAR(255) NOT NULL,
+		    contentType VARCHAR(255) NOT NULL,
+		    size BIGINT UNSIGNED NOT NULL,
+		    storageKey VARCHAR(512) NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (taskID),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (uploaderID) REFERENCES users(id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createDependenciesTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_dependencies (
+		    blockerID INT UNSIGNED NOT NULL,
+		    blockedID INT UNSIGNED NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (blockerID, blockedID),
+		    KEY (blockedID),
+		    FOREIGN KEY (blockerID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (blockedID) REFERENCES tasks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+// createAssigneesTables creates the assignee and watcher lists and copies
+// assignedToID of tasks that have no assignees yet into task_assignees.
+func (s *MySQLStorage) createAssigneesTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_assignees (
+		    taskID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (taskID, userID),
+		    KEY (userID),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_watchers (
+		    taskID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (taskID, userID),
+		    KEY (userID),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT IGNORE INTO task_assignees (taskID, userID)
+		SELECT t.id, t.assignedToID FROM tasks t
+		WHERE NOT EXISTS (SELECT 1 FROM task_assignees a WHERE a.taskID = t.id)
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createRecurrencesTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_recurrences (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    rule VARCHAR(255) NOT NULL,
+		    startAt DATETIME NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    description TEXT,
+		    priority ENUM('P0', 'P1', 'P2', 'P3') NOT NULL DEFAULT 'P2',
+		    assignedToID INT UNSIGNED NOT NULL,
+		    workspaceID INT UNSIGNED,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (taskID),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (assignedToID) REFERENCES users(id),
+		    FOREIGN KEY (workspaceID) REFERENCES workspaces(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+func (s *MySQLStorage) createJobsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS jobs (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    kind VARCHAR(64) NOT NULL,
+		    payload JSON NOT NULL,
+		    runAt DATETIME NOT NULL,
+		    dedupeKey VARCHAR(255) NOT NULL,
+		    attempts INT UNSIGNED NOT NULL DEFAULT 0,
+		    lastError TEXT,
+		    doneAt DATETIME NULL,
+		    failedAt DATETIME NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (dedupeKey),
+		    KEY (doneAt, failedAt, runAt)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_reminders (
+		    userID INT UNSIGNED NOT NULL,
+		    beforeSeconds INT UNSIGNED NOT NULL,
+		    
+		    PRIMARY KEY (userID, beforeSeconds),
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+// createWorklogsTable creates the time log. runningUserID is only set while
+// a timer runs, so its unique key allows one running timer per user.
+func (s *MySQLStorage) createWorklogsTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS worklogs (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    userID INT UNSIGNED NOT NULL,
+		    startedAt DATETIME NULL,
+		    seconds INT UNSIGNED NULL,
+		    workDate DATE NOT NULL,
+		    note TEXT,
+		    runningUserID INT UNSIGNED NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (runningUserID),
+		    KEY (taskID, workDate),
+		    KEY (userID, workDate),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+// createChecklistTables adds checklist items and the workspace setting that
+// makes them block DONE.
+func (s *MySQLStorage) createChecklistTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS checklist_items (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    text VARCHAR(500) NOT NULL,
+		    done BOOLEAN NOT NULL DEFAULT FALSE,
+		    position INT NOT NULL DEFAULT 0,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (taskID, position),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	return s.ensureColumn("workspaces", "checklistBlocksDone", "BOOLEAN NOT NULL DEFAULT FALSE")
+}
+
+func (s *MySQLStorage) createTemplatesTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS templates (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    workspaceID INT UNSIGNED NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    body JSON NOT NULL,
+		    createdByID INT UNSIGNED NOT NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (workspaceID, name),
+		    FOREIGN KEY (workspaceID) REFERENCES workspaces(id) ON DELETE CASCADE,
+		    FOREIGN KEY (createdByID) REFERENCES users(id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+// createArchiveColumns adds when tasks were finished and archived, and the
+// workspace setting that archives finished tasks automatically.
+func (s *MySQLStorage) createArchiveColumns() error {
+	if err := s.ensureColumn("tasks", "doneAt", "DATETIME NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("tasks", "archivedAt", "DATETIME NULL, ADD KEY (workspaceID, archivedAt)"); err != nil {
+		return err
+	}
+	return s.ensureColumn("workspaces", "autoArchiveDays", "INT UNSIGNED NOT NULL DEFAULT 0")
+}
+
+// createProjectsTables adds projects, the per-project task numbers and the
+// keys tasks had before they moved to another project.
+func (s *MySQLStorage) createProjectsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS projects (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    workspaceID INT UNSIGNED NOT NULL,
+		    projectKey VARCHAR(10) NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    nextNumber INT UNSIGNED NOT NULL DEFAULT 1,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY workspaceKey (workspaceID, projectKey),
+		    FOREIGN KEY (workspaceID) REFERENCES workspaces(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureColumn("tasks", "projectID", "INT UNSIGNED NULL, ADD FOREIGN KEY (projectID) REFERENCES projects(id)"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("tasks", "number", "INT UNSIGNED NULL, ADD UNIQUE KEY (projectID, number)"); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_key_redirects (
+		    projectID INT UNSIGNED NOT NULL,
+		    number INT UNSIGNED NOT NULL,
+		    taskID INT UNSIGNED NOT NULL,
+		    
+		    PRIMARY KEY (projectID, number),
+		    KEY (taskID),
+		    FOREIGN KEY (projectID) REFERENCES projects(id) ON DELETE CASCADE,
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+// createBoardColumns adds the rank that orders tasks on their project
+// board and the WIP limits of board columns. Tasks already in a project
+// are ranked by id, as six digit base-36 numbers.
+func (s *MySQLStorage) createBoardColumns() error {
+	err := s.ensureColumn("tasks", "boardRank", "VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NULL, ADD KEY (projectID, boardRank)")
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE tasks SET boardRank = LPAD(LOWER(CONV(id, 10, 36)), 6, '0')
+		WHERE projectID IS NOT NULL AND boardRank IS NULL`)
+	if err != nil {
+		return err
+	}
+	return s.ensureColumn("projects", "wipLimits", "JSON NULL")
+}
+
+// createSprintsTables adds the sprints of projects and the tasks in them.
+// Completed sprints keep their rows in sprint_tasks as a record of what
+// they held.
+func (s *MySQLStorage) createSprintsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS sprints (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    projectID INT UNSIGNED NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    goal TEXT,
+		    startDate DATE NOT NULL,
+		    endDate DATE NOT NULL,
+		    status ENUM('PLANNED', 'ACTIVE', 'COMPLETED') NOT NULL DEFAULT 'PLANNED',
+		    startedAt DATETIME NULL,
+		    completedAt DATETIME NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (projectID, status),
+		    FOREIGN KEY (projectID) REFERENCES projects(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS sprint_tasks (
+		    sprintID INT UNSIGNED NOT NULL,
+		    taskID INT UNSIGNED NOT NULL,
+		    addedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (sprintID, taskID),
+		    KEY (taskID),
+		    FOREIGN KEY (sprintID) REFERENCES sprints(id) ON DELETE CASCADE,
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+	`)
+	return err
+}
+
+// createMilestonesTable adds the milestones of projects, the link from
+// tasks to their milestone and the story points of tasks.
+func (s *MySQLStorage) createMilestonesTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS milestones (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    projectID INT UNSIGNED NOT NULL,
+		    name VARCHAR(255) NOT NULL,
+		    description TEXT,
+		    targetDate DATE NOT NULL,
+		    status ENUM('OPEN', 'CLOSED') NOT NULL DEFAULT 'OPEN',
+		    closedAt DATETIME NULL,
+		    releaseNotes MEDIUMTEXT,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    KEY (projectID, targetDate),
+		    FOREIGN KEY (projectID) REFERENCES projects(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = s.ensureColumn("tasks", "milestoneID", "INT UNSIGNED NULL, ADD FOREIGN KEY (milestoneID) REFERENCES milestones(id) ON DELETE SET NULL")
+	if err != nil {
+		return err
+	}
+	return s.ensureColumn("tasks", "points", "INT UNSIGNED NULL")
+}
+
+// createCustomFieldsTables adds the custom fields of projects and their
+// values on tasks. Each value fills the column of its field's type; a
+// multi-select field has a row per chosen option.
+func (s *MySQLStorage) createCustomFieldsTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS custom_fields (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    projectID INT UNSIGNED NOT NULL,
+		    name VARCHAR(64) NOT NULL,
+		    fieldType ENUM('text', 'number', 'date', 'single_select', 'multi_select', 'user') NOT NULL,
+		    options JSON NULL,
+		    createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    
+		    PRIMARY KEY (id),
+		    UNIQUE KEY (projectID, name),
+		    KEY (name),
+		    FOREIGN KEY (projectID) REFERENCES projects(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_field_values (
+		    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+		    taskID INT UNSIGNED NOT NULL,
+		    fieldID INT UNSIGNED NOT NULL,
+		    textValue VARCHAR(1000) NULL,
+		    numberValue DOUBLE NULL,
+		    dateValue DATE NULL,
+		    userID INT UNSIGNED NULL,
+		    
+		    PRIMARY KEY (id),
+		    KEY (taskID, fieldID),
+		    KEY (fieldID, textValue(64)),
+		    KEY (fieldID, numberValue),
+		    KEY (fieldID, dateValue),
+		    FOREIGN KEY (taskID) REFERENCES tasks(id) ON DELETE CASCADE,
+		    FOREIGN KEY (fieldID) REFERENCES custom_fields(id) ON DELETE CASCADE,
+		    FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	return err
+}
+
+// createDuplicatesColumn adds the link from a task closed as a duplicate
+// to the task it was merged into.
+func (s *MySQLStorage) createDuplicatesColumn() error {
+	return s.ensureColumn("tasks", "duplicateOfID", "INT UNSIGNED NULL, ADD FOREIGN KEY (duplicateOfID) REFERENCES tasks(id) ON DELETE SET NULL")
+}
+
+func (s *MySQLStorage) ensureColumn(table, column, definition string) error {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+var errBlockerRequired = errors.New("blocker_id is required")
+var errBlockerNotFound = errors.New("Blocking task not found")
+var errDependencyWorkspace = errors.New("dependent tasks must be in the same workspace")
+var errDependencyNotFound = errors.New("Dependency not found")
+
+type DependenciesService struct {
+	store common.Store
+}
+
+func NewDependenciesService(store common.Store) *DependenciesService {
+	return &DependenciesService{store: store}
+}
+
+func (s *DependenciesService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/dependencies", auth.WithJWTAuth(s.handleGetDependencies, s.store))
+	router.HandleFunc("POST /tasks/{id}/dependencies", auth.WithJWTAuth(s.handleAddDependency, s.store))
+	router.HandleFunc("DELETE /tasks/{id}/dependencies/{blockerID}", auth.WithJWTAuth(s.handleRemoveDependency, s.store))
+}
+
+func (s *DependenciesService) handleGetDependencies(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	graph, err := s.store.GetTaskDependencies(id)
+	if err != nil {
+		http.Error(w, "Error getting dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, graph)
+}
+
+// handleAddDependency marks the task as blocked by blocker_id.
+func (s *DependenciesService) handleAddDependency(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		BlockerID int `json:"blocker_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.BlockerID == 0 {
+		http.Error(w, errBlockerRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+	blocker, err := s.store.GetTask(payload.BlockerID)
+	if err != nil {
+		http.Error(w, errBlockerNotFound.Error(), http.StatusBadRequest)
+		return
+	}
+	if !sameWorkspace(task.WorkspaceID, blocker.WorkspaceID) {
+		http.Error(w, errDependencyWorkspace.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, blocker, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.AddTaskDependency(payload.BlockerID, id, userID)
+	switch {
+	case errors.Is(err, common.ErrDependencyCycle):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, common.ErrConflict):
+		http.Error(w, "Dependency already exists", http.StatusConflict)
+		return
+	case errors.Is(err, common.ErrNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Error adding dependency", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, common.Dependency{BlockerID: int64(payload.BlockerID), BlockedID: int64(id)})
+}
+
+func (s *DependenciesService) handleRemoveDependency(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	blockerID, err := pathID(r, "blockerID")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.RemoveTaskDependency(blockerID, id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errDependencyNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error removing dependency", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
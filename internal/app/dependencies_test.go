@@ -0,0 +1,100 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddDependency(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewDependenciesService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("AddTaskDependency", 5, 1, 3).Return(nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/dependencies", []byte(`{"blocker_id": 5}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddDependency(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"blocker_id": 5, "blocked_id": 1}`, w.Body.String())
+	mockStore.AssertExpectations(t)
+}
+
+func TestAddDependencyCycle(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewDependenciesService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, AssignedToID: 3}, nil)
+	mockStore.On("AddTaskDependency", 5, 1, 3).Return(common.ErrDependencyCycle)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/dependencies", []byte(`{"blocker_id": 5}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddDependency(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), common.ErrDependencyCycle.Error())
+}
+
+func TestNonMemberCannotLinkTasks(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewDependenciesService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/dependencies", []byte(`{"blocker_id": 5}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	service.handleAddDependency(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = authorizedRequest(http.MethodDelete, "/tasks/1/dependencies/5", nil, 3)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("blockerID", "5")
+	w = httptest.NewRecorder()
+	service.handleRemoveDependency(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = authorizedRequest(http.MethodGet, "/tasks/1/dependencies", nil, 3)
+	req.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+	service.handleGetDependencies(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockStore.AssertNotCalled(t, "AddTaskDependency", mock.Anything, mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "RemoveTaskDependency", mock.Anything, mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "GetTaskDependencies", mock.Anything)
+}
+
+func TestAddDependencyAcrossWorkspaces(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewDependenciesService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetTask", 5).Return(&common.Task{ID: 5, AssignedToID: 3}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/dependencies", []byte(`{"blocker_id": 5}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddDependency(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "AddTaskDependency", mock.Anything, mock.Anything, mock.Anything)
+}
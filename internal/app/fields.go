@@ -0,0 +1,264 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxFieldOptions bounds the options of a select field.
+const maxFieldOptions = 100
+
+var errInvalidFieldName = errors.New("name must be up to 64 letters, digits, '_' or '-' starting with a letter, such as customer_name")
+var errInvalidFieldType = errors.New("type must be one of " + strings.Join(common.FieldTypes, ", "))
+var errInvalidFieldOptions = errors.New("options must list up to 100 distinct, non-empty values of at most 255 characters")
+var errFieldOptionsNotAllowed = errors.New("only single_select and multi_select fields have options")
+var errFieldExists = errors.New("A custom field with this name already exists in the project")
+var errFieldNotFound = errors.New("Custom field not found")
+var errFieldsNeedProject = errors.New("only tasks in a project have custom fields")
+var errFieldUserNotMember = errors.New("user fields must name a member of the task's workspace")
+
+// fieldName keeps custom field names usable in queries, such as
+// cf.customer_name:Acme.
+var fieldName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+type FieldsService struct {
+	store common.Store
+}
+
+func NewFieldsService(store common.Store) *FieldsService {
+	return &FieldsService{store: store}
+}
+
+func (s *FieldsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /projects/{id}/fields", auth.WithJWTAuth(s.handleGetFields, s.store))
+	router.HandleFunc("POST /projects/{id}/fields", auth.WithJWTAuth(s.handleCreateField, s.store))
+	router.HandleFunc("PATCH /fields/{id}", auth.WithJWTAuth(s.handleUpdateField, s.store))
+	router.HandleFunc("DELETE /fields/{id}", auth.WithJWTAuth(s.handleDeleteField, s.store))
+}
+
+func (s *FieldsService) handleGetFields(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := s.store.GetCustomFields(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error getting custom fields", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fields)
+}
+
+func (s *FieldsService) handleCreateField(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	var payload common.CustomField
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.ProjectID = project.ID
+	if !slices.Contains(common.FieldTypes, payload.Type) {
+		http.Error(w, errInvalidFieldType.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateFieldPayload(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	field, err := s.store.CreateCustomField(&payload)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errFieldExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating custom field", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, field)
+}
+
+// handleUpdateField renames a field or changes the options of a select
+// field. Tasks lose the values of options that are removed. The type of a
+// field cannot change.
+func (s *FieldsService) handleUpdateField(w http.ResponseWriter, r *http.Request) {
+	field, ok := s.loadField(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Name    *string  `json:"name"`
+		Options []string `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Name != nil {
+		field.Name = *payload.Name
+	}
+	if payload.Options != nil {
+		field.Options = payload.Options
+	}
+	if err := validateFieldPayload(field); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.store.UpdateCustomField(field)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errFieldExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating custom field", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, field)
+}
+
+// handleDeleteField removes the field and its values from every task.
+func (s *FieldsService) handleDeleteField(w http.ResponseWriter, r *http.Request) {
+	field, ok := s.loadField(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteCustomField(int(field.ID)); err != nil && !errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Error deleting custom field", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadField returns the custom field from the path if the caller belongs
+// to the workspace of its project.
+func (s *FieldsService) loadField(w http.ResponseWriter, r *http.Request) (*common.CustomField, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	field, err := s.store.GetCustomField(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errFieldNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting custom field", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	project, err := s.store.GetProject(int(field.ProjectID))
+	if err != nil {
+		http.Error(w, "Error getting project", http.StatusInternalServerError)
+		return nil, false
+	}
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(project.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return field, true
+}
+
+// validateFieldPayload checks the name and the options of a field and
+// trims the options.
+func validateFieldPayload(f *common.CustomField) error {
+	if !fieldName.MatchString(f.Name) {
+		return errInvalidFieldName
+	}
+
+	isSelect := f.Type == common.FieldSingleSelect || f.Type == common.FieldMultiSelect
+	if !isSelect {
+		if len(f.Options) > 0 {
+			return errFieldOptionsNotAllowed
+		}
+		return nil
+	}
+	if len(f.Options) == 0 || len(f.Options) > maxFieldOptions {
+		return errInvalidFieldOptions
+	}
+	seen := map[string]bool{}
+	for i, option := range f.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len(option) > 255 || seen[strings.ToLower(option)] {
+			return errInvalidFieldOptions
+		}
+		seen[strings.ToLower(option)] = true
+		f.Options[i] = option
+	}
+	return nil
+}
+
+// checkCustomFields validates the custom field values of a task in the
+// project against the project's fields. User fields must name members of
+// the workspace. Invalid values are reported as a *badRequestError.
+func checkCustomFields(store common.Store, projectID, workspaceID *int64, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if projectID == nil {
+		return &badRequestError{errFieldsNeedProject}
+	}
+
+	fields, err := store.GetCustomFields(int(*projectID))
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		i := slices.IndexFunc(fields, func(f *common.CustomField) bool { return strings.EqualFold(f.Name, name) })
+		if i < 0 {
+			return &badRequestError{fmt.Errorf("%w %q", common.ErrUnknownField, name)}
+		}
+		value, err := common.NormalizeFieldValue(fields[i], value)
+		if err != nil {
+			return &badRequestError{err}
+		}
+		if userID, ok := value.(int64); ok && fields[i].Type == common.FieldUser {
+			if workspaceID == nil {
+				return &badRequestError{errFieldUserNotMember}
+			}
+			if _, err := store.GetWorkspaceMember(int(*workspaceID), int(userID)); err != nil {
+				return &badRequestError{errFieldUserNotMember}
+			}
+		}
+	}
+	return nil
+}
+
+// writeCustomFieldsError answers with the error of checkCustomFields and
+// reports whether there was none.
+func writeCustomFieldsError(w http.ResponseWriter, err error) bool {
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Error checking custom fields", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
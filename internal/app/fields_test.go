@@ -0,0 +1,119 @@
+package app
+
+import (
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func fieldStore() *MockStore {
+	mockStore := new(MockStore)
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetCustomFields", 4).Return([]*common.CustomField{
+		{ID: 7, ProjectID: 4, Name: "severity", Type: common.FieldSingleSelect, Options: []string{"low", "high"}},
+		{ID: 8, ProjectID: 4, Name: "reviewer", Type: common.FieldUser},
+	}, nil)
+	return mockStore
+}
+
+func TestCreateSelectFieldWithoutOptions(t *testing.T) {
+	mockStore := fieldStore()
+	service := NewFieldsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/projects/4/fields", []byte(`{"name": "environment", "type": "multi_select"}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleCreateField(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateCustomField", mock.Anything)
+}
+
+func TestCreateFieldWithNameUnusableInQueries(t *testing.T) {
+	mockStore := fieldStore()
+	service := NewFieldsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/projects/4/fields", []byte(`{"name": "customer name", "type": "text"}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleCreateField(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errInvalidFieldName.Error())
+}
+
+func TestCreateDuplicateField(t *testing.T) {
+	mockStore := fieldStore()
+	service := NewFieldsService(mockStore)
+
+	mockStore.On("CreateCustomField", mock.Anything).Return((*common.CustomField)(nil), common.ErrConflict)
+
+	req := authorizedRequest(http.MethodPost, "/projects/4/fields", []byte(`{"name": "severity", "type": "single_select", "options": ["low", " high "]}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleCreateField(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	field := mockStore.Calls[len(mockStore.Calls)-1].Arguments.Get(0).(*common.CustomField)
+	assert.Equal(t, []string{"low", "high"}, field.Options)
+}
+
+func TestUpdateTaskRejectsInvalidCustomField(t *testing.T) {
+	mockStore := fieldStore()
+	service := NewTaskService(mockStore)
+
+	workspaceID, projectID := int64(2), int64(4)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID, ProjectID: &projectID}, nil)
+
+	req := authorizedRequest(http.MethodPatch, "/tasks/9", []byte(`{"custom_fields": {"severity": "urgent"}}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "severity must be one of low, high")
+	mockStore.AssertNotCalled(t, "UpdateTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateTaskRejectsUserFieldOutsideWorkspace(t *testing.T) {
+	mockStore := fieldStore()
+	service := NewTaskService(mockStore)
+
+	workspaceID, projectID := int64(2), int64(4)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID, ProjectID: &projectID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 5).Return((*common.WorkspaceMember)(nil), errors.New("not found"))
+
+	req := authorizedRequest(http.MethodPatch, "/tasks/9", []byte(`{"custom_fields": {"reviewer": 5}}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errFieldUserNotMember.Error())
+}
+
+func TestCreateTaskWithCustomFieldsOutsideProject(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetUserByID", 3).Return(&common.User{ID: 3}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks", []byte(`{"name": "Fix login", "custom_fields": {"severity": "high"}}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errFieldsNeedProject.Error())
+	mockStore.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+}
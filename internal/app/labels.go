@@ -0,0 +1,258 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+const defaultLabelColor = "#808080"
+
+var errInvalidLabelColor = errors.New("color must be a hex color such as #1d76db")
+var errLabelExists = errors.New("A label with this name already exists in the workspace")
+var errLabelNotFound = errors.New("Label not found")
+var errLabelWorkspace = errors.New("Label and task must belong to the same workspace")
+var errLabelIDRequired = errors.New("label id is required")
+
+var labelColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type LabelsService struct {
+	store common.Store
+}
+
+func NewLabelsService(store common.Store) *LabelsService {
+	return &LabelsService{store: store}
+}
+
+func (s *LabelsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /workspaces/{id}/labels", auth.WithJWTAuth(s.handleGetLabels, s.store))
+	router.HandleFunc("POST /workspaces/{id}/labels", auth.WithJWTAuth(s.handleCreateLabel, s.store))
+	router.HandleFunc("PATCH /labels/{id}", auth.WithJWTAuth(s.handleUpdateLabel, s.store))
+	router.HandleFunc("DELETE /labels/{id}", auth.WithJWTAuth(s.handleDeleteLabel, s.store))
+	router.HandleFunc("POST /tasks/{id}/labels", auth.WithJWTAuth(s.handleAddTaskLabel, s.store))
+	router.HandleFunc("DELETE /tasks/{id}/labels/{labelID}", auth.WithJWTAuth(s.handleRemoveTaskLabel, s.store))
+}
+
+func (s *LabelsService) handleGetLabels(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	labels, err := s.store.GetLabels(workspaceID)
+	if err != nil {
+		http.Error(w, "Error getting labels", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, labels)
+}
+
+func (s *LabelsService) handleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	var payload common.Label
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.WorkspaceID = int64(workspaceID)
+	if err := validateLabelPayload(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	label, err := s.store.CreateLabel(&payload)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errLabelExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating label", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, label)
+}
+
+func (s *LabelsService) handleUpdateLabel(w http.ResponseWriter, r *http.Request) {
+	label, ok := s.loadLabel(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Name  *string `json:"name"`
+		Color *string `json:"color"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Name != nil {
+		label.Name = *payload.Name
+	}
+	if payload.Color != nil {
+		label.Color = *payload.Color
+	}
+	if err := validateLabelPayload(label); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.store.UpdateLabel(label)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errLabelExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating label", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, label)
+}
+
+func (s *LabelsService) handleDeleteLabel(w http.ResponseWriter, r *http.Request) {
+	label, ok := s.loadLabel(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteLabel(int(label.ID)); err != nil && !errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Error deleting label", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *LabelsService) handleAddTaskLabel(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		LabelID int `json:"label_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.LabelID <= 0 {
+		http.Error(w, errLabelIDRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	r.SetPathValue("labelID", strconv.Itoa(payload.LabelID))
+
+	task, label, ok := s.loadTaskLabel(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, _ := auth.GetUserIDFromRequest(r)
+	if err := s.store.AddTaskLabel(int(task.ID), int(label.ID), actorID); err != nil {
+		http.Error(w, "Error adding label", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := s.store.GetTask(int(task.ID))
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (s *LabelsService) handleRemoveTaskLabel(w http.ResponseWriter, r *http.Request) {
+	task, label, ok := s.loadTaskLabel(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, _ := auth.GetUserIDFromRequest(r)
+	err := s.store.RemoveTaskLabel(int(task.ID), int(label.ID), actorID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task does not have this label", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error removing label", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadLabel returns the label from the given path parameter if the caller
+// belongs to its workspace.
+func (s *LabelsService) loadLabel(w http.ResponseWriter, r *http.Request, param string) (*common.Label, bool) {
+	id, err := pathID(r, param)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	label, err := s.store.GetLabel(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errLabelNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting label", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(label.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return label, true
+}
+
+func (s *LabelsService) loadTaskLabel(w http.ResponseWriter, r *http.Request) (*common.Task, *common.Label, bool) {
+	taskID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	label, ok := s.loadLabel(w, r, "labelID")
+	if !ok {
+		return nil, nil, false
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if task.WorkspaceID == nil || *task.WorkspaceID != label.WorkspaceID {
+		http.Error(w, errLabelWorkspace.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return task, label, true
+}
+
+func validateLabelPayload(l *common.Label) error {
+	if l.Name == "" {
+		return errNameRequired
+	}
+	if l.Color == "" {
+		l.Color = defaultLabelColor
+	}
+	if !labelColor.MatchString(l.Color) {
+		return errInvalidLabelColor
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddLabelFromAnotherWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewLabelsService(mockStore)
+
+	otherWorkspace := int64(9)
+	mockStore.On("GetLabel", 4).Return(&common.Label{ID: 4, WorkspaceID: 2, Name: "backend"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &otherWorkspace}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/labels", []byte(`{"label_id":4}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleAddTaskLabel(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "AddTaskLabel", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLabelsHiddenOutsideWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewLabelsService(mockStore)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/workspaces/2/labels", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleGetLabels(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetLabels", 2)
+}
+
+func TestRenameLabel(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewLabelsService(mockStore)
+
+	mockStore.On("GetLabel", 4).Return(&common.Label{ID: 4, WorkspaceID: 2, Name: "backend", Color: "#1d76db"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("UpdateLabel", &common.Label{ID: 4, WorkspaceID: 2, Name: "api", Color: "#1d76db"}).Return(nil)
+
+	req := authorizedRequest(http.MethodPatch, "/labels/4", []byte(`{"name":"api"}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleUpdateLabel(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+const (
+	defaultInboxLimit = 50
+	maxInboxLimit     = 200
+)
+
+type MentionsService struct {
+	store common.Store
+}
+
+func NewMentionsService(store common.Store) *MentionsService {
+	return &MentionsService{store: store}
+}
+
+func (s *MentionsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /users/me/mentions", auth.WithJWTAuth(s.handleGetMentions, s.store))
+	router.HandleFunc("GET /users/me/notifications", auth.WithJWTAuth(s.handleGetNotifications, s.store))
+}
+
+func (s *MentionsService) handleGetMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	_, limit, err := pageParams(r, defaultInboxLimit, maxInboxLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mentions, err := s.store.GetMentionsForUser(userID, limit)
+	if err != nil {
+		http.Error(w, "Error getting mentions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, mentions)
+}
+
+func (s *MentionsService) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	_, limit, err := pageParams(r, defaultInboxLimit, maxInboxLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := s.store.GetNotifications(userID, limit)
+	if err != nil {
+		http.Error(w, "Error getting notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, notifications)
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetMentionsForCaller(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewMentionsService(mockStore)
+
+	mockStore.On("GetMentionsForUser", 3, maxInboxLimit).Return([]*common.Mention{{ID: 1, UserID: 3, TaskID: 7}}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/users/me/mentions?limit=1000", nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleGetMentions(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errInvalidTargetDate = errors.New("target_date must be a date such as 2025-06-30")
+var errMilestoneNotFound = errors.New("Milestone not found")
+var errMilestoneProject = errors.New("the task must be in the project of the milestone")
+
+type MilestonesService struct {
+	store common.Store
+	now   func() time.Time
+}
+
+func NewMilestonesService(store common.Store) *MilestonesService {
+	return &MilestonesService{store: store, now: time.Now}
+}
+
+func (s *MilestonesService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /projects/{id}/milestones", auth.WithJWTAuth(s.handleGetMilestones, s.store))
+	router.HandleFunc("POST /projects/{id}/milestones", auth.WithJWTAuth(s.handleCreateMilestone, s.store))
+	router.HandleFunc("GET /milestones/{id}", auth.WithJWTAuth(s.handleGetMilestone, s.store))
+	router.HandleFunc("PATCH /milestones/{id}", auth.WithJWTAuth(s.handleUpdateMilestone, s.store))
+	router.HandleFunc("POST /milestones/{id}/close", auth.WithJWTAuth(s.handleCloseMilestone, s.store))
+	router.HandleFunc("PUT /tasks/{id}/milestone", auth.WithJWTAuth(s.handleSetTaskMilestone, s.store))
+}
+
+func (s *MilestonesService) handleGetMilestones(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	milestones, err := s.store.GetMilestones(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error getting milestones", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, milestones)
+}
+
+func (s *MilestonesService) handleCreateMilestone(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	var payload common.Milestone
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.ProjectID = project.ID
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse(time.DateOnly, payload.TargetDate); err != nil {
+		http.Error(w, errInvalidTargetDate.Error(), http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := s.store.CreateMilestone(&payload)
+	if err != nil {
+		http.Error(w, "Error creating milestone", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, milestone)
+}
+
+// handleGetMilestone returns the milestone with its live progress and
+// projected completion date.
+func (s *MilestonesService) handleGetMilestone(w http.ResponseWriter, r *http.Request) {
+	milestone, ok := s.loadMilestone(w, r)
+	if !ok {
+		return
+	}
+
+	progress, err := s.store.GetMilestoneProgress(int(milestone.ID), s.now())
+	if err != nil {
+		http.Error(w, "Error getting milestone progress", http.StatusInternalServerError)
+		return
+	}
+	milestone.Progress = progress
+
+	utils.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// handleUpdateMilestone changes the name, description or target date of
+// an open milestone.
+func (s *MilestonesService) handleUpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		TargetDate  *string `json:"target_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	milestone, ok := s.loadMilestone(w, r)
+	if !ok {
+		return
+	}
+	if milestone.Status == common.MilestoneClosed {
+		http.Error(w, common.ErrMilestoneClosed.Error(), http.StatusConflict)
+		return
+	}
+
+	if payload.Name != nil {
+		milestone.Name = strings.TrimSpace(*payload.Name)
+		if milestone.Name == "" {
+			http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.Description != nil {
+		milestone.Description = *payload.Description
+	}
+	if payload.TargetDate != nil {
+		if _, err := time.Parse(time.DateOnly, *payload.TargetDate); err != nil {
+			http.Error(w, errInvalidTargetDate.Error(), http.StatusBadRequest)
+			return
+		}
+		milestone.TargetDate = *payload.TargetDate
+	}
+
+	if err := s.store.UpdateMilestone(milestone); err != nil {
+		http.Error(w, "Error updating milestone", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// handleCloseMilestone closes the milestone and returns its release notes,
+// the DONE tasks grouped by label.
+func (s *MilestonesService) handleCloseMilestone(w http.ResponseWriter, r *http.Request) {
+	milestone, ok := s.loadMilestone(w, r)
+	if !ok {
+		return
+	}
+
+	milestone, groups, err := s.store.CloseMilestone(int(milestone.ID), s.now())
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errMilestoneNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrMilestoneClosed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error closing milestone", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"milestone":     milestone,
+		"release_notes": groups,
+	})
+}
+
+// handleSetTaskMilestone links a task to a milestone of its project with
+// `{"milestone_id": 3}`, or unlinks it with `{"milestone_id": null}`.
+func (s *MilestonesService) handleSetTaskMilestone(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		MilestoneID *int64 `json:"milestone_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+	if payload.MilestoneID != nil {
+		milestone, err := s.store.GetMilestone(int(*payload.MilestoneID))
+		if errors.Is(err, common.ErrNotFound) {
+			http.Error(w, errMilestoneNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error getting milestone", http.StatusInternalServerError)
+			return
+		}
+		if task.ProjectID == nil || *task.ProjectID != milestone.ProjectID {
+			http.Error(w, errMilestoneProject.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.SetTaskMilestone(id, payload.MilestoneID, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrMilestoneClosed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating task", http.StatusInternalServerError)
+		return
+	}
+
+	task, err = s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+// loadMilestone returns the milestone from the path if the caller belongs
+// to the workspace of its project.
+func (s *MilestonesService) loadMilestone(w http.ResponseWriter, r *http.Request) (*common.Milestone, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	milestone, err := s.store.GetMilestone(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errMilestoneNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting milestone", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	project, err := s.store.GetProject(int(milestone.ProjectID))
+	if err != nil {
+		http.Error(w, "Error getting project", http.StatusInternalServerError)
+		return nil, false
+	}
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(project.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return milestone, true
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func milestoneStore(milestones ...*common.Milestone) *MockStore {
+	mockStore := new(MockStore)
+	for _, milestone := range milestones {
+		mockStore.On("GetMilestone", int(milestone.ID)).Return(milestone, nil)
+	}
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	return mockStore
+}
+
+func TestGetMilestoneIncludesProgress(t *testing.T) {
+	mockStore := milestoneStore(&common.Milestone{ID: 5, ProjectID: 4, Name: "1.4", Status: common.MilestoneOpen})
+	service := NewMilestonesService(mockStore)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	mockStore.On("GetMilestoneProgress", 5, now).Return(&common.MilestoneProgress{
+		TasksDone: 5, TasksTotal: 12, Percent: 41, ProjectedCompletion: "2025-04-30",
+	}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/milestones/5", nil, 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleGetMilestone(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"projected_completion":"2025-04-30"`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateMilestoneRejectsInvalidTargetDate(t *testing.T) {
+	mockStore := milestoneStore()
+	service := NewMilestonesService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/projects/4/milestones", []byte(`{"name": "1.4", "target_date": "June"}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleCreateMilestone(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateMilestone", mock.Anything)
+}
+
+func TestCloseClosedMilestone(t *testing.T) {
+	mockStore := milestoneStore(&common.Milestone{ID: 5, ProjectID: 4, Status: common.MilestoneClosed})
+	service := NewMilestonesService(mockStore)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	mockStore.On("CloseMilestone", 5, now).Return((*common.Milestone)(nil), []common.ReleaseNoteGroup(nil), common.ErrMilestoneClosed)
+
+	req := authorizedRequest(http.MethodPost, "/milestones/5/close", nil, 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleCloseMilestone(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSetTaskMilestoneOfAnotherProject(t *testing.T) {
+	mockStore := milestoneStore(&common.Milestone{ID: 5, ProjectID: 4, Status: common.MilestoneOpen})
+	service := NewMilestonesService(mockStore)
+
+	workspaceID, otherProject := int64(2), int64(8)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID, ProjectID: &otherProject}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/milestone", []byte(`{"milestone_id": 5}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskMilestone(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "SetTaskMilestone", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errInvalidProjectKey = errors.New("key must be 2 to 10 letters or digits starting with a letter, such as API")
+var errProjectExists = errors.New("A project with this key already exists")
+var errProjectNotFound = errors.New("Project not found")
+var errProjectWorkspace = errors.New("a task must be in the workspace of its project")
+var errInvalidWIPLimit = errors.New("wip_limits must map TODO, IN_PROGRESS, IN_TESTING or DONE to a number of tasks")
+var errInvalidStatusMap = errors.New("status_map must map TODO, IN_PROGRESS, IN_TESTING or DONE to one of them")
+
+var projectKey = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+var taskKeyPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]{1,9})-([1-9][0-9]{0,9})$`)
+
+type ProjectsService struct {
+	store common.Store
+}
+
+func NewProjectsService(store common.Store) *ProjectsService {
+	return &ProjectsService{store: store}
+}
+
+func (s *ProjectsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /workspaces/{id}/projects", auth.WithJWTAuth(s.handleGetProjects, s.store))
+	router.HandleFunc("POST /workspaces/{id}/projects", auth.WithJWTAuth(s.handleCreateProject, s.store))
+	router.HandleFunc("GET /projects/{id}", auth.WithJWTAuth(s.handleGetProject, s.store))
+	router.HandleFunc("PATCH /projects/{id}", auth.WithJWTAuth(s.handleUpdateProject, s.store))
+	router.HandleFunc("PUT /tasks/{id}/project", auth.WithJWTAuth(s.handleSetTaskProject, s.store))
+}
+
+func (s *ProjectsService) handleGetProjects(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	projects, err := s.store.GetProjects(workspaceID)
+	if err != nil {
+		http.Error(w, "Error getting projects", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, projects)
+}
+
+func (s *ProjectsService) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	var payload common.Project
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.WorkspaceID = int64(workspaceID)
+	payload.Key = strings.ToUpper(strings.TrimSpace(payload.Key))
+	payload.Name = strings.TrimSpace(payload.Name)
+	if !projectKey.MatchString(payload.Key) {
+		http.Error(w, errInvalidProjectKey.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	project, err := s.store.CreateProject(&payload)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errProjectExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating project", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, project)
+}
+
+func (s *ProjectsService) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, project)
+}
+
+// handleUpdateProject renames the project or sets the WIP limits of its
+// board columns, as in `{"wip_limits": {"IN_PROGRESS": 3}}`. The limits
+// given replace the old ones; a limit of 0 removes it. The key stays the
+// same.
+func (s *ProjectsService) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name      *string        `json:"name"`
+		WIPLimits map[string]int `json:"wip_limits"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	if payload.Name != nil {
+		project.Name = strings.TrimSpace(*payload.Name)
+		if project.Name == "" {
+			http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.WIPLimits != nil {
+		limits := map[string]int{}
+		for status, limit := range payload.WIPLimits {
+			if !slices.Contains(common.Statuses, status) || limit < 0 {
+				http.Error(w, errInvalidWIPLimit.Error(), http.StatusBadRequest)
+				return
+			}
+			if limit > 0 {
+				limits[status] = limit
+			}
+		}
+		project.WIPLimits = limits
+	}
+	if err := s.store.UpdateProject(project); err != nil {
+		http.Error(w, "Error updating project", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, project)
+}
+
+// handleSetTaskProject moves a top-level task, with its subtasks, to another
+// project with `{"project_id": 5}`, or out of its project with
+// `{"project_id": null}`. The tasks get numbers in the new project and
+// their old keys keep resolving to them. A project whose board uses the
+// columns differently gets a status_map, such as
+// `{"IN_TESTING": "IN_PROGRESS"}`, that changes the moved tasks' statuses.
+func (s *ProjectsService) handleSetTaskProject(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		ProjectID *int64            `json:"project_id"`
+		StatusMap map[string]string `json:"status_map"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := validateStatusMap(payload.StatusMap); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	var workspaceID int64
+	if payload.ProjectID != nil {
+		project, err := s.store.GetProject(int(*payload.ProjectID))
+		if errors.Is(err, common.ErrNotFound) {
+			http.Error(w, errProjectNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error getting project", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := checkWorkspaceMember(s.store, w, r, int(project.WorkspaceID), common.WorkspaceRoleMember); !ok {
+			return
+		}
+		workspaceID = project.WorkspaceID
+	} else {
+		if task.ProjectID == nil {
+			utils.WriteJSON(w, http.StatusOK, task)
+			return
+		}
+		workspaceID = *task.WorkspaceID
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err = s.store.MoveTask(id, workspaceID, payload.ProjectID, payload.StatusMap, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrMoveSubtask) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, common.ErrWIPLimit) || errors.Is(err, common.ErrTaskBlocked) ||
+		errors.Is(err, common.ErrOpenSubtasks) || errors.Is(err, common.ErrOpenChecklist) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error moving task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+// validateStatusMap checks that a status map of a move only names
+// statuses.
+func validateStatusMap(statusMap map[string]string) error {
+	for from, to := range statusMap {
+		if !slices.Contains(common.Statuses, from) || !slices.Contains(common.Statuses, to) {
+			return errInvalidStatusMap
+		}
+	}
+	return nil
+}
+
+// loadProject returns the project from the path if the caller belongs to
+// its workspace.
+func loadProject(store common.Store, w http.ResponseWriter, r *http.Request) (*common.Project, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	project, err := store.GetProject(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errProjectNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting project", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if _, ok := checkWorkspaceMember(store, w, r, int(project.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return project, true
+}
+
+// parseTaskKey splits a task key such as API-123 into the project key and
+// the number.
+func parseTaskKey(key string) (string, int64, bool) {
+	m := taskKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		return "", 0, false
+	}
+	number, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToUpper(m[1]), number, true
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTaskByKey(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetTaskByKey", "API", int64(7), 3).Return(&common.Task{ID: 9, AssignedToID: 3, Key: "API-7"}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/api-7", nil, 3)
+	req.SetPathValue("id", "api-7")
+	w := httptest.NewRecorder()
+
+	service.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertNotCalled(t, "GetTask", mock.Anything)
+}
+
+func TestGetTaskByOldKeyRedirects(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetTaskByKey", "API", int64(7), 3).Return(&common.Task{ID: 9, AssignedToID: 3, Key: "WEB-1"}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/API-7", nil, 3)
+	req.SetPathValue("id", "API-7")
+	w := httptest.NewRecorder()
+
+	service.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/tasks/WEB-1", w.Header().Get("Location"))
+}
+
+func TestGetTaskByKeyMovedToOtherWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+	workspaceID := int64(6)
+
+	mockStore.On("GetTaskByKey", "API", int64(7), 3).Return(&common.Task{ID: 9, Key: "WEB-1", WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 6, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/API-7", nil, 3)
+	req.SetPathValue("id", "API-7")
+	w := httptest.NewRecorder()
+
+	service.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestGetTaskByAmbiguousKey(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetTaskByKey", "API", int64(7), 3).Return((*common.Task)(nil), common.ErrAmbiguousKey)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/API-7", nil, 3)
+	req.SetPathValue("id", "API-7")
+	w := httptest.NewRecorder()
+
+	service.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateProjectRejectsInvalidKey(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/workspaces/2/projects", []byte(`{"key": "1API", "name": "API"}`), 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleCreateProject(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateProject", mock.Anything)
+}
+
+func TestCreateTaskInProjectOfAnotherWorkspace(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 5, Key: "API"}, nil)
+
+	body := []byte(`{"name": "Fix login", "assigned_to_id": 3, "workspace_id": 2, "project_id": 4}`)
+	req := authorizedRequest(http.MethodPost, "/tasks", body, 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errProjectWorkspace.Error())
+	mockStore.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
+}
+
+func TestSetTaskProjectMovesTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+	workspaceID, projectID := int64(2), int64(5)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetProject", 5).Return(&common.Project{ID: 5, WorkspaceID: 2, Key: "WEB"}, nil)
+	mockStore.On("MoveTask", 9, workspaceID, &projectID, map[string]string(nil), 3).Return(&common.Task{ID: 9, Key: "WEB-1"}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/project", []byte(`{"project_id": 5}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskProject(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestSetTaskProjectWithStatusMap(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+	workspaceID, projectID := int64(2), int64(5)
+	statusMap := map[string]string{"IN_TESTING": "IN_PROGRESS"}
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetProject", 5).Return(&common.Project{ID: 5, WorkspaceID: 2, Key: "WEB"}, nil)
+	mockStore.On("MoveTask", 9, workspaceID, &projectID, statusMap, 3).Return(&common.Task{ID: 9, Key: "WEB-1"}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/project", []byte(`{"project_id": 5, "status_map": {"IN_TESTING": "IN_PROGRESS"}}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskProject(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestSetTaskProjectAtWIPLimit(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+	workspaceID, projectID := int64(2), int64(5)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetProject", 5).Return(&common.Project{ID: 5, WorkspaceID: 2, Key: "WEB"}, nil)
+	mockStore.On("MoveTask", 9, workspaceID, &projectID, map[string]string(nil), 3).Return((*common.Task)(nil), common.ErrWIPLimit)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/project", []byte(`{"project_id": 5}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskProject(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSetTaskProjectRejectsUnknownStatus(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/project", []byte(`{"project_id": 5, "status_map": {"IN_TESTING": "REVIEW"}}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskProject(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errInvalidStatusMap.Error())
+	mockStore.AssertNotCalled(t, "MoveTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetTaskProjectOfStrangersPersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewProjectsService(mockStore)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/9/project", []byte(`{"project_id": 5}`), 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	service.handleSetTaskProject(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetProject", mock.Anything)
+	mockStore.AssertNotCalled(t, "MoveTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/recur"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"time"
+)
+
+var errRecurrenceStart = errors.New("a recurring task needs a due date or start_at")
+var errRecurrenceNotFound = errors.New("Task does not recur")
+
+type RecurrenceService struct {
+	store common.Store
+}
+
+func NewRecurrenceService(store common.Store) *RecurrenceService {
+	return &RecurrenceService{store: store}
+}
+
+func (s *RecurrenceService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/recurrence", auth.WithJWTAuth(s.handleGetRecurrence, s.store))
+	router.HandleFunc("PUT /tasks/{id}/recurrence", auth.WithJWTAuth(s.handleSetRecurrence, s.store))
+	router.HandleFunc("DELETE /tasks/{id}/recurrence", auth.WithJWTAuth(s.handleDeleteRecurrence, s.store))
+}
+
+func (s *RecurrenceService) handleGetRecurrence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	rec, err := s.store.GetTaskRecurrence(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errRecurrenceNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting recurrence", http.StatusInternalServerError)
+		return
+	}
+
+	setNextAt(rec, task)
+	utils.WriteJSON(w, http.StatusOK, rec)
+}
+
+// handleSetRecurrence makes the task recur by an RRULE such as
+// `FREQ=WEEKLY;BYDAY=MO`. The series starts at start_at, or at the task's
+// due date when start_at is not given.
+func (s *RecurrenceService) handleSetRecurrence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Rule    string     `json:"rule"`
+		StartAt *time.Time `json:"start_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	rule, err := recur.Parse(payload.Rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	start := payload.StartAt
+	if start == nil {
+		start = task.DueAt
+	}
+	if start == nil {
+		http.Error(w, errRecurrenceStart.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	rec, err := s.store.SetTaskRecurrence(&common.Recurrence{TaskID: int64(id), Rule: rule.String(), StartAt: *start}, userID)
+	if err != nil {
+		http.Error(w, "Error setting recurrence", http.StatusInternalServerError)
+		return
+	}
+
+	setNextAt(rec, task)
+	utils.WriteJSON(w, http.StatusOK, rec)
+}
+
+func (s *RecurrenceService) handleDeleteRecurrence(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.DeleteTaskRecurrence(id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errRecurrenceNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting recurrence", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setNextAt fills in when the occurrence after the task will be due, left
+// empty when the series ends with the task.
+func setNextAt(rec *common.Recurrence, task *common.Task) {
+	rule, err := recur.Parse(rec.Rule)
+	if err != nil {
+		return
+	}
+
+	after := time.Now()
+	if task.DueAt != nil && task.DueAt.After(after) {
+		after = *task.DueAt
+	}
+	if next, ok := rule.Next(rec.StartAt, after); ok {
+		rec.NextAt = &next
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetRecurrenceInvalidRule(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRecurrenceService(mockStore)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/recurrence", []byte(`{"rule": "FREQ=HOURLY"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleSetRecurrence(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unsupported FREQ "HOURLY"`)
+	mockStore.AssertNotCalled(t, "SetTaskRecurrence", mock.Anything, mock.Anything)
+}
+
+func TestSetRecurrenceNeedsStart(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRecurrenceService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/recurrence", []byte(`{"rule": "FREQ=WEEKLY"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleSetRecurrence(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), errRecurrenceStart.Error())
+}
+
+func TestSetRecurrenceNormalizesRule(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRecurrenceService(mockStore)
+	due := time.Now().Add(time.Hour)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3, DueAt: &due}, nil)
+	mockStore.On("SetTaskRecurrence", &common.Recurrence{TaskID: 1, Rule: "FREQ=WEEKLY;BYDAY=MO,FR", StartAt: due}, 3).
+		Return(&common.Recurrence{ID: 2, TaskID: 1, Rule: "FREQ=WEEKLY;BYDAY=MO,FR", StartAt: due}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/recurrence", []byte(`{"rule": "RRULE:freq=weekly;byday=fr,mo"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleSetRecurrence(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"next_at"`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestNonMemberCannotReadRecurrence(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRecurrenceService(mockStore)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 4, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/recurrence", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetRecurrence(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetTaskRecurrence", mock.Anything)
+}
+
+func TestRecurrenceSchedulerUsesClock(t *testing.T) {
+	mockStore := new(MockStore)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	scheduler := &RecurrenceScheduler{store: mockStore, interval: time.Hour, now: func() time.Time { return now }}
+
+	full := make([]*common.Task, recurrenceBatch)
+	mockStore.On("AdvanceRecurrences", now, recurrenceBatch).Return(full, nil).Once()
+	mockStore.On("AdvanceRecurrences", now, recurrenceBatch).Return([]*common.Task{}, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scheduler.Run(ctx)
+
+	mockStore.AssertNumberOfCalls(t, "AdvanceRecurrences", 2)
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	maxReminders      = 10
+	maxReminderBefore = 30 * 24 * time.Hour
+)
+
+var errTooManyReminders = errors.New("at most 10 reminders can be set")
+var errInvalidReminder = errors.New("reminders must be durations such as 24h or 0s, at most 720h before the due date")
+
+type RemindersService struct {
+	store common.Store
+}
+
+func NewRemindersService(store common.Store) *RemindersService {
+	return &RemindersService{store: store}
+}
+
+func (s *RemindersService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /users/me/reminders", auth.WithJWTAuth(s.handleGetReminders, s.store))
+	router.HandleFunc("PUT /users/me/reminders", auth.WithJWTAuth(s.handleSetReminders, s.store))
+}
+
+type reminderSettings struct {
+	Before []string `json:"before"`
+}
+
+func (s *RemindersService) handleGetReminders(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	before, err := s.store.GetReminderSettings(userID)
+	if err != nil {
+		http.Error(w, "Error getting reminders", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, newReminderSettings(before))
+}
+
+// handleSetReminders replaces the caller's reminders. Each one is how long
+// before the due date of their tasks they are notified, "0s" meaning at
+// the due time.
+func (s *RemindersService) handleSetReminders(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload reminderSettings
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(payload.Before) > maxReminders {
+		http.Error(w, errTooManyReminders.Error(), http.StatusBadRequest)
+		return
+	}
+	before := make([]time.Duration, 0, len(payload.Before))
+	for _, value := range payload.Before {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || d > maxReminderBefore || d%time.Second != 0 {
+			http.Error(w, errInvalidReminder.Error(), http.StatusBadRequest)
+			return
+		}
+		before = append(before, d)
+	}
+	slices.Sort(before)
+	before = slices.Compact(before)
+
+	if err := s.store.SetReminderSettings(userID, before); err != nil {
+		http.Error(w, "Error setting reminders", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, newReminderSettings(before))
+}
+
+func newReminderSettings(before []time.Duration) reminderSettings {
+	settings := reminderSettings{Before: make([]string, len(before))}
+	for i, d := range before {
+		settings.Before[i] = formatDuration(d)
+	}
+	return settings
+}
+
+// formatDuration writes durations the way users set them, "24h" rather
+// than "24h0m0s".
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
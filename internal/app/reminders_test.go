@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetReminders(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRemindersService(mockStore)
+
+	mockStore.On("SetReminderSettings", 3, []time.Duration{0, 90 * time.Minute, 24 * time.Hour}).Return(nil)
+
+	req := authorizedRequest(http.MethodPut, "/users/me/reminders", []byte(`{"before": ["24h", "0s", "1h30m", "24h"]}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleSetReminders(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"before": ["0s", "1h30m", "24h"]}`, w.Body.String())
+	mockStore.AssertExpectations(t)
+}
+
+func TestSetRemindersRejectsNegative(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewRemindersService(mockStore)
+
+	req := authorizedRequest(http.MethodPut, "/users/me/reminders", []byte(`{"before": ["-1h"]}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleSetReminders(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "SetReminderSettings", mock.Anything, mock.Anything)
+}
+
+func TestJobSchedulerPlansAndRuns(t *testing.T) {
+	mockStore := new(MockStore)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	scheduler := &JobScheduler{store: mockStore, interval: time.Hour, now: func() time.Time { return now }}
+
+	mockStore.On("PlanJobs", now).Return(nil).Once()
+	mockStore.On("RunDueJobs", now, jobBatch).Return(jobBatch, nil).Once()
+	mockStore.On("RunDueJobs", now, jobBatch).Return(3, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scheduler.Run(ctx)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "24h", formatDuration(24*time.Hour))
+	assert.Equal(t, "1h30m", formatDuration(90*time.Minute))
+	assert.Equal(t, "15m", formatDuration(15*time.Minute))
+	assert.Equal(t, "0s", formatDuration(0))
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/blob"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"log"
+	"time"
+)
+
+// recurrenceBatch is how many recurrences one transaction advances.
+const recurrenceBatch = 100
+
+// jobBatch is how many jobs one tick runs before checking for more.
+const jobBatch = 100
+
+// trashBatch is how many trashed tasks one transaction purges.
+const trashBatch = 100
+
+// RecurrenceScheduler creates the next occurrence of recurring tasks. Every
+// server runs one; the store makes sure each occurrence is created once.
+type RecurrenceScheduler struct {
+	store    common.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewRecurrenceScheduler(store common.Store) *RecurrenceScheduler {
+	return &RecurrenceScheduler{
+		store:    store,
+		interval: common.Envs.RecurrenceInterval,
+		now:      time.Now,
+	}
+}
+
+// Run advances due recurrences right away and then every interval until
+// ctx is cancelled.
+func (s *RecurrenceScheduler) Run(ctx context.Context) {
+	runEvery(ctx, s.interval, s.tick)
+}
+
+// tick advances recurrences in batches until none are left.
+func (s *RecurrenceScheduler) tick() {
+	now := s.now()
+	for {
+		created, err := s.store.AdvanceRecurrences(now, recurrenceBatch)
+		if err != nil {
+			log.Println("Error advancing recurring tasks:", err)
+			return
+		}
+		if len(created) < recurrenceBatch {
+			return
+		}
+	}
+}
+
+// JobScheduler plans reminders and escalations and runs the jobs that are
+// due. Jobs live in the database, so they survive restarts, and every
+// server can run a JobScheduler without running a job twice.
+type JobScheduler struct {
+	store    common.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewJobScheduler(store common.Store) *JobScheduler {
+	return &JobScheduler{
+		store:    store,
+		interval: common.Envs.JobInterval,
+		now:      time.Now,
+	}
+}
+
+func (s *JobScheduler) Run(ctx context.Context) {
+	runEvery(ctx, s.interval, s.tick)
+}
+
+func (s *JobScheduler) tick() {
+	now := s.now()
+	if err := s.store.PlanJobs(now); err != nil {
+		log.Println("Error planning jobs:", err)
+	}
+	for {
+		ran, err := s.store.RunDueJobs(now, jobBatch)
+		if err != nil {
+			log.Println("Error running jobs:", err)
+			return
+		}
+		if ran < jobBatch {
+			return
+		}
+	}
+}
+
+// TrashPurger permanently deletes tasks that have been in the trash for
+// longer than the retention period, and the files attached to them.
+type TrashPurger struct {
+	store     common.Store
+	blobs     blob.Store
+	interval  time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewTrashPurger(store common.Store, blobs blob.Store) *TrashPurger {
+	return &TrashPurger{
+		store:     store,
+		blobs:     blobs,
+		interval:  common.Envs.TrashPurgeInterval,
+		retention: common.Envs.TrashRetention,
+		now:       time.Now,
+	}
+}
+
+func (s *TrashPurger) Run(ctx context.Context) {
+	runEvery(ctx, s.interval, func() { s.tick(ctx) })
+}
+
+// tick purges expired tasks in batches until none are left.
+func (s *TrashPurger) tick(ctx context.Context) {
+	before := s.now().Add(-s.retention)
+	for {
+		purged, keys, err := s.store.PurgeTrash(before, trashBatch)
+		if err != nil {
+			log.Println("Error purging trash:", err)
+			return
+		}
+		// The rows are gone, so a failure here only leaves an unreachable blob.
+		for _, key := range keys {
+			if err := s.blobs.Delete(ctx, key); err != nil {
+				log.Println(err)
+			}
+		}
+		if purged < trashBatch {
+			return
+		}
+	}
+}
+
+// Archiver archives tasks that have been DONE for longer than their
+// workspace's auto_archive_days.
+type Archiver struct {
+	store    common.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewArchiver(store common.Store) *Archiver {
+	return &Archiver{
+		store:    store,
+		interval: common.Envs.ArchiveInterval,
+		now:      time.Now,
+	}
+}
+
+func (s *Archiver) Run(ctx context.Context) {
+	runEvery(ctx, s.interval, s.tick)
+}
+
+func (s *Archiver) tick() {
+	if _, err := s.store.AutoArchiveTasks(s.now()); err != nil {
+		log.Println("Error archiving tasks:", err)
+	}
+}
+
+// runEvery calls fn right away and then every interval until ctx is
+// cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strconv"
+)
+
+type SearchService struct {
+	store common.Store
+}
+
+func NewSearchService(store common.Store) *SearchService {
+	return &SearchService{store: store}
+}
+
+func (s *SearchService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /search", auth.WithJWTAuth(s.handleSearch, s.store))
+}
+
+func (s *SearchService) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "Missing 'q' parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			http.Error(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	results, err := s.store.SearchTasks(q, limit, userID)
+	if errors.Is(err, search.ErrEmptyQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error searching tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, results)
+}
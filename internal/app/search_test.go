@@ -0,0 +1,55 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchAsCaller(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSearchService(mockStore)
+
+	mockStore.On("SearchTasks", "login", 5, 3).
+		Return([]*common.SearchResult{{Task: &common.Task{ID: 7, Name: "Fix login page"}, Score: 1.5}}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/search?q=login&limit=5", nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleSearch(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Fix login page"`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestSearchRejectsInvalidLimit(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSearchService(mockStore)
+
+	req := authorizedRequest(http.MethodGet, "/search?q=login&limit=-1", nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleSearch(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "SearchTasks", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSearchWithEmptyQuery(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSearchService(mockStore)
+
+	mockStore.On("SearchTasks", `""`, 0, 3).Return([]*common.SearchResult(nil), search.ErrEmptyQuery)
+
+	req := authorizedRequest(http.MethodGet, `/search?q=%22%22`, nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleSearch(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
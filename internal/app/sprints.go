@@ -0,0 +1,363 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errInvalidSprintDates = errors.New("start_date and end_date must be dates such as 2025-03-03, with end_date not before start_date")
+var errSprintNotFound = errors.New("Sprint not found")
+var errSprintTaskProject = errors.New("the task must be in the project of the sprint")
+var errCarryOverSprint = errors.New("carry_over_to must be another planned or active sprint of the same project")
+
+type SprintsService struct {
+	store common.Store
+	now   func() time.Time
+}
+
+func NewSprintsService(store common.Store) *SprintsService {
+	return &SprintsService{store: store, now: time.Now}
+}
+
+func (s *SprintsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /projects/{id}/sprints", auth.WithJWTAuth(s.handleGetSprints, s.store))
+	router.HandleFunc("POST /projects/{id}/sprints", auth.WithJWTAuth(s.handleCreateSprint, s.store))
+	router.HandleFunc("GET /projects/{id}/backlog", auth.WithJWTAuth(s.handleGetBacklog, s.store))
+	router.HandleFunc("GET /sprints/{id}", auth.WithJWTAuth(s.handleGetSprint, s.store))
+	router.HandleFunc("PATCH /sprints/{id}", auth.WithJWTAuth(s.handleUpdateSprint, s.store))
+	router.HandleFunc("GET /sprints/{id}/tasks", auth.WithJWTAuth(s.handleGetSprintTasks, s.store))
+	router.HandleFunc("POST /sprints/{id}/tasks", auth.WithJWTAuth(s.handleAddSprintTask, s.store))
+	router.HandleFunc("DELETE /sprints/{id}/tasks/{taskID}", auth.WithJWTAuth(s.handleRemoveSprintTask, s.store))
+	router.HandleFunc("POST /sprints/{id}/start", auth.WithJWTAuth(s.handleStartSprint, s.store))
+	router.HandleFunc("POST /sprints/{id}/complete", auth.WithJWTAuth(s.handleCompleteSprint, s.store))
+}
+
+func (s *SprintsService) handleGetSprints(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	sprints, err := s.store.GetSprints(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error getting sprints", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sprints)
+}
+
+func (s *SprintsService) handleCreateSprint(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	var payload common.Sprint
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.ProjectID = project.ID
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	if !validSprintDates(payload.StartDate, payload.EndDate) {
+		http.Error(w, errInvalidSprintDates.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sprint, err := s.store.CreateSprint(&payload)
+	if err != nil {
+		http.Error(w, "Error creating sprint", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, sprint)
+}
+
+// handleGetBacklog lists the unfinished tasks of the project that are not
+// planned into a sprint.
+func (s *SprintsService) handleGetBacklog(w http.ResponseWriter, r *http.Request) {
+	project, ok := loadProject(s.store, w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.GetBacklog(int(project.ID))
+	if err != nil {
+		http.Error(w, "Error getting backlog", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+func (s *SprintsService) handleGetSprint(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sprint)
+}
+
+// handleUpdateSprint changes the name, goal or dates of a sprint that is
+// not completed yet.
+func (s *SprintsService) handleUpdateSprint(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name      *string `json:"name"`
+		Goal      *string `json:"goal"`
+		StartDate *string `json:"start_date"`
+		EndDate   *string `json:"end_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+	if sprint.Status == common.SprintCompleted {
+		http.Error(w, common.ErrSprintState.Error(), http.StatusConflict)
+		return
+	}
+
+	if payload.Name != nil {
+		sprint.Name = strings.TrimSpace(*payload.Name)
+		if sprint.Name == "" {
+			http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.Goal != nil {
+		sprint.Goal = *payload.Goal
+	}
+	if payload.StartDate != nil {
+		sprint.StartDate = *payload.StartDate
+	}
+	if payload.EndDate != nil {
+		sprint.EndDate = *payload.EndDate
+	}
+	if !validSprintDates(sprint.StartDate, sprint.EndDate) {
+		http.Error(w, errInvalidSprintDates.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.UpdateSprint(sprint); err != nil {
+		http.Error(w, "Error updating sprint", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sprint)
+}
+
+func (s *SprintsService) handleGetSprintTasks(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := s.store.GetSprintTasks(int(sprint.ID))
+	if err != nil {
+		http.Error(w, "Error getting sprint tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// handleAddSprintTask adds a task of the sprint's project to the sprint
+// with `{"task_id": 9}`.
+func (s *SprintsService) handleAddSprintTask(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		TaskID int `json:"task_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := s.store.GetTask(payload.TaskID)
+	if err != nil {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if task.ProjectID == nil || *task.ProjectID != sprint.ProjectID {
+		http.Error(w, errSprintTaskProject.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.AddSprintTask(int(sprint.ID), payload.TaskID, userID)
+	if err != nil {
+		writeSprintError(w, err, errTaskNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SprintsService) handleRemoveSprintTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := pathID(r, "taskID")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.RemoveSprintTask(int(sprint.ID), taskID, userID)
+	if err != nil {
+		writeSprintError(w, err, errTaskNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SprintsService) handleStartSprint(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	sprint, err := s.store.StartSprint(int(sprint.ID), s.now())
+	if err != nil {
+		writeSprintError(w, err, errSprintNotFound)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sprint)
+}
+
+// handleCompleteSprint completes an active sprint. Its unfinished tasks
+// move to the sprint given as `{"carry_over_to": 12}`, or back to the
+// backlog without it.
+func (s *SprintsService) handleCompleteSprint(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		CarryOverTo *int64 `json:"carry_over_to"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Error parsing request body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	sprint, ok := s.loadSprint(w, r)
+	if !ok {
+		return
+	}
+
+	if payload.CarryOverTo != nil {
+		next, err := s.store.GetSprint(int(*payload.CarryOverTo))
+		if errors.Is(err, common.ErrNotFound) {
+			http.Error(w, errSprintNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error getting sprint", http.StatusInternalServerError)
+			return
+		}
+		if next.ID == sprint.ID || next.ProjectID != sprint.ProjectID || next.Status == common.SprintCompleted {
+			http.Error(w, errCarryOverSprint.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	carried, err := s.store.CompleteSprint(int(sprint.ID), payload.CarryOverTo, userID, s.now())
+	if err != nil {
+		writeSprintError(w, err, errSprintNotFound)
+		return
+	}
+
+	sprint, err = s.store.GetSprint(int(sprint.ID))
+	if err != nil {
+		http.Error(w, "Error getting sprint", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"sprint":        sprint,
+		"carried_over":  carried,
+		"carry_over_to": payload.CarryOverTo,
+	})
+}
+
+// loadSprint returns the sprint from the path if the caller belongs to the
+// workspace of its project.
+func (s *SprintsService) loadSprint(w http.ResponseWriter, r *http.Request) (*common.Sprint, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	sprint, err := s.store.GetSprint(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errSprintNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting sprint", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	project, err := s.store.GetProject(int(sprint.ProjectID))
+	if err != nil {
+		http.Error(w, "Error getting project", http.StatusInternalServerError)
+		return nil, false
+	}
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(project.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return sprint, true
+}
+
+// writeSprintError writes the response for a failed sprint change.
+// notFound is the message for ErrNotFound, which is about the task or the
+// sprint depending on the change.
+func writeSprintError(w http.ResponseWriter, err error, notFound error) {
+	switch {
+	case errors.Is(err, common.ErrNotFound):
+		http.Error(w, notFound.Error(), http.StatusNotFound)
+	case errors.Is(err, common.ErrSprintState), errors.Is(err, common.ErrInActiveSprint):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error updating sprint", http.StatusInternalServerError)
+	}
+}
+
+func validSprintDates(start, end string) bool {
+	from, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return false
+	}
+	to, err := time.Parse(time.DateOnly, end)
+	return err == nil && !to.Before(from)
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sprintStore(sprints ...*common.Sprint) *MockStore {
+	mockStore := new(MockStore)
+	for _, sprint := range sprints {
+		mockStore.On("GetSprint", int(sprint.ID)).Return(sprint, nil)
+	}
+	mockStore.On("GetProject", 4).Return(&common.Project{ID: 4, WorkspaceID: 2, Key: "API", Name: "API"}, nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	return mockStore
+}
+
+func TestCreateSprintRejectsEndBeforeStart(t *testing.T) {
+	mockStore := sprintStore()
+	service := NewSprintsService(mockStore)
+
+	body := []byte(`{"name": "Sprint 12", "start_date": "2025-03-17", "end_date": "2025-03-03"}`)
+	req := authorizedRequest(http.MethodPost, "/projects/4/sprints", body, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleCreateSprint(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateSprint", mock.Anything)
+}
+
+func TestAddSprintTaskOfAnotherProject(t *testing.T) {
+	mockStore := sprintStore(&common.Sprint{ID: 5, ProjectID: 4, Status: common.SprintPlanned})
+	service := NewSprintsService(mockStore)
+
+	otherProject := int64(8)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, ProjectID: &otherProject}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/sprints/5/tasks", []byte(`{"task_id": 9}`), 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleAddSprintTask(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "AddSprintTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddSprintTaskInAnotherActiveSprint(t *testing.T) {
+	mockStore := sprintStore(&common.Sprint{ID: 5, ProjectID: 4, Status: common.SprintActive})
+	service := NewSprintsService(mockStore)
+
+	projectID := int64(4)
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, AssignedToID: 3, ProjectID: &projectID}, nil)
+	mockStore.On("AddSprintTask", 5, 9, 3).Return(common.ErrInActiveSprint)
+
+	req := authorizedRequest(http.MethodPost, "/sprints/5/tasks", []byte(`{"task_id": 9}`), 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleAddSprintTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCompleteSprintCarriesOverToNextSprint(t *testing.T) {
+	sprint := &common.Sprint{ID: 5, ProjectID: 4, Status: common.SprintActive}
+	mockStore := sprintStore(sprint, &common.Sprint{ID: 6, ProjectID: 4, Status: common.SprintPlanned})
+	service := NewSprintsService(mockStore)
+	now := time.Date(2025, 3, 14, 17, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	next := int64(6)
+	mockStore.On("CompleteSprint", 5, &next, 3, now).Return([]int64{10, 11}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/sprints/5/complete", []byte(`{"carry_over_to": 6}`), 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleCompleteSprint(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"carried_over":[10,11]`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCompleteSprintCarryOverToItself(t *testing.T) {
+	mockStore := sprintStore(&common.Sprint{ID: 5, ProjectID: 4, Status: common.SprintActive})
+	service := NewSprintsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/sprints/5/complete", []byte(`{"carry_over_to": 5}`), 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleCompleteSprint(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CompleteSprint", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
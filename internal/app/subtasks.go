@@ -0,0 +1,168 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+type SubtasksService struct {
+	store common.Store
+}
+
+func NewSubtasksService(store common.Store) *SubtasksService {
+	return &SubtasksService{store: store}
+}
+
+func (s *SubtasksService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /tasks/{id}/subtasks", auth.WithJWTAuth(s.handleGetSubtasks, s.store))
+	router.HandleFunc("PUT /tasks/{id}/subtasks/order", auth.WithJWTAuth(s.handleReorderSubtasks, s.store))
+	router.HandleFunc("PUT /tasks/{id}/parent", auth.WithJWTAuth(s.handleSetParent, s.store))
+}
+
+func (s *SubtasksService) handleGetSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	tasks, err := s.store.GetSubtasks(id)
+	if err != nil {
+		http.Error(w, "Error getting subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+func (s *SubtasksService) handleReorderSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	if err := s.store.ReorderSubtasks(id, payload.IDs); err != nil {
+		writeHierarchyError(w, err)
+		return
+	}
+
+	tasks, err := s.store.GetSubtasks(id)
+	if err != nil {
+		http.Error(w, "Error getting subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// handleSetParent moves the task under another task, or to the top level
+// when parent_id is null. Both tasks must be in the same workspace.
+func (s *SubtasksService) handleSetParent(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		ParentID *int64 `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	if payload.ParentID != nil {
+		parent, err := s.store.GetTask(int(*payload.ParentID))
+		if err != nil {
+			http.Error(w, errParentTaskNotFound.Error(), http.StatusBadRequest)
+			return
+		}
+		if !sameWorkspace(task.WorkspaceID, parent.WorkspaceID) {
+			http.Error(w, errParentWorkspace.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := checkTaskAccess(s.store, w, r, parent, common.WorkspaceRoleMember); !ok {
+			return
+		}
+	}
+
+	if err := s.store.SetTaskParent(id, payload.ParentID, userID); err != nil {
+		writeHierarchyError(w, err)
+		return
+	}
+
+	task, err = s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+func writeHierarchyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, common.ErrNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+	case errors.Is(err, common.ErrTaskCycle), errors.Is(err, common.ErrTaskTooDeep), errors.Is(err, common.ErrInvalidOrder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error updating subtasks", http.StatusInternalServerError)
+	}
+}
+
+func sameWorkspace(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
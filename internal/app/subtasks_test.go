@@ -0,0 +1,102 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForceDoneRequiresWorkspaceAdmin(t *testing.T) {
+	mockStore := new(MockStore)
+	taskService := NewTaskService(mockStore)
+	workspaceID := int64(4)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 3).Return(&common.WorkspaceMember{WorkspaceID: 4, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1?force=true", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	taskService.updateTaskStatus(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateTaskStatusByID", mock.Anything, mock.Anything)
+}
+
+func TestNonMemberCannotAdvanceStatus(t *testing.T) {
+	mockStore := new(MockStore)
+	taskService := NewTaskService(mockStore)
+	workspaceID := int64(4)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	taskService.updateTaskStatus(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "UpdateTaskStatusByID", mock.Anything, mock.Anything)
+}
+
+func TestSetParentAcrossWorkspaces(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSubtasksService(mockStore)
+	workspaceID := int64(4)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 3).Return(&common.WorkspaceMember{WorkspaceID: 4, UserID: 3, Role: common.WorkspaceRoleMember}, nil)
+	mockStore.On("GetTask", 2).Return(&common.Task{ID: 2, AssignedToID: 3}, nil)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/parent", []byte(`{"parent_id": 2}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleSetParent(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "SetTaskParent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetParentCycle(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSubtasksService(mockStore)
+	parentID := int64(2)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("GetTask", 2).Return(&common.Task{ID: 2, AssignedToID: 3}, nil)
+	mockStore.On("SetTaskParent", 1, &parentID, 3).Return(common.ErrTaskCycle)
+
+	req := authorizedRequest(http.MethodPut, "/tasks/1/parent", []byte(`{"parent_id": 2}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleSetParent(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), common.ErrTaskCycle.Error())
+}
+
+func TestNonMemberCannotSeeSubtasks(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewSubtasksService(mockStore)
+	workspaceID := int64(4)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, WorkspaceID: &workspaceID}, nil)
+	mockStore.On("GetWorkspaceMember", 4, 3).Return((*common.WorkspaceMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/1/subtasks", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleGetSubtasks(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetSubtasks", mock.Anything)
+}
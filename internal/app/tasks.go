@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
 	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
 	"io"
 	"net/http"
@@ -13,6 +14,12 @@ import (
 
 var errNameRequired = errors.New("name is required")
 var errUserIDRequired = errors.New("user id is required")
+var errInvalidPriority = errors.New("priority must be one of P0, P1, P2, P3")
+var errParentTaskNotFound = errors.New("Parent task not found")
+var errParentWorkspace = errors.New("a subtask must be in the workspace of its parent")
+var errInvalidDueAt = errors.New("due_at must be an RFC 3339 time or null")
+var errInvalidPoints = errors.New("points must be a number from 0 to 1000 or null")
+var errForceRequiresWorkspace = errors.New("only tasks in a workspace can be forced to DONE")
 
 type TaskService struct {
 	store common.Store
@@ -27,6 +34,8 @@ func (s *TaskService) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("POST /tasks", auth.WithJWTAuth(s.handleCreateTask, s.store))
 	router.HandleFunc("GET /tasks/{id}", auth.WithJWTAuth(s.handleGetTask, s.store))
 	router.HandleFunc("POST /tasks/{id}", auth.WithJWTAuth(s.updateTaskStatus, s.store))
+	router.HandleFunc("PATCH /tasks/{id}", auth.WithJWTAuth(s.handleUpdateTask, s.store))
+	router.HandleFunc("DELETE /tasks/{id}", auth.WithJWTAuth(s.handleDeleteTask, s.store))
 }
 
 func (s *TaskService) handleCreateTask(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +59,67 @@ func (s *TaskService) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := s.store.CreateTask(&payload)
+	// Subtasks live in the workspace of their parent, and in its project
+	// unless they name another one.
+	if payload.ParentID != nil {
+		parent, err := s.store.GetTask(int(*payload.ParentID))
+		if err != nil {
+			http.Error(w, errParentTaskNotFound.Error(), http.StatusBadRequest)
+			return
+		}
+		if payload.WorkspaceID == nil {
+			payload.WorkspaceID = parent.WorkspaceID
+		}
+		if !sameWorkspace(payload.WorkspaceID, parent.WorkspaceID) {
+			http.Error(w, errParentWorkspace.Error(), http.StatusBadRequest)
+			return
+		}
+		if payload.ProjectID == nil {
+			payload.ProjectID = parent.ProjectID
+		}
+	}
+
+	if payload.ProjectID != nil {
+		project, err := s.store.GetProject(int(*payload.ProjectID))
+		if err != nil {
+			http.Error(w, errProjectNotFound.Error(), http.StatusBadRequest)
+			return
+		}
+		if payload.WorkspaceID == nil {
+			payload.WorkspaceID = &project.WorkspaceID
+		}
+		if *payload.WorkspaceID != project.WorkspaceID {
+			http.Error(w, errProjectWorkspace.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if payload.WorkspaceID != nil {
+		userID, _ := auth.GetUserIDFromRequest(r)
+		if _, err := s.store.GetWorkspaceMember(int(*payload.WorkspaceID), userID); err != nil {
+			http.Error(w, errWorkspaceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	// The primary assignee defaults to the caller, but one given in the
+	// payload is checked like the others.
+	assignees := append([]int64{payload.AssignedToID}, payload.AssigneeIDs...)
+	if err := checkAssignees(s.store, payload.WorkspaceID, assignees); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !writeCustomFieldsError(w, checkCustomFields(s.store, payload.ProjectID, payload.WorkspaceID, payload.CustomFields)) {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err := s.store.CreateTask(&payload, userID)
+	if errors.Is(err, common.ErrTaskTooDeep) || errors.Is(err, common.ErrUnknownField) || errors.Is(err, common.ErrInvalidFieldValue) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error creating task", http.StatusInternalServerError)
 		return
@@ -60,6 +129,9 @@ func (s *TaskService) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// handleGetTask returns the task by its id or by its key, such as API-123.
+// Keys a task had before it moved to another project redirect to its
+// current key.
 func (s *TaskService) handleGetTask(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	if idStr == "" {
@@ -67,6 +139,11 @@ func (s *TaskService) handleGetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if projectKey, number, ok := parseTaskKey(idStr); ok {
+		s.getTaskByKey(w, r, projectKey, number)
+		return
+	}
+
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid 'id' parameter", http.StatusBadRequest)
@@ -78,15 +155,179 @@ func (s *TaskService) handleGetTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Task not found", http.StatusInternalServerError)
 		return
 	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+func (s *TaskService) getTaskByKey(w http.ResponseWriter, r *http.Request, projectKey string, number int64) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	task, err := s.store.GetTaskByKey(projectKey, number, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrAmbiguousKey) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+	// An old key can lead to a task that has moved to another workspace.
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	if task.Key != projectKey+"-"+strconv.FormatInt(number, 10) {
+		target := task.Key
+		if target == "" {
+			target = strconv.FormatInt(task.ID, 10)
+		}
+		http.Redirect(w, r, "/tasks/"+target, http.StatusMovedPermanently)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
+
+// handleUpdateTask edits the name, description, priority, due date,
+// points or custom fields of the task. Fields left out are kept, and
+// `"due_at": null` and `"points": null` clear the due date and the points.
+// Custom fields are set by name, and a null value clears one.
+func (s *TaskService) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Name         *string         `json:"name"`
+		Description  *string         `json:"description"`
+		Priority     *string         `json:"priority"`
+		DueAt        json.RawMessage `json:"due_at"`
+		Points       json.RawMessage `json:"points"`
+		CustomFields map[string]any  `json:"custom_fields"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	patch := common.TaskPatch{Name: payload.Name, Description: payload.Description, Priority: payload.Priority,
+		CustomFields: payload.CustomFields}
+	if patch.Name != nil && *patch.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	if patch.Priority != nil {
+		switch *patch.Priority {
+		case "P0", "P1", "P2", "P3":
+		default:
+			http.Error(w, errInvalidPriority.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(payload.DueAt) > 0 {
+		if err := json.Unmarshal(payload.DueAt, &patch.DueAt); err != nil {
+			http.Error(w, errInvalidDueAt.Error(), http.StatusBadRequest)
+			return
+		}
+		patch.ClearDueAt = patch.DueAt == nil
+	}
+	if len(payload.Points) > 0 {
+		if err := json.Unmarshal(payload.Points, &patch.Points); err != nil || !validPoints(patch.Points) {
+			http.Error(w, errInvalidPoints.Error(), http.StatusBadRequest)
+			return
+		}
+		patch.ClearPoints = patch.Points == nil
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+	if !writeCustomFieldsError(w, checkCustomFields(s.store, task.ProjectID, task.WorkspaceID, patch.CustomFields)) {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err = s.store.UpdateTask(id, patch, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrUnknownField) || errors.Is(err, common.ErrInvalidFieldValue) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating task", http.StatusInternalServerError)
+		return
+	}
 
 	utils.WriteJSON(w, http.StatusOK, task)
 }
 
+// handleDeleteTask moves the task and its subtasks to the trash, from where
+// they can be restored until they are purged.
+func (s *TaskService) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	err = s.store.DeleteTask(id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting task", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func validateTaskPayload(task *common.Task, r *http.Request) error {
 	if task.Status == "" {
 		task.Status = "TODO"
 	}
 
+	switch task.Priority {
+	case "":
+		task.Priority = "P2"
+	case "P0", "P1", "P2", "P3":
+	default:
+		return errInvalidPriority
+	}
+
 	if task.AssignedToID == 0 {
 		id, err := auth.GetUserIDFromRequest(r)
 		if err != nil {
@@ -99,9 +340,20 @@ func validateTaskPayload(task *common.Task, r *http.Request) error {
 		return errNameRequired
 	}
 
+	if !validPoints(task.Points) {
+		return errInvalidPoints
+	}
+
 	return nil
 }
 
+// maxPoints bounds the story points of a task.
+const maxPoints = 1000
+
+func validPoints(points *int) bool {
+	return points == nil || *points >= 0 && *points <= maxPoints
+}
+
 func (s *TaskService) updateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	if idStr == "" {
@@ -115,11 +367,46 @@ func (s *TaskService) updateTaskStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := s.store.UpdateTaskStatusByID(id)
+	task, err := s.store.GetTask(id)
 	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	// ?force=true lets workspace admins close a task with open subtasks or
+	// checklist items.
+	opts := common.StatusUpdate{Force: r.URL.Query().Get("force") == "true"}
+	role := common.WorkspaceRoleMember
+	if opts.Force {
+		if task.WorkspaceID == nil {
+			http.Error(w, errForceRequiresWorkspace.Error(), http.StatusForbidden)
+			return
+		}
+		role = common.WorkspaceRoleAdmin
+	}
+	userID, ok := checkTaskAccess(s.store, w, r, task, role)
+	if !ok {
+		return
+	}
+	opts.ActorID = int64(userID)
+
+	task, err = s.store.UpdateTaskStatusByID(id, opts)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrOpenSubtasks) || errors.Is(err, common.ErrOpenChecklist) || errors.Is(err, common.ErrTaskBlocked) ||
+		errors.Is(err, common.ErrWIPLimit) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, common.ErrTaskDone) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Error updating task status", http.StatusInternalServerError)
+		return
+	}
 
 	utils.WriteJSON(w, http.StatusOK, task)
 }
@@ -131,6 +418,11 @@ func (s *TaskService) getTasksAssignedToUser(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if r.URL.Query().Has("q") || r.URL.Query().Has("label") || r.URL.Query().Has("sort") {
+		s.queryTasks(w, r, id)
+		return
+	}
+
 	tasks, err := s.store.GetTasksAssignedToUser(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -139,3 +431,35 @@ func (s *TaskService) getTasksAssignedToUser(w http.ResponseWriter, r *http.Requ
 
 	utils.WriteJSON(w, http.StatusOK, tasks)
 }
+
+func (s *TaskService) queryTasks(w http.ResponseWriter, r *http.Request, userID int) {
+	q, err := query.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Sort, err = query.ParseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Every ?label= parameter adds a label clause, so ?label=a&label=b
+	// only matches tasks carrying both labels.
+	for _, label := range r.URL.Query()["label"] {
+		q.Clauses = append(q.Clauses, query.Clause{Pos: 1, Field: "label", Op: query.OpEqual, Values: []string{label}})
+	}
+
+	tasks, err := s.store.QueryTasks(q, userID)
+	var queryErr *query.Error
+	if errors.As(err, &queryErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error querying tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
@@ -3,12 +3,15 @@ package app
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type MockStore struct {
@@ -25,8 +28,8 @@ func (m *MockStore) GetUserByID(id int) (*common.User, error) {
 	return args.Get(0).(*common.User), args.Error(1)
 }
 
-func (m *MockStore) CreateTask(task *common.Task) (*common.Task, error) {
-	args := m.Called(task)
+func (m *MockStore) CreateTask(task *common.Task, actorID int) (*common.Task, error) {
+	args := m.Called(task, actorID)
 	return args.Get(0).(*common.Task), args.Error(1)
 }
 
@@ -35,8 +38,8 @@ func (m *MockStore) GetTask(id int) (*common.Task, error) {
 	return args.Get(0).(*common.Task), args.Error(1)
 }
 
-func (m *MockStore) UpdateTaskStatusByID(id int) (*common.Task, error) {
-	args := m.Called(id)
+func (m *MockStore) UpdateTaskStatusByID(id int, opts common.StatusUpdate) (*common.Task, error) {
+	args := m.Called(id, opts)
 	return args.Get(0).(*common.Task), args.Error(1)
 }
 
@@ -45,6 +48,626 @@ func (m *MockStore) GetTasksAssignedToUser(id int) ([]*common.Task, error) {
 	return args.Get(0).([]*common.Task), args.Error(1)
 }
 
+func (m *MockStore) QueryTasks(q *query.Query, userID int) ([]*common.Task, error) {
+	args := m.Called(q, userID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) SearchTasks(query string, limit, userID int) ([]*common.SearchResult, error) {
+	args := m.Called(query, limit, userID)
+	return args.Get(0).([]*common.SearchResult), args.Error(1)
+}
+
+func (m *MockStore) CreateTeam(team *common.Team, creatorID int) (*common.Team, error) {
+	args := m.Called(team, creatorID)
+	return args.Get(0).(*common.Team), args.Error(1)
+}
+
+func (m *MockStore) GetTeamsForUser(userID int) ([]*common.Team, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*common.Team), args.Error(1)
+}
+
+func (m *MockStore) GetTeamMember(teamID, userID int) (*common.TeamMember, error) {
+	args := m.Called(teamID, userID)
+	return args.Get(0).(*common.TeamMember), args.Error(1)
+}
+
+func (m *MockStore) GetTeamMembers(teamID int) ([]*common.TeamMember, error) {
+	args := m.Called(teamID)
+	return args.Get(0).([]*common.TeamMember), args.Error(1)
+}
+
+func (m *MockStore) AddTeamMember(member *common.TeamMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateView(v *common.View) (*common.View, error) {
+	args := m.Called(v)
+	return args.Get(0).(*common.View), args.Error(1)
+}
+
+func (m *MockStore) GetView(id, userID int) (*common.View, error) {
+	args := m.Called(id, userID)
+	return args.Get(0).(*common.View), args.Error(1)
+}
+
+func (m *MockStore) GetViewsForUser(userID int) ([]*common.View, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*common.View), args.Error(1)
+}
+
+func (m *MockStore) UpdateView(v *common.View) error {
+	args := m.Called(v)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteView(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetViewPinned(viewID, userID int, pinned bool) error {
+	args := m.Called(viewID, userID, pinned)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateWorkspace(ws *common.Workspace, creatorID int) (*common.Workspace, error) {
+	args := m.Called(ws, creatorID)
+	return args.Get(0).(*common.Workspace), args.Error(1)
+}
+
+func (m *MockStore) GetWorkspacesForUser(userID int) ([]*common.Workspace, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*common.Workspace), args.Error(1)
+}
+
+func (m *MockStore) GetWorkspaceMember(workspaceID, userID int) (*common.WorkspaceMember, error) {
+	args := m.Called(workspaceID, userID)
+	return args.Get(0).(*common.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockStore) GetWorkspaceMembers(workspaceID int) ([]*common.WorkspaceMember, error) {
+	args := m.Called(workspaceID)
+	return args.Get(0).([]*common.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockStore) AddWorkspaceMember(member *common.WorkspaceMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateLabel(l *common.Label) (*common.Label, error) {
+	args := m.Called(l)
+	return args.Get(0).(*common.Label), args.Error(1)
+}
+
+func (m *MockStore) GetLabel(id int) (*common.Label, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Label), args.Error(1)
+}
+
+func (m *MockStore) GetLabels(workspaceID int) ([]*common.Label, error) {
+	args := m.Called(workspaceID)
+	return args.Get(0).([]*common.Label), args.Error(1)
+}
+
+func (m *MockStore) UpdateLabel(l *common.Label) error {
+	args := m.Called(l)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteLabel(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) AddTaskLabel(taskID, labelID, actorID int) error {
+	args := m.Called(taskID, labelID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveTaskLabel(taskID, labelID, actorID int) error {
+	args := m.Called(taskID, labelID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateComment(c *common.Comment) (*common.Comment, error) {
+	args := m.Called(c)
+	return args.Get(0).(*common.Comment), args.Error(1)
+}
+
+func (m *MockStore) GetComment(id int) (*common.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Comment), args.Error(1)
+}
+
+func (m *MockStore) GetComments(taskID, after, limit int) ([]*common.Comment, error) {
+	args := m.Called(taskID, after, limit)
+	return args.Get(0).([]*common.Comment), args.Error(1)
+}
+
+func (m *MockStore) UpdateComment(id int, body string) (*common.Comment, error) {
+	args := m.Called(id, body)
+	return args.Get(0).(*common.Comment), args.Error(1)
+}
+
+func (m *MockStore) DeleteComment(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetCommentRevisions(commentID int) ([]*common.CommentRevision, error) {
+	args := m.Called(commentID)
+	return args.Get(0).([]*common.CommentRevision), args.Error(1)
+}
+
+func (m *MockStore) GetTaskActivity(taskID, before, limit int) ([]*common.Activity, error) {
+	args := m.Called(taskID, before, limit)
+	return args.Get(0).([]*common.Activity), args.Error(1)
+}
+
+func (m *MockStore) GetMentionsForUser(userID, limit int) ([]*common.Mention, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]*common.Mention), args.Error(1)
+}
+
+func (m *MockStore) GetNotifications(userID, limit int) ([]*common.Notification, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]*common.Notification), args.Error(1)
+}
+
+func (m *MockStore) CreateAttachment(a *common.Attachment) (*common.Attachment, error) {
+	args := m.Called(a)
+	return args.Get(0).(*common.Attachment), args.Error(1)
+}
+
+func (m *MockStore) GetAttachment(id int) (*common.Attachment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Attachment), args.Error(1)
+}
+
+func (m *MockStore) GetAttachments(taskID int) ([]*common.Attachment, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]*common.Attachment), args.Error(1)
+}
+
+func (m *MockStore) DeleteAttachment(id, actorID int) error {
+	args := m.Called(id, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetSubtasks(parentID int) ([]*common.Task, error) {
+	args := m.Called(parentID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) SetTaskParent(id int, parentID *int64, actorID int) error {
+	args := m.Called(id, parentID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) ReorderSubtasks(parentID int, ids []int64) error {
+	args := m.Called(parentID, ids)
+	return args.Error(0)
+}
+
+func (m *MockStore) AddTaskDependency(blockerID, blockedID, actorID int) error {
+	args := m.Called(blockerID, blockedID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveTaskDependency(blockerID, blockedID, actorID int) error {
+	args := m.Called(blockerID, blockedID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetTaskDependencies(taskID int) (*common.DependencyGraph, error) {
+	args := m.Called(taskID)
+	return args.Get(0).(*common.DependencyGraph), args.Error(1)
+}
+
+func (m *MockStore) AddTaskAssignee(taskID, userID, actorID int) error {
+	args := m.Called(taskID, userID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveTaskAssignee(taskID, userID, actorID int) error {
+	args := m.Called(taskID, userID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetTaskWatchers(taskID int) ([]int64, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockStore) AddTaskWatcher(taskID, userID, actorID int) error {
+	args := m.Called(taskID, userID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveTaskWatcher(taskID, userID, actorID int) error {
+	args := m.Called(taskID, userID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetTaskRecurrence(rec *common.Recurrence, actorID int) (*common.Recurrence, error) {
+	args := m.Called(rec, actorID)
+	return args.Get(0).(*common.Recurrence), args.Error(1)
+}
+
+func (m *MockStore) GetTaskRecurrence(taskID int) (*common.Recurrence, error) {
+	args := m.Called(taskID)
+	return args.Get(0).(*common.Recurrence), args.Error(1)
+}
+
+func (m *MockStore) DeleteTaskRecurrence(taskID, actorID int) error {
+	args := m.Called(taskID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) AdvanceRecurrences(now time.Time, limit int) ([]*common.Task, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) PlanJobs(now time.Time) error {
+	args := m.Called(now)
+	return args.Error(0)
+}
+
+func (m *MockStore) RunDueJobs(now time.Time, limit int) (int, error) {
+	args := m.Called(now, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) GetReminderSettings(userID int) ([]time.Duration, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]time.Duration), args.Error(1)
+}
+
+func (m *MockStore) SetReminderSettings(userID int, before []time.Duration) error {
+	args := m.Called(userID, before)
+	return args.Error(0)
+}
+
+func (m *MockStore) StartTimer(taskID, userID int, now time.Time) (*common.Worklog, error) {
+	args := m.Called(taskID, userID, now)
+	return args.Get(0).(*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) StopTimer(userID int, now time.Time) (*common.Worklog, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).(*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) GetRunningTimer(userID int) (*common.Worklog, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) CreateWorklog(w *common.Worklog) (*common.Worklog, error) {
+	args := m.Called(w)
+	return args.Get(0).(*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) GetWorklog(id int) (*common.Worklog, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) GetWorklogs(taskID int) ([]*common.Worklog, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]*common.Worklog), args.Error(1)
+}
+
+func (m *MockStore) DeleteWorklog(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetTimeReport(filter common.TimeReportFilter) ([]*common.TimeReportRow, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]*common.TimeReportRow), args.Error(1)
+}
+
+func (m *MockStore) GetChecklist(taskID int) ([]*common.ChecklistItem, error) {
+	args := m.Called(taskID)
+	return args.Get(0).([]*common.ChecklistItem), args.Error(1)
+}
+
+func (m *MockStore) GetChecklistItem(id int) (*common.ChecklistItem, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.ChecklistItem), args.Error(1)
+}
+
+func (m *MockStore) AddChecklistItem(item *common.ChecklistItem, actorID int) (*common.ChecklistItem, error) {
+	args := m.Called(item, actorID)
+	return args.Get(0).(*common.ChecklistItem), args.Error(1)
+}
+
+func (m *MockStore) UpdateChecklistItem(item *common.ChecklistItem, actorID int) error {
+	args := m.Called(item, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) ReorderChecklist(taskID int, ids []int64, actorID int) error {
+	args := m.Called(taskID, ids, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteChecklistItem(id, actorID int) error {
+	args := m.Called(id, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetWorkspace(id int) (*common.Workspace, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Workspace), args.Error(1)
+}
+
+func (m *MockStore) UpdateWorkspace(ws *common.Workspace) error {
+	args := m.Called(ws)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateTemplate(t *common.Template) (*common.Template, error) {
+	args := m.Called(t)
+	return args.Get(0).(*common.Template), args.Error(1)
+}
+
+func (m *MockStore) GetTemplate(id int) (*common.Template, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Template), args.Error(1)
+}
+
+func (m *MockStore) GetTemplates(workspaceID int) ([]*common.Template, error) {
+	args := m.Called(workspaceID)
+	return args.Get(0).([]*common.Template), args.Error(1)
+}
+
+func (m *MockStore) UpdateTemplate(t *common.Template) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteTemplate(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) CreateTaskTree(tree *common.TaskTree, actorID int) (*common.Task, error) {
+	args := m.Called(tree, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) UpdateTask(id int, patch common.TaskPatch, actorID int) (*common.Task, error) {
+	args := m.Called(id, patch, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) DeleteTask(id, actorID int) error {
+	args := m.Called(id, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetTrashedTask(id int) (*common.Task, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) RestoreTask(id, actorID int) (*common.Task, error) {
+	args := m.Called(id, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) GetTrash(userID int) ([]*common.Task, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) PurgeTrash(before time.Time, limit int) (int, []string, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockStore) ArchiveTask(id, actorID int) (*common.Task, error) {
+	args := m.Called(id, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) UnarchiveTask(id, actorID int) (*common.Task, error) {
+	args := m.Called(id, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) AutoArchiveTasks(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStore) ExportArchive(workspaceID int, fn func(*common.Task) error) error {
+	args := m.Called(workspaceID, fn)
+	return args.Error(0)
+}
+
+func (m *MockStore) ExportProjectArchive(projectID int, fn func(*common.Task) error) error {
+	args := m.Called(projectID, fn)
+	return args.Error(0)
+}
+
+func (m *MockStore) PurgeArchive(projectID int) (int, []string, error) {
+	args := m.Called(projectID)
+	return args.Int(0), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockStore) ApplyBulk(action common.BulkAction, ids []int64, atomic bool) ([]error, error) {
+	args := m.Called(action, ids, atomic)
+	return args.Get(0).([]error), args.Error(1)
+}
+
+func (m *MockStore) CreateProject(p *common.Project) (*common.Project, error) {
+	args := m.Called(p)
+	return args.Get(0).(*common.Project), args.Error(1)
+}
+
+func (m *MockStore) GetProject(id int) (*common.Project, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Project), args.Error(1)
+}
+
+func (m *MockStore) GetProjects(workspaceID int) ([]*common.Project, error) {
+	args := m.Called(workspaceID)
+	return args.Get(0).([]*common.Project), args.Error(1)
+}
+
+func (m *MockStore) UpdateProject(p *common.Project) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetTaskByKey(projectKey string, number int64, userID int) (*common.Task, error) {
+	args := m.Called(projectKey, number, userID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) MoveTask(id int, workspaceID int64, projectID *int64, statusMap map[string]string, actorID int) (*common.Task, error) {
+	args := m.Called(id, workspaceID, projectID, statusMap, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) GetBoard(projectID int) ([]*common.Task, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) MoveOnBoard(id int, move common.BoardMove) (*common.Task, error) {
+	args := m.Called(id, move)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) CreateSprint(sp *common.Sprint) (*common.Sprint, error) {
+	args := m.Called(sp)
+	return args.Get(0).(*common.Sprint), args.Error(1)
+}
+
+func (m *MockStore) GetSprint(id int) (*common.Sprint, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Sprint), args.Error(1)
+}
+
+func (m *MockStore) GetSprints(projectID int) ([]*common.Sprint, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*common.Sprint), args.Error(1)
+}
+
+func (m *MockStore) UpdateSprint(sp *common.Sprint) error {
+	args := m.Called(sp)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetSprintTasks(sprintID int) ([]*common.Task, error) {
+	args := m.Called(sprintID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) GetBacklog(projectID int) ([]*common.Task, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*common.Task), args.Error(1)
+}
+
+func (m *MockStore) AddSprintTask(sprintID, taskID, actorID int) error {
+	args := m.Called(sprintID, taskID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveSprintTask(sprintID, taskID, actorID int) error {
+	args := m.Called(sprintID, taskID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) StartSprint(id int, now time.Time) (*common.Sprint, error) {
+	args := m.Called(id, now)
+	return args.Get(0).(*common.Sprint), args.Error(1)
+}
+
+func (m *MockStore) CompleteSprint(id int, nextSprintID *int64, actorID int, now time.Time) ([]int64, error) {
+	args := m.Called(id, nextSprintID, actorID, now)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockStore) CreateMilestone(milestone *common.Milestone) (*common.Milestone, error) {
+	args := m.Called(milestone)
+	return args.Get(0).(*common.Milestone), args.Error(1)
+}
+
+func (m *MockStore) GetMilestone(id int) (*common.Milestone, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.Milestone), args.Error(1)
+}
+
+func (m *MockStore) GetMilestones(projectID int) ([]*common.Milestone, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*common.Milestone), args.Error(1)
+}
+
+func (m *MockStore) UpdateMilestone(milestone *common.Milestone) error {
+	args := m.Called(milestone)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetMilestoneProgress(id int, now time.Time) (*common.MilestoneProgress, error) {
+	args := m.Called(id, now)
+	return args.Get(0).(*common.MilestoneProgress), args.Error(1)
+}
+
+func (m *MockStore) SetTaskMilestone(taskID int, milestoneID *int64, actorID int) error {
+	args := m.Called(taskID, milestoneID, actorID)
+	return args.Error(0)
+}
+
+func (m *MockStore) CloseMilestone(id int, now time.Time) (*common.Milestone, []common.ReleaseNoteGroup, error) {
+	args := m.Called(id, now)
+	return args.Get(0).(*common.Milestone), args.Get(1).([]common.ReleaseNoteGroup), args.Error(2)
+}
+
+func (m *MockStore) CreateCustomField(f *common.CustomField) (*common.CustomField, error) {
+	args := m.Called(f)
+	return args.Get(0).(*common.CustomField), args.Error(1)
+}
+
+func (m *MockStore) GetCustomField(id int) (*common.CustomField, error) {
+	args := m.Called(id)
+	return args.Get(0).(*common.CustomField), args.Error(1)
+}
+
+func (m *MockStore) GetCustomFields(projectID int) ([]*common.CustomField, error) {
+	args := m.Called(projectID)
+	return args.Get(0).([]*common.CustomField), args.Error(1)
+}
+
+func (m *MockStore) UpdateCustomField(f *common.CustomField) error {
+	args := m.Called(f)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeleteCustomField(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) CloneTask(id int, opts common.CloneOptions) (*common.Task, error) {
+	args := m.Called(id, opts)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
+func (m *MockStore) MergeTask(id, targetID, actorID int) (*common.Task, error) {
+	args := m.Called(id, targetID, actorID)
+	return args.Get(0).(*common.Task), args.Error(1)
+}
+
 func TestHandleCreateTask(t *testing.T) {
 	mockStore := new(MockStore)
 	taskService := NewTaskService(mockStore)
@@ -52,9 +675,11 @@ func TestHandleCreateTask(t *testing.T) {
 	taskPayload := &common.Task{
 		Name:         "Test Task",
 		Status:       "TODO",
+		Priority:     "P2",
 		AssignedToID: 1,
 	}
-	mockStore.On("CreateTask", taskPayload).Return(taskPayload, nil)
+	mockStore.On("GetUserByID", 1).Return(&common.User{ID: 1}, nil)
+	mockStore.On("CreateTask", taskPayload, 0).Return(taskPayload, nil)
 
 	requestBody, _ := json.Marshal(taskPayload)
 	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewReader(requestBody))
@@ -72,3 +697,69 @@ func TestHandleCreateTask(t *testing.T) {
 	assert.Equal(t, taskPayload.Name, createdTask.Name)
 	mockStore.AssertExpectations(t)
 }
+
+func TestGetTasksWithQuery(t *testing.T) {
+	mockStore := new(MockStore)
+	taskService := NewTaskService(mockStore)
+
+	secret := []byte("testsecret")
+	common.Envs.JWTSecret = string(secret)
+	token, _ := auth.CreateJWT(secret, 3)
+
+	tasks := []*common.Task{{ID: 1, Name: "Task 1", Status: "TODO", Priority: "P1", AssignedToID: 3}}
+	mockStore.On("QueryTasks", mock.AnythingOfType("*query.Query"), 3).Return(tasks, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?q=assignee:me+priority<=P1", nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+
+	taskService.getTasksAssignedToUser(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got []*common.Task
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, tasks, got)
+	mockStore.AssertExpectations(t)
+}
+
+func TestGetTasksWithInvalidQuery(t *testing.T) {
+	mockStore := new(MockStore)
+	taskService := NewTaskService(mockStore)
+
+	secret := []byte("testsecret")
+	common.Envs.JWTSecret = string(secret)
+	token, _ := auth.CreateJWT(secret, 3)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?q=status:", nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+
+	taskService.getTasksAssignedToUser(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "position 8")
+	mockStore.AssertNotCalled(t, "QueryTasks")
+}
+
+func TestPersonalTaskHiddenFromStrangers(t *testing.T) {
+	mockStore := new(MockStore)
+	taskService := NewTaskService(mockStore)
+
+	mockStore.On("GetTask", 9).Return(&common.Task{ID: 9, Name: "Dentist", AssignedToID: 4, AssigneeIDs: []int64{4, 5}}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/tasks/9", nil, 3)
+	req.SetPathValue("id", "9")
+	w := httptest.NewRecorder()
+
+	taskService.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = authorizedRequest(http.MethodGet, "/tasks/9", nil, 5)
+	req.SetPathValue("id", "9")
+	w = httptest.NewRecorder()
+
+	taskService.handleGetTask(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
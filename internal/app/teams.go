@@ -0,0 +1,159 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+var errInvalidTeamRole = errors.New("role must be MEMBER or LEAD")
+
+type TeamsService struct {
+	store common.Store
+}
+
+func NewTeamsService(store common.Store) *TeamsService {
+	return &TeamsService{store: store}
+}
+
+func (s *TeamsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /teams", auth.WithJWTAuth(s.handleGetTeams, s.store))
+	router.HandleFunc("POST /teams", auth.WithJWTAuth(s.handleCreateTeam, s.store))
+	router.HandleFunc("GET /teams/{id}/members", auth.WithJWTAuth(s.handleGetMembers, s.store))
+	router.HandleFunc("POST /teams/{id}/members", auth.WithJWTAuth(s.handleAddMember, s.store))
+}
+
+func (s *TeamsService) handleGetTeams(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	teams, err := s.store.GetTeamsForUser(userID)
+	if err != nil {
+		http.Error(w, "Error getting teams", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, teams)
+}
+
+func (s *TeamsService) handleCreateTeam(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload common.Team
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	team, err := s.store.CreateTeam(&payload, userID)
+	if err != nil {
+		http.Error(w, "Error creating team", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, team)
+}
+
+func (s *TeamsService) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+	teamID, _, ok := s.requireMember(w, r, common.TeamRoleMember)
+	if !ok {
+		return
+	}
+
+	members, err := s.store.GetTeamMembers(teamID)
+	if err != nil {
+		http.Error(w, "Error getting team members", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, members)
+}
+
+func (s *TeamsService) handleAddMember(w http.ResponseWriter, r *http.Request) {
+	teamID, _, ok := s.requireMember(w, r, common.TeamRoleLead)
+	if !ok {
+		return
+	}
+
+	var payload common.TeamMember
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.UserID == 0 {
+		http.Error(w, errUserIDRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	switch payload.Role {
+	case "":
+		payload.Role = common.TeamRoleMember
+	case common.TeamRoleMember, common.TeamRoleLead:
+	default:
+		http.Error(w, errInvalidTeamRole.Error(), http.StatusBadRequest)
+		return
+	}
+	payload.TeamID = int64(teamID)
+
+	if _, err := s.store.GetUserByID(int(payload.UserID)); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.AddTeamMember(&payload); err != nil {
+		http.Error(w, "Error adding team member", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, payload)
+}
+
+// requireMember reads the team id from the path and makes sure the caller
+// belongs to that team, as a lead when role is TeamRoleLead. It writes the
+// error response itself and reports whether the handler may continue.
+func (s *TeamsService) requireMember(w http.ResponseWriter, r *http.Request, role string) (int, int, bool) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	member, err := s.store.GetTeamMember(teamID, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting team", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	if role == common.TeamRoleLead && member.Role != common.TeamRoleLead {
+		http.Error(w, "Only team leads can do that", http.StatusForbidden)
+		return 0, 0, false
+	}
+
+	return teamID, userID, true
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateTeamMakesCallerLead(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	mockStore.On("CreateTeam", &common.Team{Name: "Platform"}, 3).Return(&common.Team{ID: 2, Name: "Platform"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/teams", []byte(`{"name": "Platform"}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTeam(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateTeamRequiresName(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/teams", []byte(`{}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateTeam(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateTeam", mock.Anything, mock.Anything)
+}
+
+func TestTeamMembersHiddenFromOutsiders(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	mockStore.On("GetTeamMember", 2, 3).Return((*common.TeamMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/teams/2/members", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleGetMembers(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "GetTeamMembers", mock.Anything)
+}
+
+func TestOnlyLeadsAddTeamMembers(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	mockStore.On("GetTeamMember", 2, 3).Return(&common.TeamMember{TeamID: 2, UserID: 3, Role: common.TeamRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/teams/2/members", []byte(`{"user_id": 4}`), 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleAddMember(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "AddTeamMember", mock.Anything)
+}
+
+func TestAddTeamMemberDefaultsToMember(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	mockStore.On("GetTeamMember", 2, 3).Return(&common.TeamMember{TeamID: 2, UserID: 3, Role: common.TeamRoleLead}, nil)
+	mockStore.On("GetUserByID", 4).Return(&common.User{ID: 4}, nil)
+	mockStore.On("AddTeamMember", &common.TeamMember{TeamID: 2, UserID: 4, Role: common.TeamRoleMember}).Return(nil)
+
+	req := authorizedRequest(http.MethodPost, "/teams/2/members", []byte(`{"user_id": 4}`), 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleAddMember(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestAddTeamMemberRejectsUnknownRole(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTeamsService(mockStore)
+
+	mockStore.On("GetTeamMember", 2, 3).Return(&common.TeamMember{TeamID: 2, UserID: 3, Role: common.TeamRoleLead}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/teams/2/members", []byte(`{"user_id": 4, "role": "OWNER"}`), 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleAddMember(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "AddTeamMember", mock.Anything)
+}
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxTemplateTasks bounds how many tasks one instantiation creates.
+const maxTemplateTasks = 100
+
+var errTemplateExists = errors.New("A template with this name already exists in the workspace")
+var errTemplateNotFound = errors.New("Template not found")
+var errTooManyTemplateTasks = errors.New("a template can hold at most 100 tasks")
+var errInvalidDueOffset = errors.New("due_offset must be a duration such as -72h")
+var errTemplateDueAt = errors.New("due_at is required by this template")
+var errTemplateLabel = errors.New("template labels must belong to the template's workspace")
+
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+type TemplatesService struct {
+	store common.Store
+}
+
+func NewTemplatesService(store common.Store) *TemplatesService {
+	return &TemplatesService{store: store}
+}
+
+func (s *TemplatesService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /workspaces/{id}/templates", auth.WithJWTAuth(s.handleGetTemplates, s.store))
+	router.HandleFunc("POST /workspaces/{id}/templates", auth.WithJWTAuth(s.handleCreateTemplate, s.store))
+	router.HandleFunc("GET /templates/{id}", auth.WithJWTAuth(s.handleGetTemplate, s.store))
+	router.HandleFunc("PUT /templates/{id}", auth.WithJWTAuth(s.handleUpdateTemplate, s.store))
+	router.HandleFunc("DELETE /templates/{id}", auth.WithJWTAuth(s.handleDeleteTemplate, s.store))
+	router.HandleFunc("POST /templates/{id}/instantiate", auth.WithJWTAuth(s.handleInstantiate, s.store))
+}
+
+func (s *TemplatesService) handleGetTemplates(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	templates, err := s.store.GetTemplates(workspaceID)
+	if err != nil {
+		http.Error(w, "Error getting templates", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, templates)
+}
+
+func (s *TemplatesService) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	var payload common.Template
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.WorkspaceID = int64(workspaceID)
+	payload.CreatedByID = int64(userID)
+	if err := s.validateTemplate(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := s.store.CreateTemplate(&payload)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errTemplateExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating template", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, tmpl)
+}
+
+func (s *TemplatesService) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tmpl)
+}
+
+// handleUpdateTemplate replaces the name and the task tree of the template.
+func (s *TemplatesService) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	var payload common.Template
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	tmpl, ok := s.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl.Name = payload.Name
+	tmpl.Task = payload.Task
+	if err := s.validateTemplate(tmpl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.store.UpdateTemplate(tmpl)
+	if errors.Is(err, common.ErrConflict) {
+		http.Error(w, errTemplateExists.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating template", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tmpl)
+}
+
+func (s *TemplatesService) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.DeleteTemplate(int(tmpl.ID)); err != nil && !errors.Is(err, common.ErrNotFound) {
+		http.Error(w, "Error deleting template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleInstantiate creates the template's task tree in the template's
+// workspace: `{"variables": {"version": "1.4"}, "due_at": "2025-02-01T12:00:00Z"}`.
+// due_at is the due date of the top task, and tasks with a due_offset are
+// due that long after it. The tasks are assigned to assigned_to_id, the
+// caller by default.
+func (s *TemplatesService) handleInstantiate(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Variables    map[string]string `json:"variables"`
+		DueAt        *time.Time        `json:"due_at"`
+		AssignedToID int64             `json:"assigned_to_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	tmpl, ok := s.loadTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if payload.AssignedToID == 0 {
+		userID, _ := auth.GetUserIDFromRequest(r)
+		payload.AssignedToID = int64(userID)
+	}
+	if err := checkAssignees(s.store, &tmpl.WorkspaceID, []int64{payload.AssignedToID}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var missing []string
+	tree, err := buildTaskTree(&tmpl.Task, payload.Variables, payload.DueAt, &missing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		missing = slices.Compact(missing)
+		http.Error(w, "missing template variables: "+strings.Join(missing, ", "), http.StatusBadRequest)
+		return
+	}
+	if payload.DueAt != nil && tree.Task.DueAt == nil {
+		tree.Task.DueAt = payload.DueAt
+	}
+	setTreeOwner(tree, tmpl.WorkspaceID, payload.AssignedToID)
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err := s.store.CreateTaskTree(tree, userID)
+	if errors.Is(err, common.ErrTaskTooDeep) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, task)
+}
+
+func (s *TemplatesService) loadTemplate(w http.ResponseWriter, r *http.Request) (*common.Template, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	tmpl, err := s.store.GetTemplate(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errTemplateNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting template", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if _, ok := checkWorkspaceMember(s.store, w, r, int(tmpl.WorkspaceID), common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return tmpl, true
+}
+
+func (s *TemplatesService) validateTemplate(tmpl *common.Template) error {
+	tmpl.Name = strings.TrimSpace(tmpl.Name)
+	if tmpl.Name == "" {
+		return errNameRequired
+	}
+	count := 0
+	return s.validateTemplateTask(&tmpl.Task, tmpl.WorkspaceID, &count)
+}
+
+func (s *TemplatesService) validateTemplateTask(t *common.TemplateTask, workspaceID int64, count *int) error {
+	*count++
+	if *count > maxTemplateTasks {
+		return errTooManyTemplateTasks
+	}
+
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errNameRequired
+	}
+	switch t.Priority {
+	case "", "P0", "P1", "P2", "P3":
+	default:
+		return errInvalidPriority
+	}
+	if t.DueOffset != "" {
+		if _, err := time.ParseDuration(t.DueOffset); err != nil {
+			return errInvalidDueOffset
+		}
+	}
+	for i, text := range t.Checklist {
+		text, ok := checklistText(text)
+		if !ok {
+			return errChecklistText
+		}
+		t.Checklist[i] = text
+	}
+	for _, labelID := range t.LabelIDs {
+		label, err := s.store.GetLabel(int(labelID))
+		if err != nil || label.WorkspaceID != workspaceID {
+			return errTemplateLabel
+		}
+	}
+
+	for i := range t.Subtasks {
+		if err := s.validateTemplateTask(&t.Subtasks[i], workspaceID, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildTaskTree fills in the variables of the template task and its
+// subtasks. Variables without a value are added to missing.
+func buildTaskTree(t *common.TemplateTask, vars map[string]string, dueAt *time.Time, missing *[]string) (*common.TaskTree, error) {
+	expand := func(text string) string {
+		return templateVariable.ReplaceAllStringFunc(text, func(match string) string {
+			name := templateVariable.FindStringSubmatch(match)[1]
+			value, ok := vars[name]
+			if !ok {
+				*missing = append(*missing, name)
+			}
+			return value
+		})
+	}
+
+	task := &common.Task{
+		Name:        expand(t.Name),
+		Description: expand(t.Description),
+		Status:      "TODO",
+		Priority:    t.Priority,
+	}
+	if task.Priority == "" {
+		task.Priority = "P2"
+	}
+	if t.DueOffset != "" {
+		if dueAt == nil {
+			return nil, errTemplateDueAt
+		}
+		offset, err := time.ParseDuration(t.DueOffset)
+		if err != nil {
+			return nil, errInvalidDueOffset
+		}
+		due := dueAt.Add(offset)
+		task.DueAt = &due
+	}
+
+	tree := &common.TaskTree{Task: task, LabelIDs: t.LabelIDs}
+	for _, text := range t.Checklist {
+		tree.Checklist = append(tree.Checklist, expand(text))
+	}
+	for i := range t.Subtasks {
+		sub, err := buildTaskTree(&t.Subtasks[i], vars, dueAt, missing)
+		if err != nil {
+			return nil, err
+		}
+		tree.Subtasks = append(tree.Subtasks, sub)
+	}
+	return tree, nil
+}
+
+func setTreeOwner(tree *common.TaskTree, workspaceID, assignedToID int64) {
+	tree.Task.WorkspaceID = &workspaceID
+	tree.Task.AssignedToID = assignedToID
+	for _, sub := range tree.Subtasks {
+		setTreeOwner(sub, workspaceID, assignedToID)
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func releaseTemplate() *common.Template {
+	return &common.Template{ID: 4, WorkspaceID: 2, Name: "Release", Task: common.TemplateTask{
+		Name:      "Release {{version}}",
+		Checklist: []string{"Tag {{ version }}"},
+		Subtasks: []common.TemplateTask{
+			{Name: "Changelog for {{version}}", DueOffset: "-72h"},
+		},
+	}}
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTemplatesService(mockStore)
+	dueAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	changelogDue := dueAt.Add(-72 * time.Hour)
+	workspaceID := int64(2)
+
+	mockStore.On("GetTemplate", 4).Return(releaseTemplate(), nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3}, nil)
+	mockStore.On("CreateTaskTree", &common.TaskTree{
+		Task: &common.Task{Name: "Release 1.4", Status: "TODO", Priority: "P2", DueAt: &dueAt, AssignedToID: 3,
+			WorkspaceID: &workspaceID},
+		Checklist: []string{"Tag 1.4"},
+		Subtasks: []*common.TaskTree{
+			{Task: &common.Task{Name: "Changelog for 1.4", Status: "TODO", Priority: "P2", DueAt: &changelogDue,
+				AssignedToID: 3, WorkspaceID: &workspaceID}},
+		},
+	}, 3).Return(&common.Task{ID: 10, Name: "Release 1.4"}, nil)
+
+	body := []byte(`{"variables": {"version": "1.4"}, "due_at": "2025-02-01T12:00:00Z"}`)
+	req := authorizedRequest(http.MethodPost, "/templates/4/instantiate", body, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleInstantiate(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestInstantiateTemplateMissingVariables(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTemplatesService(mockStore)
+
+	mockStore.On("GetTemplate", 4).Return(releaseTemplate(), nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3}, nil)
+
+	body := []byte(`{"due_at": "2025-02-01T12:00:00Z"}`)
+	req := authorizedRequest(http.MethodPost, "/templates/4/instantiate", body, 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleInstantiate(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "missing template variables: version\n", w.Body.String())
+	mockStore.AssertNotCalled(t, "CreateTaskTree", mock.Anything, mock.Anything)
+}
+
+func TestInstantiateTemplateNeedsDueDate(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTemplatesService(mockStore)
+
+	mockStore.On("GetTemplate", 4).Return(releaseTemplate(), nil)
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/templates/4/instantiate", []byte(`{"variables": {"version": "1.4"}}`), 3)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+
+	service.handleInstantiate(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateTaskTree", mock.Anything, mock.Anything)
+}
+
+func TestCreateTemplateRejectsForeignLabel(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTemplatesService(mockStore)
+
+	mockStore.On("GetWorkspaceMember", 2, 3).Return(&common.WorkspaceMember{WorkspaceID: 2, UserID: 3}, nil)
+	mockStore.On("GetLabel", 9).Return(&common.Label{ID: 9, WorkspaceID: 5, Name: "bug"}, nil)
+
+	body := []byte(`{"name": "Release", "task": {"name": "Release {{version}}", "label_ids": [9]}}`)
+	req := authorizedRequest(http.MethodPost, "/workspaces/2/templates", body, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleCreateTemplate(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateTemplate", mock.Anything)
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+var errNotInTrash = errors.New("Task is not in the trash")
+
+type TrashService struct {
+	store common.Store
+}
+
+func NewTrashService(store common.Store) *TrashService {
+	return &TrashService{store: store}
+}
+
+func (s *TrashService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /trash", auth.WithJWTAuth(s.handleGetTrash, s.store))
+	router.HandleFunc("POST /tasks/{id}/restore", auth.WithJWTAuth(s.handleRestoreTask, s.store))
+}
+
+// handleGetTrash lists the deleted tasks the caller deleted, is assigned to
+// or can see through a workspace.
+func (s *TrashService) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromRequest(r)
+	tasks, err := s.store.GetTrash(userID)
+	if err != nil {
+		http.Error(w, "Error getting trash", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// handleRestoreTask takes the task, and the subtasks deleted with it, out of
+// the trash.
+func (s *TrashService) handleRestoreTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTrashedTask(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errNotInTrash.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting task", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return
+	}
+
+	userID, _ := auth.GetUserIDFromRequest(r)
+	task, err = s.store.RestoreTask(id, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errNotInTrash.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, common.ErrParentTrashed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error restoring task", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, task)
+}
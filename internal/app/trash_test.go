@@ -0,0 +1,86 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeleteTaskMovesItToTrash(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTaskService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("DeleteTask", 1, 3).Return(nil)
+
+	req := authorizedRequest(http.MethodDelete, "/tasks/1", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleDeleteTask(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestRestoreTaskUnderTrashedParent(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTrashService(mockStore)
+	parentID := int64(1)
+
+	mockStore.On("GetTrashedTask", 2).Return(&common.Task{ID: 2, AssignedToID: 3, ParentID: &parentID}, nil)
+	mockStore.On("RestoreTask", 2, 3).Return((*common.Task)(nil), common.ErrParentTrashed)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/2/restore", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleRestoreTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestRestoreTaskNotInTrash(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewTrashService(mockStore)
+
+	mockStore.On("GetTrashedTask", 2).Return((*common.Task)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/2/restore", nil, 3)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+
+	service.handleRestoreTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "RestoreTask", 2, 3)
+}
+
+func TestStrangerCannotTrashOrRestorePersonalTask(t *testing.T) {
+	mockStore := new(MockStore)
+	tasks := NewTaskService(mockStore)
+	trash := NewTrashService(mockStore)
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+	mockStore.On("GetTrashedTask", 2).Return(&common.Task{ID: 2, AssignedToID: 4, AssigneeIDs: []int64{4}}, nil)
+
+	req := authorizedRequest(http.MethodDelete, "/tasks/1", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	tasks.handleDeleteTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = authorizedRequest(http.MethodPost, "/tasks/2/restore", nil, 3)
+	req.SetPathValue("id", "2")
+	w = httptest.NewRecorder()
+
+	trash.handleRestoreTask(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertNotCalled(t, "DeleteTask", 1, 3)
+	mockStore.AssertNotCalled(t, "RestoreTask", 2, 3)
+}
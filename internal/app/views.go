@@ -0,0 +1,319 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+)
+
+var errViewNotFound = errors.New("View not found")
+var errViewReadOnly = errors.New("Only the owner or a lead of the team can change this view")
+var errNotTeamMember = errors.New("You can only share views with your own teams")
+
+// viewColumns are the task fields a view may display, named as in the task JSON.
+var viewColumns = map[string]bool{
+	"id":             true,
+	"name":           true,
+	"description":    true,
+	"status":         true,
+	"priority":       true,
+	"due_at":         true,
+	"assigned_to_id": true,
+	"created_at":     true,
+	"custom_fields":  true,
+}
+
+type ViewsService struct {
+	store common.Store
+}
+
+func NewViewsService(store common.Store) *ViewsService {
+	return &ViewsService{store: store}
+}
+
+func (s *ViewsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /views", auth.WithJWTAuth(s.handleGetViews, s.store))
+	router.HandleFunc("POST /views", auth.WithJWTAuth(s.handleCreateView, s.store))
+	router.HandleFunc("GET /views/{id}", auth.WithJWTAuth(s.handleGetView, s.store))
+	router.HandleFunc("PUT /views/{id}", auth.WithJWTAuth(s.handleUpdateView, s.store))
+	router.HandleFunc("DELETE /views/{id}", auth.WithJWTAuth(s.handleDeleteView, s.store))
+	router.HandleFunc("POST /views/{id}/pin", auth.WithJWTAuth(s.handlePinView, s.store))
+	router.HandleFunc("DELETE /views/{id}/pin", auth.WithJWTAuth(s.handleUnpinView, s.store))
+	router.HandleFunc("GET /views/{id}/tasks", auth.WithJWTAuth(s.handleGetViewTasks, s.store))
+}
+
+func (s *ViewsService) handleGetViews(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	views, err := s.store.GetViewsForUser(userID)
+	if err != nil {
+		http.Error(w, "Error getting views", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, views)
+}
+
+func (s *ViewsService) handleCreateView(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload common.View
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.OwnerID = int64(userID)
+	if err := s.validateViewPayload(&payload, userID); err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	view, err := s.store.CreateView(&payload)
+	if err != nil {
+		http.Error(w, "Error creating view", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, view)
+}
+
+func (s *ViewsService) handleGetView(w http.ResponseWriter, r *http.Request) {
+	view, _, err := s.loadView(r, false)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, view)
+}
+
+func (s *ViewsService) handleUpdateView(w http.ResponseWriter, r *http.Request) {
+	view, userID, err := s.loadView(r, true)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	var payload common.View
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	payload.ID = view.ID
+	payload.OwnerID = view.OwnerID
+	payload.Pinned = view.Pinned
+	payload.CreatedAt = view.CreatedAt
+	if err := s.validateViewPayload(&payload, userID); err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	if err := s.store.UpdateView(&payload); err != nil {
+		http.Error(w, "Error updating view", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := s.store.GetView(int(view.ID), userID)
+	if err != nil {
+		http.Error(w, "Error getting view", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (s *ViewsService) handleDeleteView(w http.ResponseWriter, r *http.Request) {
+	view, _, err := s.loadView(r, true)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	if err := s.store.DeleteView(int(view.ID)); err != nil {
+		http.Error(w, "Error deleting view", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ViewsService) handlePinView(w http.ResponseWriter, r *http.Request) {
+	s.setPinned(w, r, true)
+}
+
+func (s *ViewsService) handleUnpinView(w http.ResponseWriter, r *http.Request) {
+	s.setPinned(w, r, false)
+}
+
+func (s *ViewsService) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	view, userID, err := s.loadView(r, false)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	if err := s.store.SetViewPinned(int(view.ID), userID, pinned); err != nil {
+		http.Error(w, "Error pinning view", http.StatusInternalServerError)
+		return
+	}
+
+	view.Pinned = pinned
+	utils.WriteJSON(w, http.StatusOK, view)
+}
+
+// handleGetViewTasks runs the saved query as the caller, so "assignee:me"
+// in a shared view always means whoever opens it, and a view shared with
+// a team shows each member only the tasks they can see themselves.
+func (s *ViewsService) handleGetViewTasks(w http.ResponseWriter, r *http.Request) {
+	view, userID, err := s.loadView(r, false)
+	if err != nil {
+		writeViewError(w, err)
+		return
+	}
+
+	q, err := parseView(view)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	tasks, err := s.store.QueryTasks(q, userID)
+	var queryErr *query.Error
+	if errors.As(err, &queryErr) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error querying tasks", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// loadView returns the view from the path if the caller may see it: views
+// are visible to their owner and to members of the team they are shared
+// with. With write set, only the owner and leads of that team pass.
+func (s *ViewsService) loadView(r *http.Request, write bool) (*common.View, int, error) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		return nil, 0, &badRequestError{err}
+	}
+
+	view, err := s.store.GetView(id, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if view.OwnerID == int64(userID) {
+		return view, userID, nil
+	}
+
+	// Views of other users that are not shared with the caller's team are
+	// reported as missing so their existence is not revealed.
+	if view.TeamID == nil {
+		return nil, 0, errViewNotFound
+	}
+	member, err := s.store.GetTeamMember(int(*view.TeamID), userID)
+	if errors.Is(err, common.ErrNotFound) {
+		return nil, 0, errViewNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if write && member.Role != common.TeamRoleLead {
+		return nil, 0, errViewReadOnly
+	}
+	return view, userID, nil
+}
+
+func (s *ViewsService) validateViewPayload(v *common.View, userID int) error {
+	if v.Name == "" {
+		return &badRequestError{errNameRequired}
+	}
+	if v.Columns == nil {
+		v.Columns = []string{}
+	}
+	for _, c := range v.Columns {
+		if !viewColumns[c] {
+			return &badRequestError{errors.New("Unknown column '" + c + "'")}
+		}
+	}
+
+	q, err := parseView(v)
+	if err != nil {
+		return &badRequestError{err}
+	}
+	if _, err := query.Compile(q, query.Env{UserID: int64(userID)}); err != nil {
+		return &badRequestError{err}
+	}
+
+	if v.TeamID != nil {
+		if _, err := s.store.GetTeamMember(int(*v.TeamID), userID); err != nil {
+			if errors.Is(err, common.ErrNotFound) {
+				return errNotTeamMember
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func parseView(v *common.View) (*query.Query, error) {
+	q, err := query.Parse(v.Filter)
+	if err != nil {
+		return nil, err
+	}
+	q.Sort, err = query.ParseSort(v.Sort)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// badRequestError marks errors caused by the request itself.
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *badRequestError) Unwrap() error {
+	return e.err
+}
+
+func writeViewError(w http.ResponseWriter, err error) {
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, common.ErrNotFound), errors.Is(err, errViewNotFound):
+		http.Error(w, errViewNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, errViewReadOnly), errors.Is(err, errNotTeamMember):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "Error loading view", http.StatusInternalServerError)
+	}
+}
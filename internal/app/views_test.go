@@ -0,0 +1,94 @@
+package app
+
+import (
+	"bytes"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func authorizedRequest(method, target string, body []byte, userID int64) *http.Request {
+	secret := []byte("testsecret")
+	common.Envs.JWTSecret = string(secret)
+	token, _ := auth.CreateJWT(secret, userID)
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Authorization", token)
+	return req
+}
+
+func TestViewTasksRunAsCaller(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewViewsService(mockStore)
+
+	teamID := int64(2)
+	view := &common.View{ID: 5, OwnerID: 1, TeamID: &teamID, Name: "Mine", Filter: "assignee:me", Sort: "-priority"}
+	mockStore.On("GetView", 5, 3).Return(view, nil)
+	mockStore.On("GetTeamMember", 2, 3).Return(&common.TeamMember{TeamID: 2, UserID: 3, Role: common.TeamRoleMember}, nil)
+	mockStore.On("QueryTasks", mock.AnythingOfType("*query.Query"), 3).Return([]*common.Task{}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/views/5/tasks", nil, 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleGetViewTasks(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestSharedViewHiddenFromOtherTeams(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewViewsService(mockStore)
+
+	teamID := int64(2)
+	view := &common.View{ID: 5, OwnerID: 1, TeamID: &teamID, Name: "Mine", Filter: "assignee:me"}
+	mockStore.On("GetView", 5, 3).Return(view, nil)
+	mockStore.On("GetTeamMember", 2, 3).Return((*common.TeamMember)(nil), common.ErrNotFound)
+
+	req := authorizedRequest(http.MethodGet, "/views/5", nil, 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleGetView(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestTeamMemberCannotEditSharedView(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewViewsService(mockStore)
+
+	teamID := int64(2)
+	view := &common.View{ID: 5, OwnerID: 1, TeamID: &teamID, Name: "Mine", Filter: "assignee:me"}
+	mockStore.On("GetView", 5, 3).Return(view, nil)
+	mockStore.On("GetTeamMember", 2, 3).Return(&common.TeamMember{TeamID: 2, UserID: 3, Role: common.TeamRoleMember}, nil)
+
+	req := authorizedRequest(http.MethodDelete, "/views/5", nil, 3)
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	service.handleDeleteView(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockStore.AssertNotCalled(t, "DeleteView", 5)
+}
+
+func TestCreateViewValidatesFilter(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewViewsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/views", []byte(`{"name":"Broken","filter":"priority<=P9"}`), 3)
+	w := httptest.NewRecorder()
+
+	service.handleCreateView(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown priority "P9"`)
+	mockStore.AssertNotCalled(t, "CreateView", mock.Anything)
+}
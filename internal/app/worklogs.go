@@ -0,0 +1,338 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	maxWorklogDuration = 24 * time.Hour
+	maxReportDays      = 366
+	defaultReportDays  = 30
+)
+
+var errInvalidWorklogDuration = errors.New("duration must be between 1m and 24h, such as 1h30m")
+var errInvalidWorklogDate = errors.New("date must look like 2025-01-31")
+var errNoRunningTimer = errors.New("No timer is running")
+var errWorklogNotFound = errors.New("Worklog not found")
+var errNotWorklogAuthor = errors.New("Only the author can delete a worklog")
+var errInvalidReportRange = errors.New("from and to must be dates such as 2025-01-31, at most 366 days apart")
+var errInvalidReportGroup = errors.New("group_by must be one of task, user, team")
+
+type WorklogsService struct {
+	store common.Store
+	now   func() time.Time
+}
+
+func NewWorklogsService(store common.Store) *WorklogsService {
+	return &WorklogsService{store: store, now: time.Now}
+}
+
+func (s *WorklogsService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST /tasks/{id}/timer", auth.WithJWTAuth(s.handleStartTimer, s.store))
+	router.HandleFunc("GET /users/me/timer", auth.WithJWTAuth(s.handleGetTimer, s.store))
+	router.HandleFunc("DELETE /users/me/timer", auth.WithJWTAuth(s.handleStopTimer, s.store))
+	router.HandleFunc("GET /tasks/{id}/worklogs", auth.WithJWTAuth(s.handleGetWorklogs, s.store))
+	router.HandleFunc("POST /tasks/{id}/worklogs", auth.WithJWTAuth(s.handleCreateWorklog, s.store))
+	router.HandleFunc("DELETE /worklogs/{id}", auth.WithJWTAuth(s.handleDeleteWorklog, s.store))
+	router.HandleFunc("GET /reports/time", auth.WithJWTAuth(s.handleTimeReport, s.store))
+}
+
+// taskForUser returns the task when the caller may log time on it, that is
+// when it is outside a workspace or the caller is a member of its workspace.
+func (s *WorklogsService) taskForUser(w http.ResponseWriter, r *http.Request) (*common.Task, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	task, err := s.store.GetTask(id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return nil, false
+	}
+	if _, ok := checkTaskAccess(s.store, w, r, task, common.WorkspaceRoleMember); !ok {
+		return nil, false
+	}
+	return task, true
+}
+
+func (s *WorklogsService) handleStartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	task, ok := s.taskForUser(w, r)
+	if !ok {
+		return
+	}
+
+	timer, err := s.store.StartTimer(int(task.ID), userID, s.now())
+	if errors.Is(err, common.ErrTimerRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error starting timer", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, timer)
+}
+
+func (s *WorklogsService) handleGetTimer(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	timer, err := s.store.GetRunningTimer(userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errNoRunningTimer.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting timer", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, timer)
+}
+
+// handleStopTimer stops the caller's running timer, whichever task it is
+// on, and returns the logged time.
+func (s *WorklogsService) handleStopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	worklog, err := s.store.StopTimer(userID, s.now())
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errNoRunningTimer.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error stopping timer", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, worklog)
+}
+
+func (s *WorklogsService) handleGetWorklogs(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.taskForUser(w, r)
+	if !ok {
+		return
+	}
+
+	worklogs, err := s.store.GetWorklogs(int(task.ID))
+	if err != nil {
+		http.Error(w, "Error getting worklogs", http.StatusInternalServerError)
+		return
+	}
+
+	var total int64
+	for _, worklog := range worklogs {
+		total += worklog.Seconds
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]any{"total_seconds": total, "worklogs": worklogs})
+}
+
+// handleCreateWorklog logs time by hand: `{"duration": "1h30m", "date":
+// "2025-01-31", "note": "Code review"}`. date defaults to today.
+func (s *WorklogsService) handleCreateWorklog(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Duration string `json:"duration"`
+		Date     string `json:"date"`
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	duration, err := time.ParseDuration(payload.Duration)
+	if err != nil || duration < time.Minute || duration > maxWorklogDuration {
+		http.Error(w, errInvalidWorklogDuration.Error(), http.StatusBadRequest)
+		return
+	}
+	if payload.Date == "" {
+		payload.Date = s.now().Format(time.DateOnly)
+	}
+	if _, err := time.Parse(time.DateOnly, payload.Date); err != nil {
+		http.Error(w, errInvalidWorklogDate.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, ok := s.taskForUser(w, r)
+	if !ok {
+		return
+	}
+
+	worklog, err := s.store.CreateWorklog(&common.Worklog{
+		TaskID:  task.ID,
+		UserID:  int64(userID),
+		Seconds: int64(duration / time.Second),
+		Date:    payload.Date,
+		Note:    payload.Note,
+	})
+	if err != nil {
+		http.Error(w, "Error logging time", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, worklog)
+}
+
+func (s *WorklogsService) handleDeleteWorklog(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	worklog, err := s.store.GetWorklog(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errWorklogNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting worklog", http.StatusInternalServerError)
+		return
+	}
+	if worklog.UserID != int64(userID) {
+		http.Error(w, errNotWorklogAuthor.Error(), http.StatusForbidden)
+		return
+	}
+
+	err = s.store.DeleteWorklog(id)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errWorklogNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting worklog", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTimeReport sums up logged time between ?from= and ?to= (the last
+// 30 days by default) by task, user or team, as JSON or, with
+// ?format=csv, as a CSV download.
+func (s *WorklogsService) handleTimeReport(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := s.timeReportFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.ViewerID = userID
+
+	report, err := s.store.GetTimeReport(filter)
+	if err != nil {
+		http.Error(w, "Error getting time report", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		utils.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("time-by-%s-%s-%s.csv", filter.GroupBy,
+		filter.From.Format(time.DateOnly), filter.To.Format(time.DateOnly))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "name", "seconds", "hours"})
+	for _, row := range report {
+		out.Write([]string{
+			strconv.FormatInt(row.ID, 10),
+			csvSafe(row.Name),
+			strconv.FormatInt(row.Seconds, 10),
+			strconv.FormatFloat(float64(row.Seconds)/3600, 'f', 2, 64),
+		})
+	}
+	out.Flush()
+}
+
+func (s *WorklogsService) timeReportFilter(r *http.Request) (common.TimeReportFilter, error) {
+	query := r.URL.Query()
+	filter := common.TimeReportFilter{GroupBy: query.Get("group_by")}
+
+	switch filter.GroupBy {
+	case "":
+		filter.GroupBy = common.TimeReportByTask
+	case common.TimeReportByTask, common.TimeReportByUser, common.TimeReportByTeam:
+	default:
+		return filter, errInvalidReportGroup
+	}
+
+	today, _ := time.Parse(time.DateOnly, s.now().Format(time.DateOnly))
+	filter.To = today
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return filter, errInvalidReportRange
+		}
+		filter.To = to
+	}
+	filter.From = filter.To.AddDate(0, 0, -defaultReportDays)
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return filter, errInvalidReportRange
+		}
+		filter.From = from
+	}
+
+	if filter.From.After(filter.To) || filter.To.Sub(filter.From) > maxReportDays*24*time.Hour {
+		return filter, errInvalidReportRange
+	}
+	return filter, nil
+}
+
+// csvSafe keeps spreadsheet programs from running names that look like
+// formulas.
+func csvSafe(value string) string {
+	if value != "" && (value[0] == '=' || value[0] == '+' || value[0] == '-' || value[0] == '@') {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStartTimerWhileRunning(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorklogsService(mockStore)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("StartTimer", 1, 3, now).Return((*common.Worklog)(nil), common.ErrTimerRunning)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/timer", nil, 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleStartTimer(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateWorklogDefaultsToToday(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorklogsService(mockStore)
+	service.now = func() time.Time { return time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC) }
+
+	mockStore.On("GetTask", 1).Return(&common.Task{ID: 1, AssignedToID: 3}, nil)
+	mockStore.On("CreateWorklog", &common.Worklog{TaskID: 1, UserID: 3, Seconds: 5400, Date: "2025-01-08", Note: "Review"}).
+		Return(&common.Worklog{ID: 4, TaskID: 1, UserID: 3, Seconds: 5400, Date: "2025-01-08", Note: "Review"}, nil)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/worklogs", []byte(`{"duration": "1h30m", "note": "Review"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleCreateWorklog(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateWorklogRejectsLongDuration(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorklogsService(mockStore)
+
+	req := authorizedRequest(http.MethodPost, "/tasks/1/worklogs", []byte(`{"duration": "25h"}`), 3)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	service.handleCreateWorklog(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "CreateWorklog", mock.Anything)
+}
+
+func TestTimeReportCSV(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorklogsService(mockStore)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mockStore.On("GetTimeReport", common.TimeReportFilter{ViewerID: 3, From: from, To: to, GroupBy: common.TimeReportByUser}).
+		Return([]*common.TimeReportRow{{ID: 3, Name: "Jane Doe", Seconds: 5400}, {ID: 4, Name: "=cmd", Seconds: 60}}, nil)
+
+	req := authorizedRequest(http.MethodGet, "/reports/time?from=2025-01-01&to=2025-01-31&group_by=user&format=csv", nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleTimeReport(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name,seconds,hours\n3,Jane Doe,5400,1.50\n4,'=cmd,60,0.02\n", w.Body.String())
+}
+
+func TestTimeReportRejectsLongRange(t *testing.T) {
+	mockStore := new(MockStore)
+	service := NewWorklogsService(mockStore)
+
+	req := authorizedRequest(http.MethodGet, "/reports/time?from=2023-01-01&to=2025-01-31", nil, 3)
+	w := httptest.NewRecorder()
+
+	service.handleTimeReport(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockStore.AssertNotCalled(t, "GetTimeReport", mock.Anything)
+}
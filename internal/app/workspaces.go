@@ -0,0 +1,258 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/pkg/utils"
+	"net/http"
+	"slices"
+)
+
+var errWorkspaceNotFound = errors.New("Workspace not found")
+var errInvalidWorkspaceRole = errors.New("role must be MEMBER or ADMIN")
+var errInvalidAutoArchive = errors.New("auto_archive_days must be between 0 and 3650")
+
+// maxAutoArchiveDays bounds auto_archive_days; 0 turns auto-archiving off.
+const maxAutoArchiveDays = 3650
+
+type WorkspacesService struct {
+	store common.Store
+}
+
+func NewWorkspacesService(store common.Store) *WorkspacesService {
+	return &WorkspacesService{store: store}
+}
+
+func (s *WorkspacesService) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /workspaces", auth.WithJWTAuth(s.handleGetWorkspaces, s.store))
+	router.HandleFunc("POST /workspaces", auth.WithJWTAuth(s.handleCreateWorkspace, s.store))
+	router.HandleFunc("PATCH /workspaces/{id}", auth.WithJWTAuth(s.handleUpdateWorkspace, s.store))
+	router.HandleFunc("GET /workspaces/{id}/members", auth.WithJWTAuth(s.handleGetMembers, s.store))
+	router.HandleFunc("POST /workspaces/{id}/members", auth.WithJWTAuth(s.handleAddMember, s.store))
+}
+
+func (s *WorkspacesService) handleGetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	workspaces, err := s.store.GetWorkspacesForUser(userID)
+	if err != nil {
+		http.Error(w, "Error getting workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, workspaces)
+}
+
+func (s *WorkspacesService) handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var payload common.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ws, err := s.store.CreateWorkspace(&payload, userID)
+	if err != nil {
+		http.Error(w, "Error creating workspace", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, ws)
+}
+
+// handleUpdateWorkspace lets admins rename the workspace and change its
+// settings, such as `{"checklist_blocks_done": true}` or
+// `{"auto_archive_days": 30}`.
+func (s *WorkspacesService) handleUpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Name                *string `json:"name"`
+		ChecklistBlocksDone *bool   `json:"checklist_blocks_done"`
+		AutoArchiveDays     *int    `json:"auto_archive_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	ws, err := s.store.GetWorkspace(workspaceID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errWorkspaceNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error getting workspace", http.StatusInternalServerError)
+		return
+	}
+
+	if payload.Name != nil {
+		ws.Name = *payload.Name
+	}
+	if payload.ChecklistBlocksDone != nil {
+		ws.ChecklistBlocksDone = *payload.ChecklistBlocksDone
+	}
+	if payload.AutoArchiveDays != nil {
+		if *payload.AutoArchiveDays < 0 || *payload.AutoArchiveDays > maxAutoArchiveDays {
+			http.Error(w, errInvalidAutoArchive.Error(), http.StatusBadRequest)
+			return
+		}
+		ws.AutoArchiveDays = *payload.AutoArchiveDays
+	}
+	if ws.Name == "" {
+		http.Error(w, errNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.UpdateWorkspace(ws); err != nil {
+		http.Error(w, "Error updating workspace", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, ws)
+}
+
+func (s *WorkspacesService) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	members, err := s.store.GetWorkspaceMembers(workspaceID)
+	if err != nil {
+		http.Error(w, "Error getting workspace members", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, members)
+}
+
+func (s *WorkspacesService) handleAddMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID, _, ok := requireWorkspaceMember(s.store, w, r, common.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var payload common.WorkspaceMember
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if payload.UserID == 0 {
+		http.Error(w, errUserIDRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	switch payload.Role {
+	case "":
+		payload.Role = common.WorkspaceRoleMember
+	case common.WorkspaceRoleMember, common.WorkspaceRoleAdmin:
+	default:
+		http.Error(w, errInvalidWorkspaceRole.Error(), http.StatusBadRequest)
+		return
+	}
+	payload.WorkspaceID = int64(workspaceID)
+
+	if _, err := s.store.GetUserByID(int(payload.UserID)); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.AddWorkspaceMember(&payload); err != nil {
+		http.Error(w, "Error adding workspace member", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, payload)
+}
+
+// requireWorkspaceMember reads the workspace id from the path and makes sure
+// the caller belongs to it, as an admin when role is WorkspaceRoleAdmin. It
+// writes the error response itself and reports whether the handler may
+// continue.
+func requireWorkspaceMember(store common.Store, w http.ResponseWriter, r *http.Request, role string) (int, int, bool) {
+	workspaceID, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	userID, ok := checkWorkspaceMember(store, w, r, workspaceID, role)
+	return workspaceID, userID, ok
+}
+
+// checkWorkspaceMember is requireWorkspaceMember for a workspace id that does
+// not come from the path. Workspaces the caller does not belong to are
+// reported as missing.
+func checkWorkspaceMember(store common.Store, w http.ResponseWriter, r *http.Request, workspaceID int, role string) (int, bool) {
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+
+	member, err := store.GetWorkspaceMember(workspaceID, userID)
+	if errors.Is(err, common.ErrNotFound) {
+		http.Error(w, errWorkspaceNotFound.Error(), http.StatusNotFound)
+		return 0, false
+	}
+	if err != nil {
+		http.Error(w, "Error getting workspace", http.StatusInternalServerError)
+		return 0, false
+	}
+	if role == common.WorkspaceRoleAdmin && member.Role != common.WorkspaceRoleAdmin {
+		http.Error(w, "Only workspace admins can do that", http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// checkTaskAccess answers for the caller when they cannot act on the task,
+// by the same rule as the task queries: workspace tasks need a membership
+// with role, and personal tasks belong to their assignees. Tasks the caller
+// cannot see are reported as missing.
+func checkTaskAccess(store common.Store, w http.ResponseWriter, r *http.Request, task *common.Task, role string) (int, bool) {
+	if task.WorkspaceID != nil {
+		return checkWorkspaceMember(store, w, r, int(*task.WorkspaceID), role)
+	}
+
+	userID, err := auth.GetUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+	if !isTaskAssignee(task, userID) {
+		http.Error(w, errTaskNotFound.Error(), http.StatusNotFound)
+		return 0, false
+	}
+	return userID, true
+}
+
+// isTaskAssignee reports whether the user is one of the task's assignees,
+// who are the only ones to see a personal task.
+func isTaskAssignee(task *common.Task, userID int) bool {
+	return task.AssignedToID == int64(userID) || slices.Contains(task.AssigneeIDs, int64(userID))
+}
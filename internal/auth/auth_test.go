@@ -5,11 +5,13 @@ import (
 	"github.com/golang-jwt/jwt"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/auth"
 	"github.com/pkacprzak5/TaskManagementSystem/internal/common"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type MockStore struct {
@@ -24,10 +26,190 @@ func (m *MockStore) GetUserByID(id int) (*common.User, error) {
 	}
 	return user, nil
 }
-func (m *MockStore) CreateTask(task *common.Task) (*common.Task, error)    { return nil, nil }
-func (m *MockStore) GetTask(id int) (*common.Task, error)                  { return nil, nil }
-func (m *MockStore) UpdateTaskStatusByID(id int) (*common.Task, error)     { return nil, nil }
-func (m *MockStore) GetTasksAssignedToUser(id int) ([]*common.Task, error) { return nil, nil }
+func (m *MockStore) CreateTask(task *common.Task, actorID int) (*common.Task, error) { return nil, nil }
+func (m *MockStore) GetTask(id int) (*common.Task, error)                            { return nil, nil }
+func (m *MockStore) UpdateTaskStatusByID(id int, opts common.StatusUpdate) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) GetTasksAssignedToUser(id int) ([]*common.Task, error)         { return nil, nil }
+func (m *MockStore) QueryTasks(q *query.Query, userID int) ([]*common.Task, error) { return nil, nil }
+func (m *MockStore) SearchTasks(query string, limit, userID int) ([]*common.SearchResult, error) {
+	return nil, nil
+}
+func (m *MockStore) CreateTeam(team *common.Team, creatorID int) (*common.Team, error) {
+	return nil, nil
+}
+func (m *MockStore) GetTeamsForUser(userID int) ([]*common.Team, error)           { return nil, nil }
+func (m *MockStore) GetTeamMember(teamID, userID int) (*common.TeamMember, error) { return nil, nil }
+func (m *MockStore) GetTeamMembers(teamID int) ([]*common.TeamMember, error)      { return nil, nil }
+func (m *MockStore) AddTeamMember(member *common.TeamMember) error                { return nil }
+func (m *MockStore) CreateView(v *common.View) (*common.View, error)              { return nil, nil }
+func (m *MockStore) GetView(id, userID int) (*common.View, error)                 { return nil, nil }
+func (m *MockStore) GetViewsForUser(userID int) ([]*common.View, error)           { return nil, nil }
+func (m *MockStore) UpdateView(v *common.View) error                              { return nil }
+func (m *MockStore) DeleteView(id int) error                                      { return nil }
+func (m *MockStore) SetViewPinned(viewID, userID int, pinned bool) error          { return nil }
+func (m *MockStore) CreateWorkspace(ws *common.Workspace, creatorID int) (*common.Workspace, error) {
+	return nil, nil
+}
+func (m *MockStore) GetWorkspacesForUser(userID int) ([]*common.Workspace, error) { return nil, nil }
+func (m *MockStore) GetWorkspaceMember(workspaceID, userID int) (*common.WorkspaceMember, error) {
+	return nil, nil
+}
+func (m *MockStore) GetWorkspaceMembers(workspaceID int) ([]*common.WorkspaceMember, error) {
+	return nil, nil
+}
+func (m *MockStore) AddWorkspaceMember(member *common.WorkspaceMember) error         { return nil }
+func (m *MockStore) CreateLabel(l *common.Label) (*common.Label, error)              { return nil, nil }
+func (m *MockStore) GetLabel(id int) (*common.Label, error)                          { return nil, nil }
+func (m *MockStore) GetLabels(workspaceID int) ([]*common.Label, error)              { return nil, nil }
+func (m *MockStore) UpdateLabel(l *common.Label) error                               { return nil }
+func (m *MockStore) DeleteLabel(id int) error                                        { return nil }
+func (m *MockStore) AddTaskLabel(taskID, labelID, actorID int) error                 { return nil }
+func (m *MockStore) RemoveTaskLabel(taskID, labelID, actorID int) error              { return nil }
+func (m *MockStore) CreateComment(c *common.Comment) (*common.Comment, error)        { return nil, nil }
+func (m *MockStore) GetComment(id int) (*common.Comment, error)                      { return nil, nil }
+func (m *MockStore) GetComments(taskID, after, limit int) ([]*common.Comment, error) { return nil, nil }
+func (m *MockStore) UpdateComment(id int, body string) (*common.Comment, error)      { return nil, nil }
+func (m *MockStore) DeleteComment(id int) error                                      { return nil }
+func (m *MockStore) GetCommentRevisions(commentID int) ([]*common.CommentRevision, error) {
+	return nil, nil
+}
+func (m *MockStore) GetTaskActivity(taskID, before, limit int) ([]*common.Activity, error) {
+	return nil, nil
+}
+func (m *MockStore) GetMentionsForUser(userID, limit int) ([]*common.Mention, error) { return nil, nil }
+func (m *MockStore) GetNotifications(userID, limit int) ([]*common.Notification, error) {
+	return nil, nil
+}
+func (m *MockStore) CreateAttachment(a *common.Attachment) (*common.Attachment, error) {
+	return nil, nil
+}
+func (m *MockStore) GetAttachment(id int) (*common.Attachment, error)                { return nil, nil }
+func (m *MockStore) GetAttachments(taskID int) ([]*common.Attachment, error)         { return nil, nil }
+func (m *MockStore) DeleteAttachment(id, actorID int) error                          { return nil }
+func (m *MockStore) GetSubtasks(parentID int) ([]*common.Task, error)                { return nil, nil }
+func (m *MockStore) SetTaskParent(id int, parentID *int64, actorID int) error        { return nil }
+func (m *MockStore) ReorderSubtasks(parentID int, ids []int64) error                 { return nil }
+func (m *MockStore) AddTaskDependency(blockerID, blockedID, actorID int) error       { return nil }
+func (m *MockStore) RemoveTaskDependency(blockerID, blockedID, actorID int) error    { return nil }
+func (m *MockStore) GetTaskDependencies(taskID int) (*common.DependencyGraph, error) { return nil, nil }
+func (m *MockStore) AddTaskAssignee(taskID, userID, actorID int) error               { return nil }
+func (m *MockStore) RemoveTaskAssignee(taskID, userID, actorID int) error            { return nil }
+func (m *MockStore) GetTaskWatchers(taskID int) ([]int64, error)                     { return nil, nil }
+func (m *MockStore) AddTaskWatcher(taskID, userID, actorID int) error                { return nil }
+func (m *MockStore) RemoveTaskWatcher(taskID, userID, actorID int) error             { return nil }
+func (m *MockStore) SetTaskRecurrence(rec *common.Recurrence, actorID int) (*common.Recurrence, error) {
+	return nil, nil
+}
+func (m *MockStore) GetTaskRecurrence(taskID int) (*common.Recurrence, error) { return nil, nil }
+func (m *MockStore) DeleteTaskRecurrence(taskID, actorID int) error           { return nil }
+func (m *MockStore) AdvanceRecurrences(now time.Time, limit int) ([]*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) PlanJobs(now time.Time) error                                 { return nil }
+func (m *MockStore) RunDueJobs(now time.Time, limit int) (int, error)             { return 0, nil }
+func (m *MockStore) GetReminderSettings(userID int) ([]time.Duration, error)      { return nil, nil }
+func (m *MockStore) SetReminderSettings(userID int, before []time.Duration) error { return nil }
+func (m *MockStore) StartTimer(taskID, userID int, now time.Time) (*common.Worklog, error) {
+	return nil, nil
+}
+func (m *MockStore) StopTimer(userID int, now time.Time) (*common.Worklog, error) { return nil, nil }
+func (m *MockStore) GetRunningTimer(userID int) (*common.Worklog, error)          { return nil, nil }
+func (m *MockStore) CreateWorklog(w *common.Worklog) (*common.Worklog, error)     { return nil, nil }
+func (m *MockStore) GetWorklog(id int) (*common.Worklog, error)                   { return nil, nil }
+func (m *MockStore) GetWorklogs(taskID int) ([]*common.Worklog, error)            { return nil, nil }
+func (m *MockStore) DeleteWorklog(id int) error                                   { return nil }
+func (m *MockStore) GetTimeReport(filter common.TimeReportFilter) ([]*common.TimeReportRow, error) {
+	return nil, nil
+}
+func (m *MockStore) GetChecklist(taskID int) ([]*common.ChecklistItem, error) { return nil, nil }
+func (m *MockStore) GetChecklistItem(id int) (*common.ChecklistItem, error)   { return nil, nil }
+func (m *MockStore) AddChecklistItem(item *common.ChecklistItem, actorID int) (*common.ChecklistItem, error) {
+	return nil, nil
+}
+func (m *MockStore) UpdateChecklistItem(item *common.ChecklistItem, actorID int) error { return nil }
+func (m *MockStore) ReorderChecklist(taskID int, ids []int64, actorID int) error       { return nil }
+func (m *MockStore) DeleteChecklistItem(id, actorID int) error                         { return nil }
+func (m *MockStore) GetWorkspace(id int) (*common.Workspace, error)                    { return nil, nil }
+func (m *MockStore) UpdateWorkspace(ws *common.Workspace) error                        { return nil }
+func (m *MockStore) CreateTemplate(t *common.Template) (*common.Template, error)       { return nil, nil }
+func (m *MockStore) GetTemplate(id int) (*common.Template, error)                      { return nil, nil }
+func (m *MockStore) GetTemplates(workspaceID int) ([]*common.Template, error)          { return nil, nil }
+func (m *MockStore) UpdateTemplate(t *common.Template) error                           { return nil }
+func (m *MockStore) DeleteTemplate(id int) error                                       { return nil }
+func (m *MockStore) CreateTaskTree(tree *common.TaskTree, actorID int) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) UpdateTask(id int, patch common.TaskPatch, actorID int) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) DeleteTask(id, actorID int) error                  { return nil }
+func (m *MockStore) GetTrashedTask(id int) (*common.Task, error)       { return nil, nil }
+func (m *MockStore) RestoreTask(id, actorID int) (*common.Task, error) { return nil, nil }
+func (m *MockStore) GetTrash(userID int) ([]*common.Task, error)       { return nil, nil }
+func (m *MockStore) PurgeTrash(before time.Time, limit int) (int, []string, error) {
+	return 0, nil, nil
+}
+func (m *MockStore) ArchiveTask(id, actorID int) (*common.Task, error)                { return nil, nil }
+func (m *MockStore) UnarchiveTask(id, actorID int) (*common.Task, error)              { return nil, nil }
+func (m *MockStore) AutoArchiveTasks(now time.Time) (int64, error)                    { return 0, nil }
+func (m *MockStore) ExportArchive(workspaceID int, fn func(*common.Task) error) error { return nil }
+func (m *MockStore) ExportProjectArchive(projectID int, fn func(*common.Task) error) error {
+	return nil
+}
+func (m *MockStore) PurgeArchive(projectID int) (int, []string, error) { return 0, nil, nil }
+func (m *MockStore) ApplyBulk(action common.BulkAction, ids []int64, atomic bool) ([]error, error) {
+	return nil, nil
+}
+func (m *MockStore) CreateProject(p *common.Project) (*common.Project, error) { return nil, nil }
+func (m *MockStore) GetProject(id int) (*common.Project, error)               { return nil, nil }
+func (m *MockStore) GetProjects(workspaceID int) ([]*common.Project, error)   { return nil, nil }
+func (m *MockStore) UpdateProject(p *common.Project) error                    { return nil }
+func (m *MockStore) GetTaskByKey(projectKey string, number int64, userID int) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) MoveTask(id int, workspaceID int64, projectID *int64, statusMap map[string]string, actorID int) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) GetBoard(projectID int) ([]*common.Task, error)                  { return nil, nil }
+func (m *MockStore) MoveOnBoard(id int, move common.BoardMove) (*common.Task, error) { return nil, nil }
+func (m *MockStore) CreateSprint(sp *common.Sprint) (*common.Sprint, error)          { return nil, nil }
+func (m *MockStore) GetSprint(id int) (*common.Sprint, error)                        { return nil, nil }
+func (m *MockStore) GetSprints(projectID int) ([]*common.Sprint, error)              { return nil, nil }
+func (m *MockStore) UpdateSprint(sp *common.Sprint) error                            { return nil }
+func (m *MockStore) GetSprintTasks(sprintID int) ([]*common.Task, error)             { return nil, nil }
+func (m *MockStore) GetBacklog(projectID int) ([]*common.Task, error)                { return nil, nil }
+func (m *MockStore) AddSprintTask(sprintID, taskID, actorID int) error               { return nil }
+func (m *MockStore) RemoveSprintTask(sprintID, taskID, actorID int) error            { return nil }
+func (m *MockStore) StartSprint(id int, now time.Time) (*common.Sprint, error)       { return nil, nil }
+func (m *MockStore) CompleteSprint(id int, nextSprintID *int64, actorID int, now time.Time) ([]int64, error) {
+	return nil, nil
+}
+func (m *MockStore) CreateMilestone(milestone *common.Milestone) (*common.Milestone, error) {
+	return nil, nil
+}
+func (m *MockStore) GetMilestone(id int) (*common.Milestone, error)           { return nil, nil }
+func (m *MockStore) GetMilestones(projectID int) ([]*common.Milestone, error) { return nil, nil }
+func (m *MockStore) UpdateMilestone(milestone *common.Milestone) error        { return nil }
+func (m *MockStore) GetMilestoneProgress(id int, now time.Time) (*common.MilestoneProgress, error) {
+	return nil, nil
+}
+func (m *MockStore) SetTaskMilestone(taskID int, milestoneID *int64, actorID int) error { return nil }
+func (m *MockStore) CloseMilestone(id int, now time.Time) (*common.Milestone, []common.ReleaseNoteGroup, error) {
+	return nil, nil, nil
+}
+func (m *MockStore) CreateCustomField(f *common.CustomField) (*common.CustomField, error) {
+	return nil, nil
+}
+func (m *MockStore) GetCustomField(id int) (*common.CustomField, error)           { return nil, nil }
+func (m *MockStore) GetCustomFields(projectID int) ([]*common.CustomField, error) { return nil, nil }
+func (m *MockStore) UpdateCustomField(f *common.CustomField) error                { return nil }
+func (m *MockStore) DeleteCustomField(id int) error                               { return nil }
+func (m *MockStore) CloneTask(id int, opts common.CloneOptions) (*common.Task, error) {
+	return nil, nil
+}
+func (m *MockStore) MergeTask(id, targetID, actorID int) (*common.Task, error) { return nil, nil }
 
 func TestWithJWTAuth_Success(t *testing.T) {
 	store := &MockStore{
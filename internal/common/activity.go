@@ -0,0 +1,63 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// recordActivity appends an entry to the task's activity feed as part of
+// the caller's transaction, so the entry exists exactly when the change does.
+func recordActivity(tx *sql.Tx, taskID, actorID int64, action string, details any) error {
+	return insertActivity(tx, taskID, &actorID, action, details)
+}
+
+// recordAutomaticActivity records a change that no user made, such as
+// automatic archiving. Its entry has no actor.
+func recordAutomaticActivity(tx *sql.Tx, taskID int64, action string, details any) error {
+	return insertActivity(tx, taskID, nil, action, details)
+}
+
+func insertActivity(tx *sql.Tx, taskID int64, actorID *int64, action string, details any) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO activity (taskID, actorID, action, details) VALUES (?, ?, ?, ?)",
+		taskID, actorID, action, encoded)
+	if err != nil {
+		return fmt.Errorf("failed to record activity on task %d: %w", taskID, err)
+	}
+	return nil
+}
+
+// GetTaskActivity returns up to limit entries of the task's activity feed
+// older than the entry before, newest first. before 0 starts at the newest.
+func (s *Storage) GetTaskActivity(taskID, before, limit int) ([]*Activity, error) {
+	if before == 0 {
+		before = math.MaxInt32
+	}
+	rows, err := s.db.Query(`SELECT id, taskID, actorID, action, details, createdAt FROM activity
+		WHERE taskID = ? AND id < ? ORDER BY id DESC LIMIT ?`, taskID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	entries := []*Activity{}
+	for rows.Next() {
+		var a Activity
+		var details []byte
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.ActorID, &a.Action, &details, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity row: %w", err)
+		}
+		a.Details = details
+		entries = append(entries, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return entries, nil
+}
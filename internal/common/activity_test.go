@@ -0,0 +1,112 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUpdateTaskRecordsChanges(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	dueAt := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", DueAt: &dueAt, AssignedToID: 1,
+		CreatedAt: time.Now()}
+	updated := &Task{ID: 1, Name: "Fix login timeout", Status: "TODO", Priority: "P2", AssignedToID: 1,
+		CreatedAt: task.CreatedAt}
+	name, priority := "Fix login timeout", "P2"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectExec("UPDATE tasks SET name = \\?, description = \\?, priority = \\?, dueAt = \\?, points = \\? WHERE id = ?").
+		WithArgs("Fix login timeout", "", "P2", nil, nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskEdited,
+			[]byte(`{"changes":{"due_at":{"from":"2025-01-08T12:00:00Z","to":null},"name":{"from":"Fix login","to":"Fix login timeout"}}}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, updated)
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(1).
+		WillReturnRows(taskRows(updated))
+
+	got, err := store.UpdateTask(1, TaskPatch{Name: &name, Priority: &priority, ClearDueAt: true}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fix login timeout", got.Name)
+	assert.Nil(t, got.DueAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTaskWithoutChanges(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+	name := "Fix login"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+
+	_, err := store.UpdateTask(1, TaskPatch{Name: &name}, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTaskAssigneeTwiceRecordsOnce(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO task_assignees").
+		WithArgs(1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityAssigneeAdded, []byte(`{"user_id":4}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO task_assignees").
+		WithArgs(1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.AddTaskAssignee(1, 4, 3))
+	assert.NoError(t, store.AddTaskAssignee(1, 4, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskActivityPages(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM activity WHERE taskID = \\? AND id < \\? ORDER BY id DESC LIMIT ?").
+		WithArgs(1, 40, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "taskID", "actorID", "action", "details", "createdAt"}).
+			AddRow(39, 1, 3, ActivityStatusChanged, []byte(`{"from":"TODO","to":"IN_PROGRESS"}`), now).
+			AddRow(35, 1, 3, ActivityTaskEdited, []byte(`{"changes":{}}`), now))
+
+	entries, err := store.GetTaskActivity(1, 40, 2)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, int64(39), entries[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,246 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ArchiveTask archives the DONE task. Archived tasks are left out of task
+// lists and queries unless asked for with is:archived, but can still be
+// opened, searched and unarchived.
+func (s *Storage) ArchiveTask(id, actorID int) (*Task, error) {
+	return s.setArchived(id, actorID, true)
+}
+
+// UnarchiveTask brings the archived task back to task lists.
+func (s *Storage) UnarchiveTask(id, actorID int) (*Task, error) {
+	return s.setArchived(id, actorID, false)
+}
+
+func (s *Storage) setArchived(id, actorID int, archive bool) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		task, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL FOR UPDATE", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", id, err)
+		}
+		if (task.ArchivedAt != nil) == archive {
+			return nil
+		}
+
+		var archivedAt *time.Time
+		action := ActivityTaskUnarchived
+		if archive {
+			if task.Status != "DONE" {
+				return ErrTaskNotDone
+			}
+			now := time.Now()
+			archivedAt = &now
+			action = ActivityTaskArchived
+		}
+
+		if _, err := tx.Exec("UPDATE tasks SET archivedAt = ? WHERE id = ?", archivedAt, id); err != nil {
+			return fmt.Errorf("failed to archive task %d: %w", id, err)
+		}
+		return recordActivity(tx, task.ID, int64(actorID), action, map[string]any{"from": task.ArchivedAt, "to": archivedAt})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTask(id)
+}
+
+// AutoArchiveTasks archives the tasks that have been DONE for longer than
+// their workspace's AutoArchiveDays, and returns how many it archived.
+// Tasks finished before DONE times were kept count from their creation.
+// Each archived task records it in its activity, without an actor.
+func (s *Storage) AutoArchiveTasks(now time.Time) (int64, error) {
+	var archived int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		archived = 0
+		rows, err := tx.Query(`
+			SELECT t.id FROM tasks t JOIN workspaces w ON w.id = t.workspaceID
+			WHERE w.autoArchiveDays > 0 AND t.status = 'DONE' AND t.archivedAt IS NULL AND t.deletedAt IS NULL
+				AND COALESCE(t.doneAt, t.createdAt) <= DATE_SUB(?, INTERVAL w.autoArchiveDays DAY)
+			FOR UPDATE SKIP LOCKED`, now)
+		if err != nil {
+			return fmt.Errorf("failed to get done tasks: %w", err)
+		}
+		ids, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		args := append([]any{now}, int64Args(ids)...)
+		if _, err := tx.Exec("UPDATE tasks SET archivedAt = ? WHERE id IN ("+placeholders(len(ids))+")", args...); err != nil {
+			return fmt.Errorf("failed to archive done tasks: %w", err)
+		}
+		for _, id := range ids {
+			err := recordAutomaticActivity(tx, id, ActivityTaskArchived, map[string]any{"from": nil, "to": now})
+			if err != nil {
+				return err
+			}
+		}
+		archived = int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// ExportArchive calls fn with every archived task of the workspace, oldest
+// archived first. The tasks are streamed, so exports of large archives do
+// not have to fit in memory.
+func (s *Storage) ExportArchive(workspaceID int, fn func(*Task) error) error {
+	err := s.exportArchive("t.workspaceID = ?", workspaceID, fn)
+	if err != nil {
+		return fmt.Errorf("failed to export archive of workspace %d: %w", workspaceID, err)
+	}
+	return nil
+}
+
+// ExportProjectArchive is ExportArchive for the archived tasks of a project.
+func (s *Storage) ExportProjectArchive(projectID int, fn func(*Task) error) error {
+	err := s.exportArchive("t.projectID = ?", projectID, fn)
+	if err != nil {
+		return fmt.Errorf("failed to export archive of project %d: %w", projectID, err)
+	}
+	return nil
+}
+
+func (s *Storage) exportArchive(scope string, id int, fn func(*Task) error) error {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		WHERE `+scope+` AND t.archivedAt IS NOT NULL AND t.deletedAt IS NULL
+		ORDER BY t.archivedAt, t.id`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan task row: %w", err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return nil
+}
+
+// PurgeArchive deletes the archived tasks of the project for good, so they
+// no longer weigh on the tasks table. Nothing checks that they have been
+// exported first; the caller asks for that. An archived task whose subtasks
+// are not all archived too is kept, since an export would not hold them. It
+// returns how many tasks were deleted and the storage keys of their
+// attachments, which the caller deletes.
+func (s *Storage) PurgeArchive(projectID int) (int, []string, error) {
+	var purged []int64
+	var keys []string
+	err := s.withTx(func(tx *sql.Tx) error {
+		purged, keys = nil, nil
+
+		rows, err := tx.Query(`SELECT id FROM tasks
+			WHERE projectID = ? AND archivedAt IS NOT NULL AND deletedAt IS NULL FOR UPDATE`, projectID)
+		if err != nil {
+			return fmt.Errorf("failed to get archived tasks: %w", err)
+		}
+		ids, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// Subtasks go with their parent through the cascade, so the whole
+		// subtree of each archived task is checked.
+		rows, err = tx.Query(`
+			WITH RECURSIVE subtree (root, id) AS (
+				SELECT id, id FROM tasks WHERE id IN (`+placeholders(len(ids))+`)
+				UNION ALL
+				SELECT st.root, t.id FROM tasks t JOIN subtree st ON t.parentID = st.id
+			)
+			SELECT st.root, st.id, t.archivedAt IS NULL AND t.deletedAt IS NULL
+			FROM subtree st JOIN tasks t ON t.id = st.id`, int64Args(ids)...)
+		if err != nil {
+			return fmt.Errorf("failed to get subtasks of archived tasks: %w", err)
+		}
+		subtrees := map[int64][]int64{}
+		kept := map[int64]bool{}
+		for rows.Next() {
+			var root, id int64
+			var live bool
+			if err := rows.Scan(&root, &id, &live); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan task row: %w", err)
+			}
+			subtrees[root] = append(subtrees[root], id)
+			if live {
+				kept[root] = true
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over rows: %w", err)
+		}
+
+		var roots []int64
+		seen := map[int64]bool{}
+		for _, root := range ids {
+			if kept[root] {
+				continue
+			}
+			roots = append(roots, root)
+			for _, id := range subtrees[root] {
+				if !seen[id] {
+					seen[id] = true
+					purged = append(purged, id)
+				}
+			}
+		}
+		if len(roots) == 0 {
+			return nil
+		}
+
+		keys, err = purgeTasks(tx, roots)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.removeFromIndex(purged)
+	return len(purged), keys, nil
+}
+
+// scanIDs reads a single column of ids and closes rows.
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan id row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,165 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestArchiveTaskRequiresDone(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Fix login", Status: "IN_TESTING", Priority: "P2", AssignedToID: 3, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectRollback()
+
+	_, err := store.ArchiveTask(1, 3)
+	assert.ErrorIs(t, err, ErrTaskNotDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArchiveTask(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Fix login", Status: "DONE", Priority: "P2", AssignedToID: 3, CreatedAt: time.Now()}
+	archivedAt := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	archived := *task
+	archived.ArchivedAt = &archivedAt
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectExec("UPDATE tasks SET archivedAt = \\? WHERE id = ?").
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskArchived, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(1).
+		WillReturnRows(taskRows(&archived))
+
+	got, err := store.ArchiveTask(1, 3)
+	assert.NoError(t, err)
+	assert.NotNil(t, got.ArchivedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAutoArchiveTasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT t.id FROM tasks t JOIN workspaces w ON w.id = t.workspaceID (.+) INTERVAL w.autoArchiveDays DAY\\)\\s+FOR UPDATE SKIP LOCKED").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(6))
+	mock.ExpectExec("UPDATE tasks SET archivedAt = \\? WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(now, int64(4), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for i, id := range []int64{4, 6} {
+		mock.ExpectExec("INSERT INTO activity").
+			WithArgs(id, nil, ActivityTaskArchived, []byte(`{"from":null,"to":"2025-01-08T12:00:00Z"}`)).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+	mock.ExpectCommit()
+
+	archived, err := store.AutoArchiveTasks(now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportArchive(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	archivedAt := time.Now()
+	workspaceID := int64(2)
+	tasks := []*Task{
+		{ID: 1, Name: "Release 1.3", Status: "DONE", Priority: "P2", AssignedToID: 3, WorkspaceID: &workspaceID, ArchivedAt: &archivedAt},
+		{ID: 4, Name: "Release 1.4", Status: "DONE", Priority: "P2", AssignedToID: 3, WorkspaceID: &workspaceID, ArchivedAt: &archivedAt},
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+WHERE t.workspaceID = \\? AND t.archivedAt IS NOT NULL AND t.deletedAt IS NULL\\s+ORDER BY t.archivedAt, t.id").
+		WithArgs(2).
+		WillReturnRows(taskRows(tasks...))
+
+	var ids []int64
+	err := store.ExportArchive(2, func(task *Task) error {
+		ids = append(ids, task.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 4}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportProjectArchive(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	archivedAt := time.Now()
+	projectID := int64(4)
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+WHERE t.projectID = \\? AND t.archivedAt IS NOT NULL AND t.deletedAt IS NULL\\s+ORDER BY t.archivedAt, t.id").
+		WithArgs(4).
+		WillReturnRows(taskRows(&Task{ID: 1, Name: "Release 1.3", Status: "DONE", Priority: "P2", AssignedToID: 3,
+			ProjectID: &projectID, ArchivedAt: &archivedAt}))
+
+	var ids []int64
+	err := store.ExportProjectArchive(4, func(task *Task) error {
+		ids = append(ids, task.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeArchiveKeepsTasksWithOpenSubtasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks\\s+WHERE projectID = \\? AND archivedAt IS NOT NULL AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(5))
+	// Task 2 is a subtask of 1; task 5 has a subtask 6 that is not archived.
+	mock.ExpectQuery("WITH RECURSIVE subtree \\(root, id\\)").
+		WithArgs(int64(1), int64(2), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"root", "id", "live"}).
+			AddRow(1, 1, false).AddRow(2, 2, false).AddRow(5, 5, false).
+			AddRow(1, 2, false).AddRow(5, 6, true))
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) SELECT DISTINCT a.storageKey FROM attachments a").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"storageKey"}).AddRow("tasks/2/abc"))
+	mock.ExpectExec("DELETE FROM tasks WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	purged, keys, err := store.PurgeArchive(4)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, []string{"tasks/2/abc"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
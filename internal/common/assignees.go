@@ -0,0 +1,147 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// AddTaskAssignee assigns the user to the task. Assigning a user twice
+// changes nothing.
+func (s *Storage) AddTaskAssignee(taskID, userID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("INSERT IGNORE INTO task_assignees (taskID, userID) VALUES (?, ?)", taskID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to assign task %d: %w", taskID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityAssigneeAdded, map[string]any{"user_id": userID})
+	})
+}
+
+// RemoveTaskAssignee unassigns the user. When the user was the primary
+// assignee, the remaining assignee with the lowest id takes over. The last
+// assignee cannot be removed.
+func (s *Storage) RemoveTaskAssignee(taskID, userID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var primaryID int64
+		err := tx.QueryRow("SELECT assignedToID FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", taskID).Scan(&primaryID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", taskID, err)
+		}
+
+		var assigned, total int
+		err = tx.QueryRow("SELECT COALESCE(SUM(userID = ?), 0), COUNT(*) FROM task_assignees WHERE taskID = ?",
+			userID, taskID).Scan(&assigned, &total)
+		if err != nil {
+			return fmt.Errorf("failed to get assignees of task %d: %w", taskID, err)
+		}
+		if assigned == 0 {
+			return ErrNotFound
+		}
+		if total == 1 {
+			return ErrLastAssignee
+		}
+
+		_, err = tx.Exec("DELETE FROM task_assignees WHERE taskID = ? AND userID = ?", taskID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to unassign task %d: %w", taskID, err)
+		}
+
+		details := map[string]any{"user_id": userID}
+		if primaryID == int64(userID) {
+			var newPrimaryID int64
+			err = tx.QueryRow("SELECT MIN(userID) FROM task_assignees WHERE taskID = ?", taskID).Scan(&newPrimaryID)
+			if err != nil {
+				return fmt.Errorf("failed to get assignees of task %d: %w", taskID, err)
+			}
+			_, err = tx.Exec("UPDATE tasks SET assignedToID = ? WHERE id = ?", newPrimaryID, taskID)
+			if err != nil {
+				return fmt.Errorf("failed to update primary assignee of task %d: %w", taskID, err)
+			}
+			details["assigned_to_id"] = map[string]any{"from": primaryID, "to": newPrimaryID}
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityAssigneeRemoved, details)
+	})
+}
+
+func (s *Storage) GetTaskWatchers(taskID int) ([]int64, error) {
+	return watcherIDs(s.db, int64(taskID))
+}
+
+func watcherIDs(q querier, taskID int64) ([]int64, error) {
+	rows, err := q.Query("SELECT userID FROM task_watchers WHERE taskID = ? ORDER BY userID", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchers of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan watcher row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return ids, nil
+}
+
+// AddTaskWatcher subscribes the user to the task. Watching a task twice
+// changes nothing.
+func (s *Storage) AddTaskWatcher(taskID, userID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("INSERT IGNORE INTO task_watchers (taskID, userID) VALUES (?, ?)", taskID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add watcher to task %d: %w", taskID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityWatcherAdded, map[string]any{"user_id": userID})
+	})
+}
+
+func (s *Storage) RemoveTaskWatcher(taskID, userID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM task_watchers WHERE taskID = ? AND userID = ?", taskID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to remove watcher from task %d: %w", taskID, err)
+		}
+		if err := requireAffected(res); err != nil {
+			return err
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityWatcherRemoved, map[string]any{"user_id": userID})
+	})
+}
+
+// notifyWatchers notifies everyone watching the task, except the user who
+// made the change.
+func notifyWatchers(q querier, taskID, actorID int64, kind string, details any) error {
+	watchers, err := watcherIDs(q, taskID)
+	if err != nil {
+		return err
+	}
+
+	var actor *int64
+	if actorID != 0 {
+		actor = &actorID
+	}
+	for _, userID := range watchers {
+		if userID == actorID {
+			continue
+		}
+		if err := notify(q, userID, kind, taskID, actor, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRemoveTaskAssigneeHandsOverPrimary(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT assignedToID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"assignedToID"}).AddRow(3))
+	mock.ExpectQuery("SELECT (.+) FROM task_assignees WHERE taskID = ?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"assigned", "total"}).AddRow(1, 2))
+	mock.ExpectExec("DELETE FROM task_assignees WHERE taskID = \\? AND userID = ?").
+		WithArgs(1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT MIN\\(userID\\) FROM task_assignees WHERE taskID = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"userID"}).AddRow(4))
+	mock.ExpectExec("UPDATE tasks SET assignedToID = \\? WHERE id = ?").
+		WithArgs(int64(4), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(5), ActivityAssigneeRemoved, []byte(`{"assigned_to_id":{"from":3,"to":4},"user_id":3}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.RemoveTaskAssignee(1, 3, 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveLastTaskAssignee(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT assignedToID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"assignedToID"}).AddRow(3))
+	mock.ExpectQuery("SELECT (.+) FROM task_assignees WHERE taskID = ?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"assigned", "total"}).AddRow(1, 1))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.RemoveTaskAssignee(1, 3, 5), ErrLastAssignee)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateTaskWithAssignees(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	task := &Task{Name: "Pair on review", Status: "TODO", Priority: "P2", AssignedToID: 1, AssigneeIDs: []int64{4, 1, 4}}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(7), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(7), int64(3), ActivityTaskCreated, []byte(`{"parent_id":null}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO task_search").
+		WithArgs(int64(7), task.Name, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	created, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 4}, created.AssigneeIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const attachmentColumns = "id, taskID, uploaderID, filename, contentType, size, storageKey, createdAt"
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	var a Attachment
+	err := row.Scan(&a.ID, &a.TaskID, &a.UploaderID, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAttachment stores the metadata of an uploaded file and records it
+// in the task's activity feed. The content must already be in blob storage.
+func (s *Storage) CreateAttachment(a *Attachment) (*Attachment, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`INSERT INTO attachments (taskID, uploaderID, filename, contentType, size, storageKey)
+			VALUES (?, ?, ?, ?, ?, ?)`, a.TaskID, a.UploaderID, a.Filename, a.ContentType, a.Size, a.StorageKey)
+		if err != nil {
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+		a.ID, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		a.CreatedAt = time.Now()
+
+		return recordActivity(tx, a.TaskID, a.UploaderID, ActivityAttachmentAdded,
+			map[string]any{"attachment_id": a.ID, "filename": a.Filename})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Storage) GetAttachment(id int) (*Attachment, error) {
+	a, err := scanAttachment(s.db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Storage) GetAttachments(taskID int) ([]*Attachment, error) {
+	return taskAttachments(s.db, int64(taskID))
+}
+
+func taskAttachments(q querier, taskID int64) ([]*Attachment, error) {
+	rows, err := q.Query("SELECT "+attachmentColumns+" FROM attachments WHERE taskID = ? ORDER BY id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment removes the metadata and records who deleted it. The
+// caller removes the content from blob storage afterwards.
+func (s *Storage) DeleteAttachment(id, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var taskID int64
+		var filename string
+		err := tx.QueryRow("SELECT taskID, filename FROM attachments WHERE id = ? FOR UPDATE", id).Scan(&taskID, &filename)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get attachment with id %d: %w", id, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM attachments WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete attachment with id %d: %w", id, err)
+		}
+
+		return recordActivity(tx, taskID, int64(actorID), ActivityAttachmentDeleted,
+			map[string]any{"attachment_id": id, "filename": filename})
+	})
+}
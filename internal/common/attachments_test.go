@@ -0,0 +1,45 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeleteAttachmentRecordsActivity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT taskID, filename FROM attachments WHERE id = \\? FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"taskID", "filename"}).AddRow(1, "crash.log"))
+	mock.ExpectExec("DELETE FROM attachments WHERE id = ?").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityAttachmentDeleted, []byte(`{"attachment_id":5,"filename":"crash.log"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.DeleteAttachment(5, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMissingAttachment(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT taskID, filename FROM attachments").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"taskID", "filename"}))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.DeleteAttachment(5, 3), ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
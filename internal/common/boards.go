@@ -0,0 +1,192 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/rank"
+	"time"
+)
+
+// GetBoard returns the live tasks of the project in board order. Tasks of
+// one status keep their relative order; grouping them into columns is up
+// to the caller.
+func (s *Storage) GetBoard(projectID int) ([]*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		WHERE t.projectID = ? AND t.deletedAt IS NULL AND t.archivedAt IS NULL
+		ORDER BY t.boardRank, t.id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board of project %d: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
+
+// MoveOnBoard moves the task to another place on its project board, in
+// the same column or in another one. Only the moved task gets a new rank,
+// so the rest of the column is left untouched. Changing the column changes
+// the status, under the same rules as any other status change: a task only
+// moves to the column of its next status, and ErrInvalidTransition is
+// returned for any other one.
+func (s *Storage) MoveOnBoard(id int, move BoardMove) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		return s.moveOnBoard(tx, int64(id), move)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTask(id)
+}
+
+func (s *Storage) moveOnBoard(tx *sql.Tx, id int64, move BoardMove) error {
+	task, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL FOR UPDATE", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get task %d: %w", id, err)
+	}
+	if task.ProjectID == nil {
+		return ErrNotOnBoard
+	}
+	projectID := *task.ProjectID
+
+	status := move.Status
+	if status == "" {
+		status = task.Status
+	}
+	if status != task.Status && nextStatus[task.Status] != status {
+		return ErrInvalidTransition
+	}
+	// Both paths lock the project row, so moves on one board take turns.
+	if status != task.Status {
+		if err := checkStatusChange(tx, task, status, StatusUpdate{ActorID: move.ActorID}); err != nil {
+			return err
+		}
+	} else if _, err := lockProject(tx, projectID); err != nil {
+		return err
+	}
+
+	var before string
+	query := `SELECT MIN(boardRank) FROM tasks
+		WHERE projectID = ? AND status = ? AND id != ? AND deletedAt IS NULL AND archivedAt IS NULL`
+	args := []any{projectID, status, id}
+	if move.AfterID != nil {
+		if *move.AfterID == id {
+			return ErrInvalidMove
+		}
+		var afterRank sql.NullString
+		err := tx.QueryRow(`SELECT boardRank FROM tasks
+			WHERE id = ? AND projectID = ? AND status = ? AND deletedAt IS NULL AND archivedAt IS NULL`,
+			*move.AfterID, projectID, status).Scan(&afterRank)
+		if errors.Is(err, sql.ErrNoRows) || err == nil && !afterRank.Valid {
+			return ErrInvalidMove
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", *move.AfterID, err)
+		}
+		before = afterRank.String
+		query += " AND boardRank > ?"
+		args = append(args, before)
+	}
+
+	var after sql.NullString
+	if err := tx.QueryRow(query, args...).Scan(&after); err != nil {
+		return fmt.Errorf("failed to get board position of task %d: %w", id, err)
+	}
+	boardRank, err := rank.Between(before, after.String)
+	if err != nil {
+		return fmt.Errorf("failed to rank task %d: %w", id, err)
+	}
+
+	doneAt := task.DoneAt
+	if status != "DONE" {
+		doneAt = nil
+	} else if task.Status != "DONE" {
+		now := time.Now()
+		doneAt = &now
+	}
+	_, err = tx.Exec("UPDATE tasks SET status = ?, doneAt = ?, boardRank = ? WHERE id = ?", status, doneAt, boardRank, id)
+	if err != nil {
+		return fmt.Errorf("failed to move task %d on board: %w", id, err)
+	}
+	if status == task.Status {
+		return nil
+	}
+
+	err = recordActivity(tx, id, move.ActorID, ActivityStatusChanged, map[string]any{"from": task.Status, "to": status})
+	if err != nil {
+		return err
+	}
+	return notifyWatchers(tx, id, move.ActorID, NotificationStatusChanged, map[string]any{"status": status})
+}
+
+// appendRank returns a board rank after every task of the project, which
+// puts a task at the bottom of its column. The caller must hold the lock
+// on the project row.
+func appendRank(q querier, projectID int64) (string, error) {
+	var last sql.NullString
+	if err := q.QueryRow("SELECT MAX(boardRank) FROM tasks WHERE projectID = ?", projectID).Scan(&last); err != nil {
+		return "", fmt.Errorf("failed to get board of project %d: %w", projectID, err)
+	}
+	r, err := rank.Between(last.String, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to rank task in project %d: %w", projectID, err)
+	}
+	return r, nil
+}
+
+// lockProject locks the project row until the transaction ends and returns
+// the WIP limits of its board.
+func lockProject(tx *sql.Tx, projectID int64) (map[string]int, error) {
+	var wipLimits []byte
+	err := tx.QueryRow("SELECT wipLimits FROM projects WHERE id = ? FOR UPDATE", projectID).Scan(&wipLimits)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project %d: %w", projectID, err)
+	}
+
+	var limits map[string]int
+	if wipLimits != nil {
+		if err := json.Unmarshal(wipLimits, &limits); err != nil {
+			return nil, fmt.Errorf("failed to decode WIP limits of project %d: %w", projectID, err)
+		}
+	}
+	return limits, nil
+}
+
+// checkWIPLimit returns ErrWIPLimit when the column of the project board
+// for status has no room for n more tasks. The project row stays locked, so
+// two tasks cannot both take the last free place.
+func checkWIPLimit(tx *sql.Tx, projectID int64, status string, n int) error {
+	limits, err := lockProject(tx, projectID)
+	if err != nil {
+		return err
+	}
+	limit := limits[status]
+	if limit <= 0 {
+		return nil
+	}
+
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM tasks
+		WHERE projectID = ? AND status = ? AND deletedAt IS NULL AND archivedAt IS NULL`, projectID, status).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count tasks of project %d: %w", projectID, err)
+	}
+	if count+n > limit {
+		return ErrWIPLimit
+	}
+	return nil
+}
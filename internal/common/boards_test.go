@@ -0,0 +1,133 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func boardTask(status string) *Task {
+	projectID, workspaceID, number := int64(4), int64(2), int64(7)
+	return &Task{ID: 9, Name: "Fix login", Status: status, Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+		ProjectID: &projectID, Number: &number, Key: "API-7", CreatedAt: time.Now()}
+}
+
+func TestMoveOnBoardWithinColumn(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := boardTask("TODO")
+	afterID := int64(12)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(taskRows(task))
+	mock.ExpectQuery("SELECT wipLimits FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"wipLimits"}).AddRow(nil))
+	mock.ExpectQuery("SELECT boardRank FROM tasks WHERE id = \\? AND projectID = \\? AND status = \\?").
+		WithArgs(afterID, int64(4), "TODO").
+		WillReturnRows(sqlmock.NewRows([]string{"boardRank"}).AddRow("i00100"))
+	mock.ExpectQuery("SELECT MIN\\(boardRank\\) FROM tasks (.+) AND boardRank > \\?").
+		WithArgs(int64(4), "TODO", int64(9), "i00100").
+		WillReturnRows(sqlmock.NewRows([]string{"MIN(boardRank)"}).AddRow("i00200"))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\?, boardRank = \\? WHERE id = \\?").
+		WithArgs("TODO", nil, "i001i0", int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(9).
+		WillReturnRows(taskRows(task))
+
+	_, err := store.MoveOnBoard(9, BoardMove{ActorID: 3, AfterID: &afterID})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveOnBoardToTopOfAnotherColumn(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := boardTask("TODO")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(taskRows(task))
+	mock.ExpectQuery("SELECT wipLimits FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"wipLimits"}).AddRow([]byte(`{"IN_PROGRESS":3}`)))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE projectID = \\? AND status = \\?").
+		WithArgs(int64(4), "IN_PROGRESS").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT COUNT(.+) FROM task_dependencies").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"blockers"}).AddRow(0))
+	mock.ExpectQuery("SELECT MIN\\(boardRank\\) FROM tasks").
+		WithArgs(int64(4), "IN_PROGRESS", int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"MIN(boardRank)"}).AddRow("i00000"))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\?, boardRank = \\? WHERE id = \\?").
+		WithArgs("IN_PROGRESS", nil, "hzzz00", int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityStatusChanged, []byte(`{"from":"TODO","to":"IN_PROGRESS"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectWatchers(mock, 9)
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(9).
+		WillReturnRows(taskRows(boardTask("IN_PROGRESS")))
+
+	moved, err := store.MoveOnBoard(9, BoardMove{ActorID: 3, Status: "IN_PROGRESS"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IN_PROGRESS", moved.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveOnBoardFollowsWorkflow(t *testing.T) {
+	for _, tt := range []struct{ from, to string }{{"TODO", "DONE"}, {"DONE", "TODO"}, {"IN_TESTING", "IN_PROGRESS"}} {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer db.Close()
+
+			store := NewStore(db)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+				WithArgs(int64(9)).
+				WillReturnRows(taskRows(boardTask(tt.from)))
+			mock.ExpectRollback()
+
+			_, err := store.MoveOnBoard(9, BoardMove{ActorID: 3, Status: tt.to})
+			assert.ErrorIs(t, err, ErrInvalidTransition)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMoveOnBoardEnforcesWIPLimit(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(taskRows(boardTask("TODO")))
+	mock.ExpectQuery("SELECT wipLimits FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"wipLimits"}).AddRow([]byte(`{"IN_PROGRESS":2}`)))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE projectID = \\? AND status = \\?").
+		WithArgs(int64(4), "IN_PROGRESS").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	_, err := store.MoveOnBoard(9, BoardMove{ActorID: 3, Status: "IN_PROGRESS"})
+	assert.ErrorIs(t, err, ErrWIPLimit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
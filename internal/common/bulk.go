@@ -0,0 +1,132 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ApplyBulk applies the action to each of the tasks and returns one error
+// per task, nil for the tasks that were changed. With atomic set the whole
+// batch runs in one transaction that is rolled back at the first failure;
+// the failing task gets its own error and every other task gets
+// ErrBulkAborted. Otherwise each task is changed in a transaction of its
+// own and a failure only affects that task. The returned error is set
+// when the batch could not be run at all.
+func (s *Storage) ApplyBulk(action BulkAction, ids []int64, atomic bool) ([]error, error) {
+	results := make([]error, len(ids))
+	var trashed []int64
+
+	if atomic {
+		failed := -1
+		err := s.withTx(func(tx *sql.Tx) error {
+			trashed = trashed[:0]
+			for i, id := range ids {
+				removed, err := s.applyBulkAction(tx, action, id)
+				if err != nil {
+					failed = i
+					results[i] = err
+					return err
+				}
+				trashed = append(trashed, removed...)
+			}
+			return nil
+		})
+		if failed < 0 && err != nil {
+			return nil, err
+		}
+		if failed >= 0 {
+			for i := range results {
+				if i != failed {
+					results[i] = ErrBulkAborted
+				}
+			}
+			return results, nil
+		}
+	} else {
+		for i, id := range ids {
+			results[i] = s.withTx(func(tx *sql.Tx) error {
+				removed, err := s.applyBulkAction(tx, action, id)
+				if err != nil {
+					return err
+				}
+				trashed = append(trashed, removed...)
+				return nil
+			})
+		}
+	}
+
+	s.removeFromIndex(trashed)
+	return results, nil
+}
+
+// applyBulkAction changes one task of a batch within tx. It returns the
+// ids of the tasks it moved to the trash.
+func (s *Storage) applyBulkAction(tx *sql.Tx, action BulkAction, id int64) ([]int64, error) {
+	switch action.Action {
+	case BulkTransition:
+		_, err := s.advanceStatus(tx, id, StatusUpdate{ActorID: action.ActorID, Force: action.Force, To: action.Status})
+		return nil, err
+	case BulkReassign:
+		return nil, reassignTask(tx, id, action.UserID, action.ActorID)
+	case BulkAddLabel:
+		if err := lockTask(tx, id); err != nil {
+			return nil, err
+		}
+		return nil, addTaskLabel(tx, id, action.LabelID, action.ActorID)
+	case BulkDelete:
+		return trashTask(tx, id, action.ActorID)
+	case BulkMove:
+		return nil, moveTask(tx, id, action.WorkspaceID, action.ProjectID, action.StatusMap, action.ActorID)
+	}
+	return nil, fmt.Errorf("unknown bulk action %q", action.Action)
+}
+
+// reassignTask makes the user the only assignee of the task.
+func reassignTask(tx *sql.Tx, id, userID, actorID int64) error {
+	var primaryID int64
+	err := tx.QueryRow("SELECT assignedToID FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", id).Scan(&primaryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get task %d: %w", id, err)
+	}
+
+	rows, err := tx.Query("SELECT userID FROM task_assignees WHERE taskID = ? ORDER BY userID", id)
+	if err != nil {
+		return fmt.Errorf("failed to get assignees of task %d: %w", id, err)
+	}
+	assignees := []int64{}
+	for rows.Next() {
+		var assigneeID int64
+		if err := rows.Scan(&assigneeID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan assignee row: %w", err)
+		}
+		assignees = append(assignees, assigneeID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+	if primaryID == userID && len(assignees) == 1 && assignees[0] == userID {
+		return nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM task_assignees WHERE taskID = ? AND userID != ?", id, userID); err != nil {
+		return fmt.Errorf("failed to unassign task %d: %w", id, err)
+	}
+	if _, err := tx.Exec("INSERT IGNORE INTO task_assignees (taskID, userID) VALUES (?, ?)", id, userID); err != nil {
+		return fmt.Errorf("failed to assign task %d: %w", id, err)
+	}
+	if _, err := tx.Exec("UPDATE tasks SET assignedToID = ? WHERE id = ?", userID, id); err != nil {
+		return fmt.Errorf("failed to update primary assignee of task %d: %w", id, err)
+	}
+
+	changes := map[string]any{
+		"assigned_to_id": map[string]any{"from": primaryID, "to": userID},
+		"assignees":      map[string]any{"from": assignees, "to": []int64{userID}},
+	}
+	return recordActivity(tx, id, actorID, ActivityTaskEdited, map[string]any{"changes": changes})
+}
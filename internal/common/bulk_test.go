@@ -0,0 +1,122 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApplyBulkAtomicRollsBackOnFailure(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(1), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE tasks SET deletedAt = \\?, deletedByID = \\? WHERE id IN \\(\\?\\)").
+		WithArgs(sqlmock.AnyArg(), int64(3), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskDeleted, []byte(`{"task_ids":[1]}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	errs, err := store.ApplyBulk(BulkAction{Action: BulkDelete, ActorID: 3}, []int64{1, 2, 4}, true)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, errs[0], ErrBulkAborted)
+	assert.ErrorIs(t, errs[1], ErrNotFound)
+	assert.ErrorIs(t, errs[2], ErrBulkAborted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyBulkBestEffortKeepsSuccesses(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels \\(taskID, labelID\\) VALUES \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityLabelAdded, []byte(`{"label_id":7}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	errs, err := store.ApplyBulk(BulkAction{Action: BulkAddLabel, ActorID: 3, LabelID: 7}, []int64{1, 2}, false)
+	assert.NoError(t, err)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyBulkReassignReplacesAssignees(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT assignedToID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"assignedToID"}).AddRow(3))
+	mock.ExpectQuery("SELECT userID FROM task_assignees WHERE taskID = \\?").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"userID"}).AddRow(3).AddRow(4))
+	mock.ExpectExec("DELETE FROM task_assignees WHERE taskID = \\? AND userID != \\?").
+		WithArgs(int64(1), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT IGNORE INTO task_assignees").
+		WithArgs(int64(1), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE tasks SET assignedToID = \\? WHERE id = \\?").
+		WithArgs(int64(5), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskEdited,
+			[]byte(`{"changes":{"assigned_to_id":{"from":3,"to":5},"assignees":{"from":[3,4],"to":[5]}}}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	errs, err := store.ApplyBulk(BulkAction{Action: BulkReassign, ActorID: 3, UserID: 5}, []int64{1}, true)
+	assert.NoError(t, err)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyBulkMoveRejectsSubtask(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(1, 1, nil, nil))
+	mock.ExpectRollback()
+
+	errs, err := store.ApplyBulk(BulkAction{Action: BulkMove, ActorID: 3, WorkspaceID: 2}, []int64{2}, false)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, errs[0], ErrMoveSubtask)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
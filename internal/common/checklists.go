@@ -0,0 +1,173 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const checklistColumns = "id, taskID, text, done, position, createdAt"
+
+func scanChecklistItem(row rowScanner) (*ChecklistItem, error) {
+	var item ChecklistItem
+	err := row.Scan(&item.ID, &item.TaskID, &item.Text, &item.Done, &item.Position, &item.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetChecklist returns the checklist items of the task in their order.
+func (s *Storage) GetChecklist(taskID int) ([]*ChecklistItem, error) {
+	rows, err := s.db.Query("SELECT "+checklistColumns+" FROM checklist_items WHERE taskID = ? ORDER BY position, id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checklist of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	items := []*ChecklistItem{}
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan checklist item row: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return items, nil
+}
+
+func (s *Storage) GetChecklistItem(id int) (*ChecklistItem, error) {
+	item, err := scanChecklistItem(s.db.QueryRow("SELECT "+checklistColumns+" FROM checklist_items WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checklist item %d: %w", id, err)
+	}
+	return item, nil
+}
+
+// AddChecklistItem appends the item to the end of the task's checklist. The
+// task row is locked so concurrent adds get distinct positions.
+func (s *Storage) AddChecklistItem(item *ChecklistItem, actorID int) (*ChecklistItem, error) {
+	var id int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		if err := lockTask(tx, item.TaskID); err != nil {
+			return err
+		}
+
+		var position int
+		err := tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE taskID = ?", item.TaskID).
+			Scan(&position)
+		if err != nil {
+			return fmt.Errorf("failed to get checklist of task %d: %w", item.TaskID, err)
+		}
+
+		res, err := tx.Exec("INSERT INTO checklist_items (taskID, text, done, position) VALUES (?, ?, ?, ?)",
+			item.TaskID, item.Text, item.Done, position)
+		if err != nil {
+			return fmt.Errorf("failed to add checklist item to task %d: %w", item.TaskID, err)
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return recordActivity(tx, item.TaskID, int64(actorID), ActivityChecklistAdded, map[string]any{"item_id": id, "text": item.Text})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetChecklistItem(int(id))
+}
+
+// UpdateChecklistItem saves the text and state of the item.
+func (s *Storage) UpdateChecklistItem(item *ChecklistItem, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		current, err := scanChecklistItem(tx.QueryRow("SELECT "+checklistColumns+" FROM checklist_items WHERE id = ? FOR UPDATE", item.ID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get checklist item %d: %w", item.ID, err)
+		}
+
+		_, err = tx.Exec("UPDATE checklist_items SET text = ?, done = ? WHERE id = ?", item.Text, item.Done, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update checklist item %d: %w", item.ID, err)
+		}
+
+		changes := map[string]any{}
+		if current.Text != item.Text {
+			changes["text"] = map[string]any{"from": current.Text, "to": item.Text}
+		}
+		if current.Done != item.Done {
+			changes["done"] = map[string]any{"from": current.Done, "to": item.Done}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return recordActivity(tx, current.TaskID, int64(actorID), ActivityChecklistEdited,
+			map[string]any{"item_id": item.ID, "changes": changes})
+	})
+}
+
+// ReorderChecklist sets the order of the task's checklist. ids must list
+// every item exactly once.
+func (s *Storage) ReorderChecklist(taskID int, ids []int64, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id FROM checklist_items WHERE taskID = ? FOR UPDATE", taskID)
+		if err != nil {
+			return fmt.Errorf("failed to get checklist of task %d: %w", taskID, err)
+		}
+		current := make(map[int64]bool)
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan checklist item row: %w", err)
+			}
+			current[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over rows: %w", err)
+		}
+
+		if len(ids) != len(current) {
+			return ErrInvalidChecklistOrder
+		}
+		for _, id := range ids {
+			if !current[id] {
+				return ErrInvalidChecklistOrder
+			}
+			delete(current, id)
+		}
+
+		for i, id := range ids {
+			if _, err := tx.Exec("UPDATE checklist_items SET position = ? WHERE id = ?", i+1, id); err != nil {
+				return fmt.Errorf("failed to reorder checklist of task %d: %w", taskID, err)
+			}
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityChecklistReordered, map[string]any{"item_ids": ids})
+	})
+}
+
+func (s *Storage) DeleteChecklistItem(id, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		item, err := scanChecklistItem(tx.QueryRow("SELECT "+checklistColumns+" FROM checklist_items WHERE id = ? FOR UPDATE", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get checklist item %d: %w", id, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM checklist_items WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete checklist item %d: %w", id, err)
+		}
+		return recordActivity(tx, item.TaskID, int64(actorID), ActivityChecklistRemoved, map[string]any{"item_id": id, "text": item.Text})
+	})
+}
@@ -0,0 +1,124 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var checklistColumnNames = []string{"id", "taskID", "text", "done", "position", "createdAt"}
+
+func TestAddChecklistItemAppends(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(position\\), 0\\) \\+ 1 FROM checklist_items WHERE taskID = ?").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO checklist_items").
+		WithArgs(int64(1), "Update changelog", false, 3).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityChecklistAdded, []byte(`{"item_id":5,"text":"Update changelog"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM checklist_items WHERE id = ?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(checklistColumnNames).AddRow(5, 1, "Update changelog", false, 3, now))
+
+	item, err := store.AddChecklistItem(&ChecklistItem{TaskID: 1, Text: "Update changelog"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, item.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateChecklistItemRecordsChanges(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM checklist_items WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(checklistColumnNames).AddRow(5, 1, "Update changelog", false, 1, time.Now()))
+	mock.ExpectExec("UPDATE checklist_items SET text = \\?, done = \\? WHERE id = ?").
+		WithArgs("Update changelog", true, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityChecklistEdited, []byte(`{"changes":{"done":{"from":false,"to":true}},"item_id":5}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.UpdateChecklistItem(&ChecklistItem{ID: 5, TaskID: 1, Text: "Update changelog", Done: true}, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderChecklistRejectsMissingItem(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM checklist_items WHERE taskID = \\? FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.ReorderChecklist(1, []int64{6, 7}, 3), ErrInvalidChecklistOrder)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoneBlockedByOpenChecklist(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	workspaceID := int64(2)
+	task := &Task{ID: 1, Name: "Release", Status: "IN_TESTING", AssignedToID: 1, WorkspaceID: &workspaceID,
+		CreatedAt: time.Now(), Checklist: &Progress{Done: 3, Total: 5}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE parentID = \\? AND status != 'DONE'").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"open"}).AddRow(0))
+	mock.ExpectQuery("SELECT checklistBlocksDone FROM workspaces WHERE id = ?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"checklistBlocksDone"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err := store.UpdateTaskStatusByID(1, StatusUpdate{})
+	assert.ErrorIs(t, err, ErrOpenChecklist)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskChecklistProgress(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Release", Status: "TODO", AssignedToID: 1, CreatedAt: time.Now(),
+		Checklist: &Progress{Done: 3, Total: 5}}
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+
+	got, err := store.GetTask(1)
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Done: 3, Total: 5, Percent: 60}, got.Checklist)
+	assert.Nil(t, got.Progress)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,303 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CloneTask copies the task next to the original, under the same parent
+// and in the same project, and returns the copy. opts chooses whether its
+// subtasks, checklist, labels and attachments are copied too. Copies start
+// in TODO with an unticked checklist; comments, watchers, sprints and the
+// milestone stay with the original.
+//
+// Attachment contents are copied before the transaction, so no rows stay
+// locked during blob I/O. When the attachments of the copied tasks change
+// in the meantime, CloneTask returns ErrConflict.
+func (s *Storage) CloneTask(id int, opts CloneOptions) (*Task, error) {
+	var copies map[int64]string
+	if opts.CopyAttachment != nil {
+		attachments, err := s.subtreeAttachments(int64(id), opts.Subtasks)
+		if err != nil {
+			return nil, err
+		}
+		copies = make(map[int64]string, len(attachments))
+		for _, a := range attachments {
+			key, err := opts.CopyAttachment(a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy attachment %d: %w", a.ID, err)
+			}
+			copies[a.ID] = key
+		}
+	}
+
+	var clone *Task
+	var created []*Task
+	err := s.withTx(func(tx *sql.Tx) error {
+		created = created[:0]
+		source, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", id, err)
+		}
+
+		sources := map[*Task]int64{}
+		tree, err := cloneTree(tx, source, opts, sources)
+		if err != nil {
+			return err
+		}
+		if opts.Name != "" {
+			tree.Task.Name = opts.Name
+		}
+		// The copy has the height of the original under the same parent,
+		// so it fits in the hierarchy.
+		if source.ParentID != nil {
+			if err := lockTask(tx, *source.ParentID); err != nil {
+				return err
+			}
+			tree.Task.ParentID = source.ParentID
+			if tree.Task.Position, err = nextPosition(tx, *source.ParentID); err != nil {
+				return err
+			}
+		}
+
+		if err := insertTaskTree(tx, tree, &created); err != nil {
+			return err
+		}
+		if copies != nil {
+			used := 0
+			for _, task := range created {
+				n, err := copyAttachments(tx, sources[task], task.ID, opts.ActorID, copies)
+				if err != nil {
+					return err
+				}
+				used += n
+			}
+			if used != len(copies) {
+				return ErrConflict
+			}
+		}
+		err = recordActivity(tx, tree.Task.ID, opts.ActorID, ActivityTaskCloned, map[string]any{"source_id": id})
+		if err != nil {
+			return err
+		}
+		// Reading the copy before the commit means an error always leaves
+		// nothing behind, so callers can discard the attachments they copied.
+		clone, err = scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ?", tree.Task.ID))
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", tree.Task.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexTasks(created)
+	return clone, nil
+}
+
+// cloneTree builds the tree of copies of source and its subtasks, and
+// records in sources which task each copy was made from.
+func cloneTree(tx *sql.Tx, source *Task, opts CloneOptions, sources map[*Task]int64) (*TaskTree, error) {
+	task := &Task{
+		Name:         source.Name,
+		Description:  source.Description,
+		Status:       "TODO",
+		Priority:     source.Priority,
+		DueAt:        source.DueAt,
+		AssignedToID: source.AssignedToID,
+		AssigneeIDs:  source.AssigneeIDs,
+		WorkspaceID:  source.WorkspaceID,
+		ProjectID:    source.ProjectID,
+		Points:       source.Points,
+		CustomFields: source.CustomFields,
+	}
+	sources[task] = source.ID
+	tree := &TaskTree{Task: task}
+
+	if opts.Labels {
+		for _, label := range source.Labels {
+			tree.LabelIDs = append(tree.LabelIDs, label.ID)
+		}
+	}
+	if opts.Checklist && source.Checklist != nil {
+		rows, err := tx.Query("SELECT text FROM checklist_items WHERE taskID = ? ORDER BY position, id", source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get checklist of task %d: %w", source.ID, err)
+		}
+		for rows.Next() {
+			var text string
+			if err := rows.Scan(&text); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan checklist item row: %w", err)
+			}
+			tree.Checklist = append(tree.Checklist, text)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating over rows: %w", err)
+		}
+	}
+	if opts.Subtasks && source.Progress != nil {
+		rows, err := tx.Query("SELECT "+taskColumns+" FROM tasks t WHERE t.parentID = ? AND t.deletedAt IS NULL ORDER BY t.position, t.id", source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subtasks of task %d: %w", source.ID, err)
+		}
+		subtasks, err := scanTasks(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subtasks {
+			subtree, err := cloneTree(tx, sub, opts, sources)
+			if err != nil {
+				return nil, err
+			}
+			tree.Subtasks = append(tree.Subtasks, subtree)
+		}
+	}
+	return tree, nil
+}
+
+// subtreeAttachments returns the attachments of the task and, with
+// subtasks, of its live subtasks.
+func (s *Storage) subtreeAttachments(id int64, subtasks bool) ([]*Attachment, error) {
+	if !subtasks {
+		return taskAttachments(s.db, id)
+	}
+
+	rows, err := s.db.Query(`
+		WITH RECURSIVE subtree (id) AS (
+			SELECT id FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id FROM tasks t JOIN subtree st ON t.parentID = st.id WHERE t.deletedAt IS NULL
+		)
+		SELECT `+attachmentColumns+` FROM attachments WHERE taskID IN (SELECT id FROM subtree) ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments of task %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return attachments, nil
+}
+
+// copyAttachments gives the task toID the copies of the attachments of the
+// task fromID and returns how many it added. The copies are the actor's
+// uploads. An attachment without a copy was added after the contents were
+// copied and fails the clone with ErrConflict.
+func copyAttachments(tx *sql.Tx, fromID, toID, actorID int64, copies map[int64]string) (int, error) {
+	attachments, err := taskAttachments(tx, fromID)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range attachments {
+		key, ok := copies[a.ID]
+		if !ok {
+			return 0, ErrConflict
+		}
+		_, err = tx.Exec(`INSERT INTO attachments (taskID, uploaderID, filename, contentType, size, storageKey)
+			VALUES (?, ?, ?, ?, ?, ?)`, toID, actorID, a.Filename, a.ContentType, a.Size, key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to copy attachment %d: %w", a.ID, err)
+		}
+	}
+	return len(attachments), nil
+}
+
+// MergeTask closes the task as a duplicate of targetID and returns the
+// target. The task's comments move to the target and its watchers start
+// watching the target instead; the task becomes DONE and links to the
+// target through DuplicateOfID.
+func (s *Storage) MergeTask(id, targetID, actorID int) (*Task, error) {
+	if id == targetID {
+		return nil, ErrInvalidMerge
+	}
+	err := s.withTx(func(tx *sql.Tx) error {
+		// Both tasks are locked in id order, so concurrent merges of the
+		// same pair cannot deadlock.
+		tasks := map[int64]*Task{}
+		for _, taskID := range []int{min(id, targetID), max(id, targetID)} {
+			task, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL FOR UPDATE", taskID))
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get task %d: %w", taskID, err)
+			}
+			tasks[int64(taskID)] = task
+		}
+		source, target := tasks[int64(id)], tasks[int64(targetID)]
+		if source.DuplicateOfID != nil || target.DuplicateOfID != nil {
+			return ErrTaskMerged
+		}
+		if source.Progress != nil && source.Progress.Done < source.Progress.Total {
+			return ErrOpenSubtasks
+		}
+
+		res, err := tx.Exec("UPDATE comments SET taskID = ? WHERE taskID = ?", targetID, id)
+		if err != nil {
+			return fmt.Errorf("failed to move comments of task %d: %w", id, err)
+		}
+		comments, _ := res.RowsAffected()
+		_, err = tx.Exec("UPDATE mentions SET taskID = ? WHERE taskID = ? AND source = ?", targetID, id, MentionSourceComment)
+		if err != nil {
+			return fmt.Errorf("failed to move mentions of task %d: %w", id, err)
+		}
+
+		res, err = tx.Exec("INSERT IGNORE INTO task_watchers (taskID, userID) SELECT ?, userID FROM task_watchers WHERE taskID = ?", targetID, id)
+		if err != nil {
+			return fmt.Errorf("failed to move watchers of task %d: %w", id, err)
+		}
+		watchers, _ := res.RowsAffected()
+		if _, err := tx.Exec("DELETE FROM task_watchers WHERE taskID = ?", id); err != nil {
+			return fmt.Errorf("failed to move watchers of task %d: %w", id, err)
+		}
+
+		doneAt := source.DoneAt
+		if source.Status != "DONE" {
+			now := time.Now()
+			doneAt = &now
+		}
+		_, err = tx.Exec("UPDATE tasks SET status = 'DONE', doneAt = ?, duplicateOfID = ? WHERE id = ?", doneAt, targetID, id)
+		if err != nil {
+			return fmt.Errorf("failed to close task %d: %w", id, err)
+		}
+		if source.Status != "DONE" {
+			err := recordActivity(tx, source.ID, int64(actorID), ActivityStatusChanged, map[string]any{"from": source.Status, "to": "DONE"})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = recordActivity(tx, source.ID, int64(actorID), ActivityTaskMerged,
+			map[string]any{"into_task_id": targetID, "comments": comments})
+		if err != nil {
+			return err
+		}
+		return recordActivity(tx, target.ID, int64(actorID), ActivityDuplicateMerged,
+			map[string]any{"task_id": id, "comments": comments, "watchers": watchers})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The comments now count towards the target's search document.
+	s.reindexTasks(int64(id), int64(targetID))
+	return s.GetTask(targetID)
+}
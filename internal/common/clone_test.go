@@ -0,0 +1,221 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCloneTaskWithSubtasksChecklistLabelsAndAttachments(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	workspaceID, parentID := int64(2), int64(1)
+	source := &Task{ID: 1, Name: "Release 1.4", Status: "DONE", Priority: "P1", AssignedToID: 1, AssigneeIDs: []int64{1},
+		WorkspaceID: &workspaceID, Labels: []Label{{ID: 6, Name: "release"}}, Progress: &Progress{Done: 1, Total: 1, Percent: 100},
+		Checklist: &Progress{Done: 1, Total: 2, Percent: 50}, CreatedAt: time.Now()}
+	subtask := &Task{ID: 2, Name: "Changelog", Status: "DONE", Priority: "P2", AssignedToID: 1, AssigneeIDs: []int64{1},
+		WorkspaceID: &workspaceID, ParentID: &parentID, Position: 1, CreatedAt: time.Now()}
+	attachmentColumnNames := []string{"id", "taskID", "uploaderID", "filename", "contentType", "size", "storageKey", "createdAt"}
+
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) FROM attachments WHERE taskID IN \\(SELECT id FROM subtree\\) ORDER BY id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames).
+			AddRow(4, 1, 5, "plan.pdf", "application/pdf", 2048, "tasks/1/abc", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(1).
+		WillReturnRows(taskRows(source))
+	mock.ExpectQuery("SELECT text FROM checklist_items WHERE taskID = \\? ORDER BY position, id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"text"}).AddRow("Tag 1.4").AddRow("Announce"))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.parentID = \\? AND t.deletedAt IS NULL ORDER BY t.position, t.id").
+		WithArgs(int64(1)).
+		WillReturnRows(taskRows(subtask))
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Release 1.4", "", "TODO", "P1", nil, int64(1), int64(2), nil, 0, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(10), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels").
+		WithArgs(int64(10), int64(6), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for i, text := range []string{"Tag 1.4", "Announce"} {
+		mock.ExpectExec("INSERT INTO checklist_items").
+			WithArgs(int64(10), text, false, i+1).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Changelog", "", "TODO", "P2", nil, int64(1), int64(2), int64(10), 1, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(11), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT (.+) FROM attachments WHERE taskID = \\? ORDER BY id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames).
+			AddRow(4, 1, 5, "plan.pdf", "application/pdf", 2048, "tasks/1/abc", time.Now()))
+	mock.ExpectExec("INSERT INTO attachments").
+		WithArgs(int64(10), int64(3), "plan.pdf", "application/pdf", int64(2048), "tasks/1/copy").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectQuery("SELECT (.+) FROM attachments WHERE taskID = \\? ORDER BY id").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(10), int64(3), ActivityTaskCloned, []byte(`{"source_id":1}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\?").
+		WithArgs(int64(10)).
+		WillReturnRows(taskRows(&Task{ID: 10, Name: "Release 1.4", Status: "TODO", Priority: "P1", AssignedToID: 1,
+			WorkspaceID: &workspaceID, Progress: &Progress{Total: 1}, Checklist: &Progress{Total: 2}, CreatedAt: time.Now()}))
+	mock.ExpectCommit()
+
+	var copied []string
+	task, err := store.CloneTask(1, CloneOptions{ActorID: 3, Subtasks: true, Checklist: true, Labels: true,
+		CopyAttachment: func(a *Attachment) (string, error) {
+			copied = append(copied, a.StorageKey)
+			return "tasks/1/copy", nil
+		}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), task.ID)
+	assert.Equal(t, []string{"tasks/1/abc"}, copied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloneTaskConflictsWithDeletedAttachment(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	source := &Task{ID: 1, Name: "Release 1.4", Status: "TODO", Priority: "P1", AssignedToID: 1, AssigneeIDs: []int64{1},
+		CreatedAt: time.Now()}
+	attachmentColumnNames := []string{"id", "taskID", "uploaderID", "filename", "contentType", "size", "storageKey", "createdAt"}
+
+	mock.ExpectQuery("SELECT (.+) FROM attachments WHERE taskID = \\? ORDER BY id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames).
+			AddRow(4, 1, 5, "plan.pdf", "application/pdf", 2048, "tasks/1/abc", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(1).
+		WillReturnRows(taskRows(source))
+	mock.ExpectExec("INSERT INTO tasks").
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(10), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The attachment was deleted after its content was copied.
+	mock.ExpectQuery("SELECT (.+) FROM attachments WHERE taskID = \\? ORDER BY id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames))
+	mock.ExpectRollback()
+
+	_, err := store.CloneTask(1, CloneOptions{ActorID: 3, CopyAttachment: func(a *Attachment) (string, error) {
+		return "tasks/1/copy", nil
+	}})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeTaskMovesCommentsAndWatchers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	target := &Task{ID: 5, Name: "Login fails on Safari", Status: "TODO", Priority: "P1", AssignedToID: 1, CreatedAt: time.Now()}
+	source := &Task{ID: 8, Name: "Cannot log in", Status: "IN_PROGRESS", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+	targetID := int64(5)
+	closed := *source
+	closed.Status, closed.DuplicateOfID = "DONE", &targetID
+
+	mock.ExpectBegin()
+	for _, task := range []*Task{target, source} {
+		mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+			WithArgs(int(task.ID)).
+			WillReturnRows(taskRows(task))
+	}
+	mock.ExpectExec("UPDATE comments SET taskID = \\? WHERE taskID = \\?").
+		WithArgs(5, 8).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE mentions SET taskID = \\? WHERE taskID = \\? AND source = \\?").
+		WithArgs(5, 8, MentionSourceComment).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT IGNORE INTO task_watchers \\(taskID, userID\\) SELECT \\?, userID FROM task_watchers WHERE taskID = \\?").
+		WithArgs(5, 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_watchers WHERE taskID = \\?").
+		WithArgs(8).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE tasks SET status = 'DONE', doneAt = \\?, duplicateOfID = \\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), 5, 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(8), int64(3), ActivityStatusChanged, []byte(`{"from":"IN_PROGRESS","to":"DONE"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(8), int64(3), ActivityTaskMerged, []byte(`{"comments":2,"into_task_id":5}`)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(5), int64(3), ActivityDuplicateMerged, []byte(`{"comments":2,"task_id":8,"watchers":1}`)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, &closed)
+	expectReindex(mock, target, "Same on Firefox", "Fixed by clearing cookies")
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(5).
+		WillReturnRows(taskRows(target))
+
+	task, err := store.MergeTask(8, 5, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), task.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeTaskWithOpenSubtasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	target := &Task{ID: 5, Name: "Login fails on Safari", Status: "TODO", Priority: "P1", AssignedToID: 1, CreatedAt: time.Now()}
+	source := &Task{ID: 8, Name: "Cannot log in", Status: "TODO", Priority: "P2", AssignedToID: 1,
+		Progress: &Progress{Done: 1, Total: 2, Percent: 50}, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	for _, task := range []*Task{target, source} {
+		mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+			WithArgs(int(task.ID)).
+			WillReturnRows(taskRows(task))
+	}
+	mock.ExpectRollback()
+
+	_, err := store.MergeTask(8, 5, 3)
+	assert.ErrorIs(t, err, ErrOpenSubtasks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeTaskAlreadyMerged(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	otherID := int64(2)
+	target := &Task{ID: 5, Name: "Login fails on Safari", Status: "TODO", Priority: "P1", AssignedToID: 1, CreatedAt: time.Now()}
+	source := &Task{ID: 8, Name: "Cannot log in", Status: "DONE", Priority: "P2", AssignedToID: 1,
+		DuplicateOfID: &otherID, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	for _, task := range []*Task{target, source} {
+		mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+			WithArgs(int(task.ID)).
+			WillReturnRows(taskRows(task))
+	}
+	mock.ExpectRollback()
+
+	_, err := store.MergeTask(8, 5, 3)
+	assert.ErrorIs(t, err, ErrTaskMerged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
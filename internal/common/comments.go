@@ -0,0 +1,248 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const commentColumns = "c.id, c.taskID, c.authorID, c.parentID, c.body, c.createdAt, c.editedAt, c.deletedAt"
+
+func scanComment(row rowScanner) (*Comment, error) {
+	var c Comment
+	var parentID sql.NullInt64
+	var editedAt, deletedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.TaskID, &c.AuthorID, &parentID, &c.Body, &c.CreatedAt, &editedAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		c.ParentID = &parentID.Int64
+	}
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+	c.Deleted = deletedAt.Valid
+	return &c, nil
+}
+
+func scanComments(rows *sql.Rows) ([]*Comment, error) {
+	comments := []*Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return comments, nil
+}
+
+// CreateComment stores the comment and records it in the task's activity
+// feed. A reply to a reply is attached to the top-level comment of the
+// thread. ErrNotFound means the parent comment is not on the same task.
+func (s *Storage) CreateComment(c *Comment) (*Comment, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		if c.ParentID != nil {
+			var taskID int64
+			var rootID sql.NullInt64
+			err := tx.QueryRow("SELECT taskID, parentID FROM comments WHERE id = ?", *c.ParentID).Scan(&taskID, &rootID)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && taskID != c.TaskID) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get parent comment: %w", err)
+			}
+			if rootID.Valid {
+				c.ParentID = &rootID.Int64
+			}
+		}
+
+		res, err := tx.Exec("INSERT INTO comments (taskID, authorID, parentID, body) VALUES (?, ?, ?, ?)",
+			c.TaskID, c.AuthorID, c.ParentID, c.Body)
+		if err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		c.ID, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		c.CreatedAt = time.Now()
+
+		err = recordActivity(tx, c.TaskID, c.AuthorID, ActivityCommentCreated, map[string]any{"comment_id": c.ID})
+		if err != nil {
+			return err
+		}
+		err = recordMentions(tx, Mention{TaskID: c.TaskID, CommentID: &c.ID, AuthorID: &c.AuthorID}, c.Body)
+		if err != nil {
+			return err
+		}
+		return notifyWatchers(tx, c.TaskID, c.AuthorID, NotificationComment, map[string]any{"comment_id": c.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.reindexTasks(c.TaskID)
+	return c, nil
+}
+
+func (s *Storage) GetComment(id int) (*Comment, error) {
+	c, err := scanComment(s.db.QueryRow("SELECT "+commentColumns+" FROM comments c WHERE c.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetComments returns up to limit top-level comments with an id greater
+// than after, oldest first, each with all of its replies.
+func (s *Storage) GetComments(taskID, after, limit int) ([]*Comment, error) {
+	rows, err := s.db.Query("SELECT "+commentColumns+` FROM comments c
+		WHERE c.taskID = ? AND c.parentID IS NULL AND c.id > ? ORDER BY c.id LIMIT ?`, taskID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	comments, err := scanComments(rows)
+	if err != nil || len(comments) == 0 {
+		return comments, err
+	}
+
+	byID := make(map[int64]*Comment, len(comments))
+	args := make([]any, len(comments))
+	for i, c := range comments {
+		byID[c.ID] = c
+		args[i] = c.ID
+	}
+
+	replyRows, err := s.db.Query("SELECT "+commentColumns+" FROM comments c WHERE c.parentID IN ("+
+		placeholders(len(args))+") ORDER BY c.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replies: %w", err)
+	}
+	defer replyRows.Close()
+
+	replies, err := scanComments(replyRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		parent := byID[*reply.ParentID]
+		parent.Replies = append(parent.Replies, reply)
+	}
+	return comments, nil
+}
+
+// UpdateComment replaces the body and keeps the previous one as a revision.
+// Users newly mentioned by the edit are notified.
+func (s *Storage) UpdateComment(id int, body string) (*Comment, error) {
+	var taskID int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var authorID int64
+		var err error
+		taskID, authorID, err = reviseComment(tx, id, ActivityCommentEdited)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE comments SET body = ?, editedAt = CURRENT_TIMESTAMP WHERE id = ?", body, id)
+		if err != nil {
+			return fmt.Errorf("failed to update comment with id %d: %w", id, err)
+		}
+
+		commentID := int64(id)
+		return recordMentions(tx, Mention{TaskID: taskID, CommentID: &commentID, AuthorID: &authorID}, body)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.reindexTasks(taskID)
+	return s.GetComment(id)
+}
+
+// DeleteComment blanks the comment but keeps it in its thread. The last
+// body is kept as a revision like on any edit.
+func (s *Storage) DeleteComment(id int) error {
+	var taskID int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		taskID, _, err = reviseComment(tx, id, ActivityCommentDeleted)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE comments SET body = '', deletedAt = CURRENT_TIMESTAMP WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete comment with id %d: %w", id, err)
+		}
+
+		_, err = tx.Exec("DELETE FROM mentions WHERE source = ? AND sourceID = ?", MentionSourceComment, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete mentions of comment with id %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.reindexTasks(taskID)
+	return nil
+}
+
+// reviseComment locks the comment, saves its current body as a revision and
+// records the change in the activity feed. It returns the comment's task and
+// author. Deleted comments cannot change.
+func reviseComment(tx *sql.Tx, id int, action string) (int64, int64, error) {
+	var taskID, authorID int64
+	var body string
+	var deletedAt sql.NullTime
+	err := tx.QueryRow("SELECT taskID, authorID, body, deletedAt FROM comments WHERE id = ? FOR UPDATE", id).
+		Scan(&taskID, &authorID, &body, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && deletedAt.Valid) {
+		return 0, 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get comment with id %d: %w", id, err)
+	}
+
+	_, err = tx.Exec("INSERT INTO comment_revisions (commentID, body) VALUES (?, ?)", id, body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to save revision of comment with id %d: %w", id, err)
+	}
+
+	err = recordActivity(tx, taskID, authorID, action, map[string]any{"comment_id": id})
+	return taskID, authorID, err
+}
+
+func (s *Storage) GetCommentRevisions(commentID int) ([]*CommentRevision, error) {
+	rows, err := s.db.Query("SELECT id, commentID, body, replacedAt FROM comment_revisions WHERE commentID = ? ORDER BY id",
+		commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions of comment with id %d: %w", commentID, err)
+	}
+	defer rows.Close()
+
+	revisions := []*CommentRevision{}
+	for rows.Next() {
+		var r CommentRevision
+		if err := rows.Scan(&r.ID, &r.CommentID, &r.Body, &r.ReplacedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision row: %w", err)
+		}
+		revisions = append(revisions, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return revisions, nil
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var commentColumnNames = []string{"id", "taskID", "authorID", "parentID", "body", "createdAt", "editedAt", "deletedAt"}
+
+func expectReindex(mock sqlmock.Sqlmock, task *Task, comments ...string) {
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(int(task.ID)).
+		WillReturnRows(taskRows(task))
+	rows := sqlmock.NewRows([]string{"body"})
+	for _, c := range comments {
+		rows.AddRow(c)
+	}
+	mock.ExpectQuery("SELECT body FROM comments WHERE taskID = ?").
+		WithArgs(task.ID).
+		WillReturnRows(rows)
+}
+
+func TestCreateReplyAttachesToThreadRoot(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	index := search.NewMemoryIndex()
+	store := NewStoreWithIndex(db, index)
+
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+	parentID := int64(12)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT taskID, parentID FROM comments WHERE id = ?").
+		WithArgs(parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"taskID", "parentID"}).AddRow(1, 10))
+	mock.ExpectExec("INSERT INTO comments").
+		WithArgs(int64(1), int64(2), int64(10), "I can reproduce the **timeout**").
+		WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(2), ActivityCommentCreated, []byte(`{"comment_id":13}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectWatchers(mock, 1, 2, 5)
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(5), NotificationComment, int64(1), int64(2), []byte(`{"comment_id":13}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, task, "First", "I can reproduce the **timeout**")
+
+	comment, err := store.CreateComment(&Comment{TaskID: 1, AuthorID: 2, ParentID: &parentID, Body: "I can reproduce the **timeout**"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), *comment.ParentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	q, _ := search.ParseQuery("timeout")
+	hits, _ := index.Search(q, 10)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, "comments", hits[0].Highlights[0].Field)
+}
+
+func TestCreateReplyOnOtherTask(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	parentID := int64(12)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT taskID, parentID FROM comments WHERE id = ?").
+		WithArgs(parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"taskID", "parentID"}).AddRow(7, nil))
+	mock.ExpectRollback()
+
+	_, err := store.CreateComment(&Comment{TaskID: 1, AuthorID: 2, ParentID: &parentID, Body: "Hello"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCommentKeepsRevision(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT taskID, authorID, body, deletedAt FROM comments WHERE id = \\? FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"taskID", "authorID", "body", "deletedAt"}).AddRow(1, 2, "Old", nil))
+	mock.ExpectExec("INSERT INTO comment_revisions").
+		WithArgs(5, "Old").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(2), ActivityCommentEdited, []byte(`{"comment_id":5}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE comments SET body = \\?, editedAt").
+		WithArgs("New", 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, task, "New")
+	mock.ExpectQuery("SELECT (.+) FROM comments c WHERE c.id = ?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(commentColumnNames).AddRow(5, 1, 2, nil, "New", now, now, nil))
+
+	comment, err := store.UpdateComment(5, "New")
+	assert.NoError(t, err)
+	assert.Equal(t, "New", comment.Body)
+	assert.NotNil(t, comment.EditedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCommentsNestsReplies(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM comments c WHERE c.taskID = \\? AND c.parentID IS NULL AND c.id > \\?").
+		WithArgs(1, 0, 2).
+		WillReturnRows(sqlmock.NewRows(commentColumnNames).
+			AddRow(1, 1, 2, nil, "First", now, nil, nil).
+			AddRow(3, 1, 2, nil, "", now, nil, now))
+	mock.ExpectQuery("SELECT (.+) FROM comments c WHERE c.parentID IN").
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows(commentColumnNames).
+			AddRow(2, 1, 4, 1, "Reply", now, nil, nil))
+
+	comments, err := store.GetComments(1, 0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Len(t, comments[0].Replies, 1)
+	assert.Equal(t, "Reply", comments[0].Replies[0].Body)
+	assert.True(t, comments[1].Deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
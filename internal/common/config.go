@@ -1,8 +1,13 @@
 package common
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +17,43 @@ type Config struct {
 	DBAddress  string
 	DBName     string
 	JWTSecret  string
+
+	// MaxTaskDepth is the number of levels a task hierarchy can have; a
+	// task without a parent is on level 1.
+	MaxTaskDepth int
+
+	// RecurrenceInterval is how often the scheduler looks for recurring
+	// tasks that need their next occurrence.
+	RecurrenceInterval time.Duration
+
+	// JobInterval is how often the job scheduler plans reminders and runs
+	// due jobs. EscalationDelay is how long a task stays overdue before its
+	// assignees' team leads are told.
+	JobInterval     time.Duration
+	EscalationDelay time.Duration
+
+	// TrashRetention is how long deleted tasks stay in the trash before
+	// they are purged for good. TrashPurgeInterval is how often the purge
+	// runs.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// ArchiveInterval is how often DONE tasks are checked against their
+	// workspace's auto-archive setting.
+	ArchiveInterval time.Duration
+
+	// Attachments
+	BlobBackend         string
+	BlobDir             string
+	S3Endpoint          string
+	S3Bucket            string
+	S3Region            string
+	S3AccessKey         string
+	S3SecretKey         string
+	AttachmentMaxBytes  int64
+	AttachmentTypes     []string
+	AttachmentURLSecret string
+	AttachmentURLTTL    time.Duration
 }
 
 var Envs = initConfig()
@@ -24,7 +66,41 @@ func initConfig() Config {
 		DBAddress:  fmt.Sprintf("%s:%s", getEnv("DB_ADDRESS", "localhost"), getEnv("DB_PORT", "3306")),
 		DBName:     getEnv("DB_NAME", "projectmanager"),
 		JWTSecret:  getEnv("JWT_SECRET", "secret"),
+
+		MaxTaskDepth:       int(getEnvInt64("MAX_TASK_DEPTH", 5)),
+		RecurrenceInterval: getEnvDuration("RECURRENCE_INTERVAL", time.Minute),
+		JobInterval:        getEnvDuration("JOB_INTERVAL", 30*time.Second),
+		EscalationDelay:    getEnvDuration("ESCALATION_DELAY", 24*time.Hour),
+		TrashRetention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		ArchiveInterval:    getEnvDuration("ARCHIVE_INTERVAL", time.Hour),
+
+		BlobBackend:         getEnv("BLOB_BACKEND", "fs"),
+		BlobDir:             getEnv("BLOB_DIR", "data/blobs"),
+		S3Endpoint:          getEnv("S3_ENDPOINT", ""),
+		S3Bucket:            getEnv("S3_BUCKET", ""),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey:         getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:         getEnv("S3_SECRET_KEY", ""),
+		AttachmentMaxBytes:  getEnvInt64("ATTACHMENT_MAX_BYTES", 10<<20),
+		AttachmentTypes:     strings.Split(getEnv("ATTACHMENT_TYPES", "image/png,image/jpeg,image/gif,image/webp,text/plain,application/pdf,application/zip,application/x-gzip"), ","),
+		AttachmentURLSecret: attachmentURLSecret(),
+		AttachmentURLTTL:    getEnvDuration("ATTACHMENT_URL_TTL", 15*time.Minute),
+	}
+}
+
+// attachmentURLSecret returns ATTACHMENT_URL_SECRET or, when it is not set,
+// a random secret. Links signed with a random secret stop working on
+// restart and on other servers.
+func attachmentURLSecret() string {
+	if secret := os.Getenv("ATTACHMENT_URL_SECRET"); secret != "" {
+		return secret
 	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate a secret: %v", err))
+	}
+	return hex.EncodeToString(b)
 }
 
 func getEnv(key, fallback string) string {
@@ -33,3 +109,21 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt64(key string, fallback int64) int64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
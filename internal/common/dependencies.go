@@ -0,0 +1,197 @@
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// AddTaskDependency records that blockedID cannot start before blockerID
+// is done. Links that would close a cycle return ErrDependencyCycle and
+// existing links return ErrConflict. The link shows in the activity of the
+// blocked task.
+func (s *Storage) AddTaskDependency(blockerID, blockedID, actorID int) error {
+	if blockerID == blockedID {
+		return ErrDependencyCycle
+	}
+
+	return s.withTx(func(tx *sql.Tx) error {
+		// Lock both tasks in a fixed order, so two links between the same
+		// tasks cannot pass the cycle check at the same time.
+		first, second := min(blockerID, blockedID), max(blockerID, blockedID)
+		if err := lockTask(tx, int64(first)); err != nil {
+			return err
+		}
+		if err := lockTask(tx, int64(second)); err != nil {
+			return err
+		}
+
+		cycle, err := dependsOn(tx, int64(blockedID), int64(blockerID))
+		if err != nil {
+			return fmt.Errorf("failed to check dependencies of task %d: %w", blockedID, err)
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err = tx.Exec("INSERT INTO task_dependencies (blockerID, blockedID) VALUES (?, ?)", blockerID, blockedID)
+		if isDuplicateKey(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add dependency: %w", err)
+		}
+		return recordActivity(tx, int64(blockedID), int64(actorID), ActivityDependencyAdded, map[string]any{"blocker_id": blockerID})
+	})
+}
+
+// dependsOn reports whether the task blockerID is reachable from taskID
+// through the tasks that taskID blocks, directly or not; linking blockerID
+// before taskID would then close a cycle. The edges are read level by level
+// with shared locks, which also cover the gaps where new edges of the
+// traversed tasks would go. A concurrent link on the traversed path waits
+// for this transaction instead of closing a cycle neither of them sees; when
+// two links wait on each other, InnoDB rolls one of them back as a deadlock.
+func dependsOn(tx *sql.Tx, taskID, blockerID int64) (bool, error) {
+	seen := map[int64]bool{taskID: true}
+	level := []int64{taskID}
+	for len(level) > 0 {
+		rows, err := tx.Query("SELECT blockedID FROM task_dependencies WHERE blockerID IN ("+placeholders(len(level))+") FOR SHARE", int64Args(level)...)
+		if err != nil {
+			return false, err
+		}
+		var next []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return false, fmt.Errorf("failed to scan dependency row: %w", err)
+			}
+			if id == blockerID {
+				rows.Close()
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				next = append(next, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, fmt.Errorf("error iterating over rows: %w", err)
+		}
+		level = next
+	}
+	return false, nil
+}
+
+func (s *Storage) RemoveTaskDependency(blockerID, blockedID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM task_dependencies WHERE blockerID = ? AND blockedID = ?", blockerID, blockedID)
+		if err != nil {
+			return fmt.Errorf("failed to remove dependency: %w", err)
+		}
+		if err := requireAffected(res); err != nil {
+			return err
+		}
+		return recordActivity(tx, int64(blockedID), int64(actorID), ActivityDependencyRemoved, map[string]any{"blocker_id": blockerID})
+	})
+}
+
+// GetTaskDependencies returns the tasks blocking the task, transitively,
+// and the tasks it directly blocks.
+func (s *Storage) GetTaskDependencies(taskID int) (*DependencyGraph, error) {
+	rows, err := s.db.Query(`
+		WITH RECURSIVE upstream (id) AS (
+			SELECT CAST(? AS UNSIGNED)
+			UNION
+			SELECT d.blockerID FROM task_dependencies d JOIN upstream u ON d.blockedID = u.id
+		)
+		SELECT d.blockerID, d.blockedID FROM task_dependencies d JOIN upstream u ON d.blockedID = u.id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	edges := []Dependency{}
+	for rows.Next() {
+		var d Dependency
+		if err := rows.Scan(&d.BlockerID, &d.BlockedID); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency row: %w", err)
+		}
+		edges = append(edges, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	graph := &DependencyGraph{TaskID: int64(taskID), Blockers: []*Task{}, Edges: edges}
+
+	order := topoOrder(edges, int64(taskID))
+	if len(order) > 0 {
+		tasks, err := s.getTasksByIDs(order)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range order {
+			if task, ok := tasks[id]; ok {
+				graph.Blockers = append(graph.Blockers, task)
+			}
+		}
+	}
+
+	blocking, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		JOIN task_dependencies d ON d.blockedID = t.id WHERE d.blockerID = ? AND t.deletedAt IS NULL ORDER BY t.id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks blocked by task %d: %w", taskID, err)
+	}
+	defer blocking.Close()
+
+	graph.Blocking, err = scanTasks(blocking)
+	if err != nil {
+		return nil, err
+	}
+	if graph.Blocking == nil {
+		graph.Blocking = []*Task{}
+	}
+	return graph, nil
+}
+
+// topoOrder sorts the tasks of the dependency graph, except root, so that
+// every task comes after its blockers. Ties are broken by id to keep the
+// order stable.
+func topoOrder(edges []Dependency, root int64) []int64 {
+	pending := make(map[int64]int)
+	next := make(map[int64][]int64)
+	for _, e := range edges {
+		pending[e.BlockedID]++
+		if _, ok := pending[e.BlockerID]; !ok {
+			pending[e.BlockerID] = 0
+		}
+		next[e.BlockerID] = append(next[e.BlockerID], e.BlockedID)
+	}
+
+	var ready []int64
+	for id, n := range pending {
+		if n == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	var order []int64
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return ready[i] < ready[j] })
+		id := ready[0]
+		ready = ready[1:]
+		if id != root {
+			order = append(order, id)
+		}
+		for _, blocked := range next[id] {
+			pending[blocked]--
+			if pending[blocked] == 0 {
+				ready = append(ready, blocked)
+			}
+		}
+	}
+	return order
+}
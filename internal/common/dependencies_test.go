@@ -0,0 +1,151 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTopoOrder(t *testing.T) {
+	// 1 and 2 block 3, 3 and 4 block 5, 5 blocks the root 9.
+	edges := []Dependency{
+		{BlockerID: 5, BlockedID: 9},
+		{BlockerID: 3, BlockedID: 5},
+		{BlockerID: 4, BlockedID: 5},
+		{BlockerID: 2, BlockedID: 3},
+		{BlockerID: 1, BlockedID: 3},
+	}
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, topoOrder(edges, 9))
+	assert.Nil(t, topoOrder(nil, 9))
+}
+
+func TestAddTaskDependencyRejectsCycle(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	// 2 blocks 4, which blocks 5.
+	mock.ExpectQuery("SELECT blockedID FROM task_dependencies WHERE blockerID IN \\(\\?\\) FOR SHARE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"blockedID"}).AddRow(4))
+	mock.ExpectQuery("SELECT blockedID FROM task_dependencies WHERE blockerID IN \\(\\?\\) FOR SHARE").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"blockedID"}).AddRow(5))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.AddTaskDependency(5, 2, 3), ErrDependencyCycle)
+	assert.ErrorIs(t, store.AddTaskDependency(3, 3, 3), ErrDependencyCycle)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTaskDependencyLocksTraversedEdges(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	// 2 blocks 3 and 4, and 3 blocks 4; nothing leads back to 1.
+	mock.ExpectQuery("SELECT blockedID FROM task_dependencies WHERE blockerID IN \\(\\?\\) FOR SHARE").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"blockedID"}).AddRow(3).AddRow(4))
+	mock.ExpectQuery("SELECT blockedID FROM task_dependencies WHERE blockerID IN \\(\\?, \\?\\) FOR SHARE").
+		WithArgs(int64(3), int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"blockedID"}).AddRow(4))
+	mock.ExpectExec("INSERT INTO task_dependencies \\(blockerID, blockedID\\) VALUES \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(2), int64(3), ActivityDependencyAdded, []byte(`{"blocker_id":1}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.AddTaskDependency(1, 2, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskDependencies(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+
+	mock.ExpectQuery("WITH RECURSIVE upstream").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"blockerID", "blockedID"}).
+			AddRow(3, 9).
+			AddRow(2, 3))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN").
+		WithArgs(int64(2), int64(3)).
+		WillReturnRows(taskRows(
+			&Task{ID: 3, Name: "Build", Status: "TODO", CreatedAt: now},
+			&Task{ID: 2, Name: "Design", Status: "DONE", CreatedAt: now}))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+JOIN task_dependencies d ON d.blockedID = t.id WHERE d.blockerID = ?").
+		WithArgs(9).
+		WillReturnRows(taskRows())
+
+	graph, err := store.GetTaskDependencies(9)
+	assert.NoError(t, err)
+	assert.Len(t, graph.Blockers, 2)
+	assert.Equal(t, "Design", graph.Blockers[0].Name)
+	assert.Equal(t, "Build", graph.Blockers[1].Name)
+	assert.Len(t, graph.Edges, 2)
+	assert.Empty(t, graph.Blocking)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlockedTaskCannotStart(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(&Task{ID: 1, Name: "Deploy", Status: "TODO", CreatedAt: time.Now()}))
+	mock.ExpectQuery("SELECT COUNT(.+) FROM task_dependencies").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"blockers"}).AddRow(2))
+	mock.ExpectRollback()
+
+	_, err := store.UpdateTaskStatusByID(1, StatusUpdate{Force: true})
+	assert.ErrorIs(t, err, ErrTaskBlocked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveTaskDependencyRecordsActivity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM task_dependencies WHERE blockerID = \\? AND blockedID = ?").
+		WithArgs(5, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityDependencyRemoved, []byte(`{"blocker_id":5}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.RemoveTaskDependency(5, 1, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a write would break a uniqueness rule.
+var ErrConflict = errors.New("already exists")
+
+// ErrTaskCycle is returned when a task would become its own ancestor.
+var ErrTaskCycle = errors.New("task cannot be nested under itself or its subtasks")
+
+// ErrTaskTooDeep is returned when a task hierarchy would exceed Envs.MaxTaskDepth levels.
+var ErrTaskTooDeep = errors.New("task hierarchy is too deep")
+
+// ErrOpenSubtasks is returned when a task with open subtasks would become DONE.
+var ErrOpenSubtasks = errors.New("task has open subtasks")
+
+// ErrOpenChecklist is returned when a task with open checklist items would
+// become DONE in a workspace that requires a finished checklist.
+var ErrOpenChecklist = errors.New("task has open checklist items")
+
+// ErrInvalidOrder is returned when a new order does not list every subtask exactly once.
+var ErrInvalidOrder = errors.New("order must list every subtask exactly once")
+
+// ErrInvalidChecklistOrder is returned when a new order does not list every checklist item exactly once.
+var ErrInvalidChecklistOrder = errors.New("order must list every checklist item exactly once")
+
+// ErrTaskDone is returned when a DONE task would move to a next status.
+var ErrTaskDone = errors.New("task is already done")
+
+// ErrInvalidTransition is returned when a task's next status is not the
+// requested one.
+var ErrInvalidTransition = errors.New("task cannot move to this status")
+
+// ErrMoveSubtask is returned when a subtask would be moved to another
+// workspace on its own.
+var ErrMoveSubtask = errors.New("subtasks move together with their parent")
+
+// ErrWIPLimit is returned when a task would enter a board column that
+// already holds as many tasks as its WIP limit allows.
+var ErrWIPLimit = errors.New("column is at its WIP limit")
+
+// ErrNotOnBoard is returned when a task outside any project is moved on a
+// board.
+var ErrNotOnBoard = errors.New("task is not in a project")
+
+// ErrInvalidMove is returned when a board move names a task that is not in
+// the target column to place the task after.
+var ErrInvalidMove = errors.New("after_id must be another task in the target column")
+
+// ErrSprintState is returned when a sprint is started or completed, or its
+// tasks change, in a state that does not allow it.
+var ErrSprintState = errors.New("sprint cannot change in its current state")
+
+// ErrInActiveSprint is returned when a task would be in two active sprints
+// at once.
+var ErrInActiveSprint = errors.New("task is already in another active sprint")
+
+// ErrMilestoneClosed is returned when a closed milestone would be closed
+// again or get new tasks.
+var ErrMilestoneClosed = errors.New("milestone is closed")
+
+// ErrInvalidFieldValue is returned when a value does not fit the type or
+// options of its custom field.
+var ErrInvalidFieldValue = errors.New("invalid custom field value")
+
+// ErrUnknownField is returned when a task names a custom field its project
+// does not have.
+var ErrUnknownField = errors.New("unknown custom field")
+
+// ErrInvalidMerge is returned when a task would be merged into itself.
+var ErrInvalidMerge = errors.New("a task cannot be merged into itself")
+
+// ErrTaskMerged is returned when a task already closed as a duplicate would
+// be merged, or merged into.
+var ErrTaskMerged = errors.New("task is already closed as a duplicate")
+
+// ErrBulkAborted is returned for the tasks of an all-or-nothing batch that
+// were rolled back or skipped because another task failed.
+var ErrBulkAborted = errors.New("not applied because another task in the batch failed")
+
+// ErrTaskNotDone is returned when a task that is not DONE would be archived.
+var ErrTaskNotDone = errors.New("only DONE tasks can be archived")
+
+// ErrParentTrashed is returned when a task would be restored under a parent
+// that is still in the trash.
+var ErrParentTrashed = errors.New("restore the parent task first")
+
+// ErrDependencyCycle is returned when a dependency would make a task block itself.
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+// ErrTaskBlocked is returned when a task with unfinished blockers would be started.
+var ErrTaskBlocked = errors.New("task is blocked by unfinished tasks")
+
+// ErrLastAssignee is returned when the only assignee of a task would be removed.
+var ErrLastAssignee = errors.New("a task must keep at least one assignee")
+
+// ErrTimerRunning is returned when a user starts a timer while another one is running.
+var ErrTimerRunning = errors.New("a timer is already running")
+
+// ErrAmbiguousKey is returned when a task key names tasks in more than one
+// of the caller's workspaces.
+var ErrAmbiguousKey = errors.New("task key matches tasks in several workspaces, use the task id")
+
+const mysqlDuplicateEntry = 1062
+
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
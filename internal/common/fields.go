@@ -0,0 +1,379 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxFieldText bounds the length of text field values.
+const maxFieldText = 1000
+
+const customFieldColumns = "id, projectID, name, fieldType, options, createdAt"
+
+func scanCustomField(row rowScanner) (*CustomField, error) {
+	var f CustomField
+	var options []byte
+	if err := row.Scan(&f.ID, &f.ProjectID, &f.Name, &f.Type, &options, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	if options != nil {
+		if err := json.Unmarshal(options, &f.Options); err != nil {
+			return nil, fmt.Errorf("failed to decode options of custom field %d: %w", f.ID, err)
+		}
+	}
+	return &f, nil
+}
+
+func fieldOptions(f *CustomField) (any, error) {
+	if len(f.Options) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(f.Options)
+}
+
+func (s *Storage) CreateCustomField(f *CustomField) (*CustomField, error) {
+	options, err := fieldOptions(f)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec("INSERT INTO custom_fields (projectID, name, fieldType, options) VALUES (?, ?, ?, ?)",
+		f.ProjectID, f.Name, f.Type, options)
+	if isDuplicateKey(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom field: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCustomField(int(id))
+}
+
+func (s *Storage) GetCustomField(id int) (*CustomField, error) {
+	f, err := scanCustomField(s.db.QueryRow("SELECT "+customFieldColumns+" FROM custom_fields WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom field %d: %w", id, err)
+	}
+	return f, nil
+}
+
+// GetCustomFields returns the custom fields of the project in the order
+// they were added.
+func (s *Storage) GetCustomFields(projectID int) ([]*CustomField, error) {
+	return projectFields(s.db, int64(projectID))
+}
+
+func projectFields(q querier, projectID int64) ([]*CustomField, error) {
+	rows, err := q.Query("SELECT "+customFieldColumns+" FROM custom_fields WHERE projectID = ? ORDER BY id", projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom fields of project %d: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	fields := []*CustomField{}
+	for rows.Next() {
+		f, err := scanCustomField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom field row: %w", err)
+		}
+		fields = append(fields, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return fields, nil
+}
+
+// UpdateCustomField renames the field or changes its options. Tasks lose
+// the values of options that are no longer offered.
+func (s *Storage) UpdateCustomField(f *CustomField) error {
+	options, err := fieldOptions(f)
+	if err != nil {
+		return err
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE custom_fields SET name = ?, options = ? WHERE id = ?", f.Name, options, f.ID)
+		if isDuplicateKey(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update custom field %d: %w", f.ID, err)
+		}
+
+		if len(f.Options) == 0 {
+			return nil
+		}
+		args := []any{f.ID}
+		for _, option := range f.Options {
+			args = append(args, option)
+		}
+		_, err = tx.Exec("DELETE FROM task_field_values WHERE fieldID = ? AND textValue NOT IN ("+placeholders(len(f.Options))+")", args...)
+		if err != nil {
+			return fmt.Errorf("failed to drop removed options of custom field %d: %w", f.ID, err)
+		}
+		return nil
+	})
+}
+
+// DeleteCustomField removes the field and its values from every task.
+func (s *Storage) DeleteCustomField(id int) error {
+	res, err := s.db.Exec("DELETE FROM custom_fields WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom field %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NormalizeFieldValue checks that value, as decoded from JSON, fits the
+// field and returns it in the form tasks show it in: a string for text,
+// date and single select fields, a float64 for numbers, the sorted
+// options of a multi select and a user id. Empty values normalize to nil.
+func NormalizeFieldValue(f *CustomField, value any) (any, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s %s", ErrInvalidFieldValue, f.Name, fmt.Sprintf(format, args...))
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch f.Type {
+	case FieldText:
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid("must be a string")
+		}
+		text = strings.TrimSpace(text)
+		if utf8.RuneCountInString(text) > maxFieldText {
+			return nil, invalid("must be at most %d characters", maxFieldText)
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return text, nil
+
+	case FieldNumber:
+		number, ok := value.(float64)
+		if !ok {
+			return nil, invalid("must be a number")
+		}
+		return number, nil
+
+	case FieldDate:
+		date, ok := value.(string)
+		if !ok {
+			return nil, invalid("must be a date such as 2025-06-30")
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, invalid("must be a date such as 2025-06-30")
+		}
+		return date, nil
+
+	case FieldSingleSelect:
+		text, _ := value.(string)
+		option, ok := fieldOption(f, text)
+		if !ok {
+			return nil, invalid("must be one of %s", strings.Join(f.Options, ", "))
+		}
+		return option, nil
+
+	case FieldMultiSelect:
+		var values []any
+		switch v := value.(type) {
+		case []any:
+			values = v
+		case []string:
+			for _, text := range v {
+				values = append(values, text)
+			}
+		default:
+			return nil, invalid("must be a list of options")
+		}
+		var chosen []string
+		for _, v := range values {
+			text, _ := v.(string)
+			option, ok := fieldOption(f, text)
+			if !ok {
+				return nil, invalid("options must be among %s", strings.Join(f.Options, ", "))
+			}
+			if !slices.Contains(chosen, option) {
+				chosen = append(chosen, option)
+			}
+		}
+		if len(chosen) == 0 {
+			return nil, nil
+		}
+		sort.Strings(chosen)
+		return chosen, nil
+
+	case FieldUser:
+		switch id := value.(type) {
+		case int64:
+			if id > 0 {
+				return id, nil
+			}
+		case float64:
+			if id > 0 && id == math.Trunc(id) && id <= math.MaxInt32 {
+				return int64(id), nil
+			}
+		}
+		return nil, invalid("must be a user id")
+	}
+	return nil, invalid("has an unknown type %q", f.Type)
+}
+
+func fieldOption(f *CustomField, value string) (string, bool) {
+	for _, option := range f.Options {
+		if strings.EqualFold(option, strings.TrimSpace(value)) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// setTaskFields stores the custom field values of the task, keyed by field
+// name, and returns what changed for the activity log. A nil value clears
+// the field.
+func setTaskFields(q querier, task *Task, values map[string]any) (map[string]map[string]any, error) {
+	changes := map[string]map[string]any{}
+	if len(values) == 0 {
+		return changes, nil
+	}
+	if task.ProjectID == nil {
+		return nil, fmt.Errorf("%w: the task is not in a project", ErrUnknownField)
+	}
+
+	fields, err := projectFields(q, *task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*CustomField{}
+	for _, f := range fields {
+		byName[strings.ToLower(f.Name)] = f
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]any{}
+	}
+	for _, name := range names {
+		f, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownField, name)
+		}
+		value, err := NormalizeFieldValue(f, values[name])
+		if err != nil {
+			return nil, err
+		}
+		from := task.CustomFields[f.Name]
+		if reflect.DeepEqual(from, value) {
+			continue
+		}
+
+		if _, err := q.Exec("DELETE FROM task_field_values WHERE taskID = ? AND fieldID = ?", task.ID, f.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear custom field %d of task %d: %w", f.ID, task.ID, err)
+		}
+		for _, row := range fieldValueRows(f, value) {
+			_, err := q.Exec(`INSERT INTO task_field_values (taskID, fieldID, textValue, numberValue, dateValue, userID)
+				VALUES (?, ?, ?, ?, ?, ?)`, append([]any{task.ID, f.ID}, row...)...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to set custom field %d of task %d: %w", f.ID, task.ID, err)
+			}
+		}
+
+		changes["custom_fields."+f.Name] = map[string]any{"from": from, "to": value}
+		if value == nil {
+			delete(task.CustomFields, f.Name)
+		} else {
+			task.CustomFields[f.Name] = value
+		}
+	}
+	if len(task.CustomFields) == 0 {
+		task.CustomFields = nil
+	}
+	return changes, nil
+}
+
+// fieldValueRows returns the textValue, numberValue, dateValue and userID
+// columns of a normalized value, one row per option of a multi select.
+func fieldValueRows(f *CustomField, value any) [][]any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		rows := make([][]any, len(v))
+		for i, option := range v {
+			rows[i] = []any{option, nil, nil, nil}
+		}
+		return rows
+	case float64:
+		return [][]any{{nil, v, nil, nil}}
+	case int64:
+		return [][]any{{nil, nil, nil, v}}
+	}
+	if f.Type == FieldDate {
+		return [][]any{{nil, nil, value, nil}}
+	}
+	return [][]any{{value, nil, nil, nil}}
+}
+
+// decodeFieldValues turns the custom field values aggregated by taskColumns
+// into the map tasks show them in.
+func decodeFieldValues(data []byte) (map[string]any, error) {
+	var rows []struct {
+		Name   string   `json:"name"`
+		Type   string   `json:"type"`
+		Text   *string  `json:"text"`
+		Number *float64 `json:"number"`
+		Date   *string  `json:"date"`
+		User   *int64   `json:"user"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	for _, row := range rows {
+		switch {
+		case row.Type == FieldMultiSelect && row.Text != nil:
+			options, _ := values[row.Name].([]string)
+			values[row.Name] = append(options, *row.Text)
+		case row.Text != nil:
+			values[row.Name] = *row.Text
+		case row.Number != nil:
+			values[row.Name] = *row.Number
+		case row.Date != nil:
+			values[row.Name] = *row.Date
+		case row.User != nil:
+			values[row.Name] = *row.User
+		}
+	}
+	for _, value := range values {
+		if options, ok := value.([]string); ok {
+			sort.Strings(options)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNormalizeFieldValue(t *testing.T) {
+	severity := &CustomField{Name: "severity", Type: FieldSingleSelect, Options: []string{"low", "high"}}
+	environments := &CustomField{Name: "environment", Type: FieldMultiSelect, Options: []string{"staging", "prod"}}
+
+	tests := []struct {
+		name  string
+		field *CustomField
+		value any
+		want  any
+		err   string
+	}{
+		{name: "text", field: &CustomField{Name: "customer", Type: FieldText}, value: "  Acme ", want: "Acme"},
+		{name: "empty text", field: &CustomField{Name: "customer", Type: FieldText}, value: "", want: nil},
+		{name: "number", field: &CustomField{Name: "estimate", Type: FieldNumber}, value: 3.5, want: 3.5},
+		{name: "number as string", field: &CustomField{Name: "estimate", Type: FieldNumber}, value: "3.5",
+			err: "invalid custom field value: estimate must be a number"},
+		{name: "date", field: &CustomField{Name: "released", Type: FieldDate}, value: "2025-06-30", want: "2025-06-30"},
+		{name: "bad date", field: &CustomField{Name: "released", Type: FieldDate}, value: "June",
+			err: "invalid custom field value: released must be a date such as 2025-06-30"},
+		{name: "option", field: severity, value: "HIGH", want: "high"},
+		{name: "unknown option", field: severity, value: "urgent",
+			err: "invalid custom field value: severity must be one of low, high"},
+		{name: "options", field: environments, value: []any{"prod", "staging", "prod"}, want: []string{"prod", "staging"}},
+		{name: "no options", field: environments, value: []any{}, want: nil},
+		{name: "user", field: &CustomField{Name: "reviewer", Type: FieldUser}, value: 4.0, want: int64(4)},
+		{name: "fractional user", field: &CustomField{Name: "reviewer", Type: FieldUser}, value: 4.5,
+			err: "invalid custom field value: reviewer must be a user id"},
+		{name: "null", field: severity, value: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeFieldValue(tt.field, tt.value)
+			if tt.err != "" {
+				assert.ErrorIs(t, err, ErrInvalidFieldValue)
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeFieldValues(t *testing.T) {
+	values, err := decodeFieldValues([]byte(`[
+		{"name": "environment", "type": "multi_select", "text": "staging", "number": null, "date": null, "user": null},
+		{"name": "estimate", "type": "number", "text": null, "number": 3, "date": null, "user": null},
+		{"name": "environment", "type": "multi_select", "text": "prod", "number": null, "date": null, "user": null},
+		{"name": "released", "type": "date", "text": null, "number": null, "date": "2025-06-30", "user": null},
+		{"name": "reviewer", "type": "user", "text": null, "number": null, "date": null, "user": 4}
+	]`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"environment": []string{"prod", "staging"},
+		"estimate":    3.0,
+		"released":    "2025-06-30",
+		"reviewer":    int64(4),
+	}, values)
+}
+
+func TestUpdateTaskSetsCustomFields(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	projectID, number := int64(4), int64(1)
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, ProjectID: &projectID,
+		Number: &number, Key: "API-1", CustomFields: map[string]any{"customer": "Acme"}, CreatedAt: time.Now()}
+	updated := *task
+	updated.CustomFields = map[string]any{"environment": []string{"prod", "staging"}}
+	fieldColumnNames := []string{"id", "projectID", "name", "fieldType", "options", "createdAt"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectQuery("SELECT (.+) FROM custom_fields WHERE projectID = \\? ORDER BY id").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows(fieldColumnNames).
+			AddRow(7, 4, "customer", FieldText, nil, time.Now()).
+			AddRow(8, 4, "environment", FieldMultiSelect, []byte(`["staging","prod"]`), time.Now()))
+	mock.ExpectExec("DELETE FROM task_field_values WHERE taskID = \\? AND fieldID = \\?").
+		WithArgs(int64(1), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_field_values WHERE taskID = \\? AND fieldID = \\?").
+		WithArgs(int64(1), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, option := range []string{"prod", "staging"} {
+		mock.ExpectExec("INSERT INTO task_field_values \\(taskID, fieldID, textValue, numberValue, dateValue, userID\\)").
+			WithArgs(int64(1), int64(8), option, nil, nil, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectExec("UPDATE tasks SET name = \\?, description = \\?, priority = \\?, dueAt = \\?, points = \\? WHERE id = ?").
+		WithArgs("Fix login", "", "P2", nil, nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskEdited,
+			[]byte(`{"changes":{"custom_fields.customer":{"from":"Acme","to":null},"custom_fields.environment":{"from":null,"to":["prod","staging"]}}}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, &updated)
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(1).
+		WillReturnRows(taskRows(&updated))
+
+	got, err := store.UpdateTask(1, TaskPatch{CustomFields: map[string]any{
+		"Customer":    nil,
+		"environment": []any{"STAGING", "prod"},
+	}}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"environment": []string{"prod", "staging"}}, got.CustomFields)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTaskRejectsUnknownCustomField(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectRollback()
+
+	_, err := store.UpdateTask(1, TaskPatch{CustomFields: map[string]any{"customer": "Acme"}}, 3)
+	assert.ErrorIs(t, err, ErrUnknownField)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,317 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxJobAttempts is how many times a failing job runs before it is given up.
+const maxJobAttempts = 5
+
+// planningGrace is how late a reminder or escalation can still be planned,
+// for example after the servers were down. Older ones are dropped.
+const planningGrace = time.Hour
+
+var errNoJob = errors.New("no job is due")
+
+// jobHandlers run a job inside the transaction that marks it done, so its
+// database changes happen exactly once.
+var jobHandlers = map[string]func(tx *sql.Tx, job *Job) error{
+	JobReminder:   runReminder,
+	JobEscalation: runEscalation,
+}
+
+type reminderPayload struct {
+	TaskID int64     `json:"task_id"`
+	UserID int64     `json:"user_id"`
+	Before int64     `json:"before"`
+	DueAt  time.Time `json:"due_at"`
+}
+
+type escalationPayload struct {
+	TaskID int64     `json:"task_id"`
+	DueAt  time.Time `json:"due_at"`
+}
+
+func enqueueJob(q querier, job *Job) error {
+	_, err := q.Exec("INSERT IGNORE INTO jobs (kind, payload, runAt, dedupeKey) VALUES (?, ?, ?, ?)",
+		job.Kind, []byte(job.Payload), job.RunAt, job.DedupeKey)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", job.DedupeKey, err)
+	}
+	return nil
+}
+
+// PlanJobs enqueues the reminders and escalations that are due at now.
+// Each one has a dedupe key made of the task, its due date and the
+// recipient, so planning the same moment again, on any server, adds
+// nothing; a new due date gets new reminders.
+func (s *Storage) PlanJobs(now time.Time) error {
+	rows, err := s.db.Query(`
+		SELECT t.id, ta.userID, r.beforeSeconds, t.dueAt FROM tasks t
+		JOIN task_assignees ta ON ta.taskID = t.id
+		JOIN user_reminders r ON r.userID = ta.userID
+		WHERE t.status <> 'DONE' AND t.dueAt IS NOT NULL AND t.deletedAt IS NULL
+			AND DATE_SUB(t.dueAt, INTERVAL r.beforeSeconds SECOND) > ?
+			AND DATE_SUB(t.dueAt, INTERVAL r.beforeSeconds SECOND) <= ?`,
+		now.Add(-planningGrace), now)
+	if err != nil {
+		return fmt.Errorf("failed to plan reminders: %w", err)
+	}
+	var jobs []*Job
+	for rows.Next() {
+		var p reminderPayload
+		if err := rows.Scan(&p.TaskID, &p.UserID, &p.Before, &p.DueAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reminder row: %w", err)
+		}
+		payload, _ := json.Marshal(p)
+		jobs = append(jobs, &Job{
+			Kind:      JobReminder,
+			Payload:   payload,
+			RunAt:     p.DueAt.Add(-time.Duration(p.Before) * time.Second),
+			DedupeKey: fmt.Sprintf("reminder:%d:%d:%d:%d", p.TaskID, p.UserID, p.Before, p.DueAt.Unix()),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	overdue := now.Add(-s.escalationDelay)
+	rows, err = s.db.Query(`SELECT id, dueAt FROM tasks
+		WHERE status <> 'DONE' AND deletedAt IS NULL AND dueAt > ? AND dueAt <= ?`, overdue.Add(-planningGrace), overdue)
+	if err != nil {
+		return fmt.Errorf("failed to plan escalations: %w", err)
+	}
+	for rows.Next() {
+		var p escalationPayload
+		if err := rows.Scan(&p.TaskID, &p.DueAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan task row: %w", err)
+		}
+		payload, _ := json.Marshal(p)
+		jobs = append(jobs, &Job{
+			Kind:      JobEscalation,
+			Payload:   payload,
+			RunAt:     p.DueAt.Add(s.escalationDelay),
+			DedupeKey: fmt.Sprintf("escalation:%d:%d", p.TaskID, p.DueAt.Unix()),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	for _, job := range jobs {
+		if err := enqueueJob(s.db, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunDueJobs runs up to limit jobs that are due at now and returns how many
+// it ran. Each job is locked, run and marked done in one transaction, and
+// jobs locked by another server are skipped, so a crash or a second
+// replica never runs a job twice. A failing job is retried later with a
+// growing delay, until maxJobAttempts.
+func (s *Storage) RunDueJobs(now time.Time, limit int) (int, error) {
+	for n := 0; n < limit; n++ {
+		err := s.runNextJob(now)
+		if errors.Is(err, errNoJob) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return limit, nil
+}
+
+func (s *Storage) runNextJob(now time.Time) error {
+	var job Job
+	var jobErr error
+	err := s.withTx(func(tx *sql.Tx) error {
+		var payload []byte
+		err := tx.QueryRow(`SELECT id, kind, payload, runAt, attempts, dedupeKey FROM jobs
+			WHERE doneAt IS NULL AND failedAt IS NULL AND runAt <= ?
+			ORDER BY runAt, id LIMIT 1
+			FOR UPDATE SKIP LOCKED`, now).Scan(&job.ID, &job.Kind, &payload, &job.RunAt, &job.Attempts, &job.DedupeKey)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNoJob
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get due job: %w", err)
+		}
+		job.Payload = payload
+
+		handler, ok := jobHandlers[job.Kind]
+		if !ok {
+			jobErr = fmt.Errorf("unknown job kind %q", job.Kind)
+			return jobErr
+		}
+		if err := handler(tx, &job); err != nil {
+			jobErr = err
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE jobs SET attempts = attempts + 1, doneAt = ? WHERE id = ?", now, job.ID)
+		if err != nil {
+			return fmt.Errorf("failed to complete job %d: %w", job.ID, err)
+		}
+		return nil
+	})
+	if jobErr != nil {
+		return s.failJob(&job, jobErr, now)
+	}
+	return err
+}
+
+// failJob records why the job failed and when it runs again.
+func (s *Storage) failJob(job *Job, jobErr error, now time.Time) error {
+	attempts := job.Attempts + 1
+	var failedAt *time.Time
+	if attempts >= maxJobAttempts {
+		failedAt = &now
+	}
+	retryAt := now.Add(time.Duration(attempts*attempts) * time.Minute)
+
+	_, err := s.db.Exec("UPDATE jobs SET attempts = ?, lastError = ?, runAt = ?, failedAt = ? WHERE id = ?",
+		attempts, jobErr.Error(), retryAt, failedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// openTaskDue returns the due date of the task unless it is DONE or in the
+// trash. Jobs use it to skip reminders that a finished, rescheduled or
+// deleted task made stale.
+func openTaskDue(tx *sql.Tx, taskID int64) (*time.Time, error) {
+	var status string
+	var dueAt sql.NullTime
+	err := tx.QueryRow("SELECT status, dueAt FROM tasks WHERE id = ? AND deletedAt IS NULL", taskID).Scan(&status, &dueAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task %d: %w", taskID, err)
+	}
+	if status == "DONE" || !dueAt.Valid {
+		return nil, nil
+	}
+	return &dueAt.Time, nil
+}
+
+func runReminder(tx *sql.Tx, job *Job) error {
+	var p reminderPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+
+	dueAt, err := openTaskDue(tx, p.TaskID)
+	if err != nil || dueAt == nil || !dueAt.Equal(p.DueAt) {
+		return err
+	}
+	var assigned int
+	err = tx.QueryRow("SELECT COUNT(*) FROM task_assignees WHERE taskID = ? AND userID = ?", p.TaskID, p.UserID).Scan(&assigned)
+	if err != nil {
+		return fmt.Errorf("failed to get assignees of task %d: %w", p.TaskID, err)
+	}
+	if assigned == 0 {
+		return nil
+	}
+
+	return notify(tx, p.UserID, NotificationReminder, p.TaskID, nil, map[string]any{
+		"due_at":         p.DueAt,
+		"before_seconds": p.Before,
+	})
+}
+
+// runEscalation tells the leads of the assignees' teams that the task is
+// overdue. Leads who are assignees themselves already know.
+func runEscalation(tx *sql.Tx, job *Job) error {
+	var p escalationPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+
+	dueAt, err := openTaskDue(tx, p.TaskID)
+	if err != nil || dueAt == nil || !dueAt.Equal(p.DueAt) {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT lead.userID FROM task_assignees ta
+		JOIN team_members m ON m.userID = ta.userID
+		JOIN team_members lead ON lead.teamID = m.teamID AND lead.role = 'LEAD'
+		WHERE ta.taskID = ? AND lead.userID NOT IN (SELECT userID FROM task_assignees WHERE taskID = ?)
+		ORDER BY lead.userID`, p.TaskID, p.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to get team leads of task %d: %w", p.TaskID, err)
+	}
+	var leads []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan team lead row: %w", err)
+		}
+		leads = append(leads, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	for _, lead := range leads {
+		if err := notify(tx, lead, NotificationOverdue, p.TaskID, nil, map[string]any{"due_at": p.DueAt}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetReminderSettings returns how long before a due date the user is
+// reminded, shortest first.
+func (s *Storage) GetReminderSettings(userID int) ([]time.Duration, error) {
+	rows, err := s.db.Query("SELECT beforeSeconds FROM user_reminders WHERE userID = ? ORDER BY beforeSeconds", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	before := []time.Duration{}
+	for rows.Next() {
+		var seconds int64
+		if err := rows.Scan(&seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder row: %w", err)
+		}
+		before = append(before, time.Duration(seconds)*time.Second)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return before, nil
+}
+
+// SetReminderSettings replaces the user's reminders.
+func (s *Storage) SetReminderSettings(userID int, before []time.Duration) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM user_reminders WHERE userID = ?", userID); err != nil {
+			return fmt.Errorf("failed to clear reminders of user %d: %w", userID, err)
+		}
+		for _, d := range before {
+			_, err := tx.Exec("INSERT IGNORE INTO user_reminders (userID, beforeSeconds) VALUES (?, ?)",
+				userID, int64(d/time.Second))
+			if err != nil {
+				return fmt.Errorf("failed to set reminders of user %d: %w", userID, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var jobColumnNames = []string{"id", "kind", "payload", "runAt", "attempts", "dedupeKey"}
+
+func TestPlanJobs(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.escalationDelay = 24 * time.Hour
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 1, 9, 12, 0, 0, 0, time.UTC)
+	overdue := time.Date(2025, 1, 7, 11, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT (.+) JOIN user_reminders r").
+		WithArgs(now.Add(-time.Hour), now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "userID", "beforeSeconds", "dueAt"}).AddRow(4, 2, 86400, due))
+	mock.ExpectQuery("SELECT id, dueAt FROM tasks").
+		WithArgs(now.Add(-25*time.Hour), now.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "dueAt"}).AddRow(5, overdue))
+	mock.ExpectExec("INSERT IGNORE INTO jobs").
+		WithArgs(JobReminder, []byte(`{"task_id":4,"user_id":2,"before":86400,"due_at":"2025-01-09T12:00:00Z"}`),
+			now, "reminder:4:2:86400:1736424000").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO jobs").
+		WithArgs(JobEscalation, []byte(`{"task_id":5,"due_at":"2025-01-07T11:00:00Z"}`),
+			overdue.Add(24*time.Hour), "escalation:5:1736247600").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, store.PlanJobs(now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDueJobsSendsReminder(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 1, 9, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM jobs (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(jobColumnNames).
+			AddRow(1, JobReminder, `{"task_id":4,"user_id":2,"before":86400,"due_at":"2025-01-09T12:00:00Z"}`, now, 0, "reminder:4:2:86400:1736424000"))
+	mock.ExpectQuery("SELECT status, dueAt FROM tasks WHERE id = ?").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "dueAt"}).AddRow("IN_PROGRESS", due))
+	mock.ExpectQuery("SELECT COUNT(.+) FROM task_assignees").
+		WithArgs(int64(4), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(2), NotificationReminder, int64(4), nil, []byte(`{"before_seconds":86400,"due_at":"2025-01-09T12:00:00Z"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE jobs SET attempts = attempts \\+ 1, doneAt = \\? WHERE id = ?").
+		WithArgs(now, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM jobs").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(jobColumnNames))
+	mock.ExpectRollback()
+
+	ran, err := store.RunDueJobs(now, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDueJobsSkipsStaleReminder(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM jobs").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(jobColumnNames).
+			AddRow(1, JobReminder, `{"task_id":4,"user_id":2,"before":0,"due_at":"2025-01-08T12:00:00Z"}`, now, 0, "reminder:4:2:0:1736337600"))
+	mock.ExpectQuery("SELECT status, dueAt FROM tasks WHERE id = ?").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "dueAt"}).AddRow("TODO", now.Add(48*time.Hour)))
+	mock.ExpectExec("UPDATE jobs SET attempts = attempts \\+ 1, doneAt").
+		WithArgs(now, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ran, err := store.RunDueJobs(now, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDueJobsEscalatesToTeamLeads(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 1, 7, 11, 0, 0, 0, time.UTC)
+	details := []byte(`{"due_at":"2025-01-07T11:00:00Z"}`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM jobs").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(jobColumnNames).
+			AddRow(1, JobEscalation, `{"task_id":5,"due_at":"2025-01-07T11:00:00Z"}`, now, 0, "escalation:5:1736247600"))
+	mock.ExpectQuery("SELECT status, dueAt FROM tasks WHERE id = ?").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "dueAt"}).AddRow("IN_PROGRESS", due))
+	mock.ExpectQuery("SELECT DISTINCT lead.userID FROM task_assignees ta").
+		WithArgs(int64(5), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"userID"}).AddRow(7).AddRow(9))
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(7), NotificationOverdue, int64(5), nil, details).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(9), NotificationOverdue, int64(5), nil, details).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE jobs SET attempts = attempts \\+ 1, doneAt").
+		WithArgs(now, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ran, err := store.RunDueJobs(now, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDueJobsReschedulesFailure(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM jobs").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(jobColumnNames).
+			AddRow(1, JobEscalation, `{"task_id":5,"due_at":"2025-01-07T11:00:00Z"}`, now, 1, "escalation:5:1736247600"))
+	mock.ExpectQuery("SELECT status, dueAt FROM tasks WHERE id = ?").
+		WithArgs(int64(5)).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectExec("UPDATE jobs SET attempts = \\?, lastError = \\?, runAt = \\?, failedAt = \\? WHERE id = ?").
+		WithArgs(2, "failed to get task 5: connection reset", now.Add(4*time.Minute), nil, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ran, err := store.RunDueJobs(now, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (s *Storage) CreateLabel(l *Label) (*Label, error) {
+	res, err := s.db.Exec("INSERT INTO labels (workspaceID, name, color) VALUES (?, ?, ?)", l.WorkspaceID, l.Name, l.Color)
+	if isDuplicateKey(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	l.ID = id
+	return l, nil
+}
+
+func (s *Storage) GetLabel(id int) (*Label, error) {
+	var l Label
+	err := s.db.QueryRow("SELECT id, workspaceID, name, color FROM labels WHERE id = ?", id).
+		Scan(&l.ID, &l.WorkspaceID, &l.Name, &l.Color)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *Storage) GetLabels(workspaceID int) ([]*Label, error) {
+	rows, err := s.db.Query("SELECT id, workspaceID, name, color FROM labels WHERE workspaceID = ? ORDER BY name", workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels of workspace with id %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	labels := []*Label{}
+	for rows.Next() {
+		var l Label
+		if err := rows.Scan(&l.ID, &l.WorkspaceID, &l.Name, &l.Color); err != nil {
+			return nil, fmt.Errorf("failed to scan label row: %w", err)
+		}
+		labels = append(labels, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return labels, nil
+}
+
+// UpdateLabel renames or recolors a label. Tasks point at the label id, so
+// they pick up the new name without being touched.
+func (s *Storage) UpdateLabel(l *Label) error {
+	_, err := s.db.Exec("UPDATE labels SET name = ?, color = ? WHERE id = ?", l.Name, l.Color, l.ID)
+	if isDuplicateKey(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update label with id %d: %w", l.ID, err)
+	}
+	return nil
+}
+
+// DeleteLabel detaches the label from every task and deletes it in one
+// transaction.
+func (s *Storage) DeleteLabel(id int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM task_labels WHERE labelID = ?", id); err != nil {
+			return fmt.Errorf("failed to detach label with id %d: %w", id, err)
+		}
+		res, err := tx.Exec("DELETE FROM labels WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete label with id %d: %w", id, err)
+		}
+		return requireAffected(res)
+	})
+}
+
+func (s *Storage) AddTaskLabel(taskID, labelID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return addTaskLabel(tx, int64(taskID), int64(labelID), int64(actorID))
+	})
+}
+
+func addTaskLabel(tx *sql.Tx, taskID, labelID, actorID int64) error {
+	res, err := tx.Exec("INSERT IGNORE INTO task_labels (taskID, labelID) VALUES (?, ?)", taskID, labelID)
+	if err != nil {
+		return fmt.Errorf("failed to add label %d to task %d: %w", labelID, taskID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	return recordActivity(tx, taskID, actorID, ActivityLabelAdded, map[string]any{"label_id": labelID})
+}
+
+func (s *Storage) RemoveTaskLabel(taskID, labelID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM task_labels WHERE taskID = ? AND labelID = ?", taskID, labelID)
+		if err != nil {
+			return fmt.Errorf("failed to remove label %d from task %d: %w", labelID, taskID, err)
+		}
+		if err := requireAffected(res); err != nil {
+			return err
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityLabelRemoved, map[string]any{"label_id": labelID})
+	})
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateLabelConflict(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("INSERT INTO labels").
+		WithArgs(int64(1), "backend", "#1d76db").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	_, err := store.CreateLabel(&Label{WorkspaceID: 1, Name: "backend", Color: "#1d76db"})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteLabelDetachesInTransaction(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM task_labels WHERE labelID = ?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec("DELETE FROM labels WHERE id = ?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.DeleteLabel(3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMissingLabelRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM task_labels WHERE labelID = ?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM labels WHERE id = ?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.DeleteLabel(3), ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskWithLabels(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	workspaceID := int64(2)
+	mockTask := &Task{
+		ID:           1,
+		Name:         "Sample Task",
+		Status:       "TODO",
+		Priority:     "P2",
+		AssignedToID: 1,
+		WorkspaceID:  &workspaceID,
+		Labels:       []Label{{ID: 4, WorkspaceID: 2, Name: "backend", Color: "#1d76db"}},
+		CreatedAt:    time.Now(),
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(1).
+		WillReturnRows(taskRows(mockTask))
+
+	task, err := store.GetTask(1)
+	assert.NoError(t, err)
+	assert.Equal(t, mockTask, task)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
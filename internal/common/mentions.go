@@ -0,0 +1,174 @@
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxMentions caps how many distinct handles one text can mention.
+const maxMentions = 50
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w[\w.-]*)`)
+	codePattern    = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// parseMentions returns the distinct lowercased handles mentioned in text,
+// in order of appearance. A handle is the local part of a user's email,
+// so "@jane.doe" refers to jane.doe@example.com. Mentions inside Markdown
+// code spans and blocks are ignored, as are email addresses.
+func parseMentions(text string) []string {
+	text = codePattern.ReplaceAllString(text, " ")
+
+	var handles []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == maxMentions {
+			break
+		}
+	}
+	return handles
+}
+
+// resolveMentions maps handles to the ids of members of the workspace.
+// Handles that match no member, or more than one, are dropped.
+func resolveMentions(q querier, workspaceID int64, handles []string) ([]int64, error) {
+	args := []any{workspaceID}
+	for _, h := range handles {
+		args = append(args, h)
+	}
+
+	rows, err := q.Query(`SELECT u.id, SUBSTRING_INDEX(u.email, '@', 1) FROM users u
+		JOIN workspace_members m ON m.userID = u.id
+		WHERE m.workspaceID = ? AND SUBSTRING_INDEX(u.email, '@', 1) IN (`+placeholders(len(handles))+`)
+		ORDER BY u.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var handle string
+		if err := rows.Scan(&id, &handle); err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		handle = strings.ToLower(handle)
+		matches[handle] = append(matches[handle], id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	var ids []int64
+	for _, h := range handles {
+		if len(matches[h]) == 1 {
+			ids = append(ids, matches[h][0])
+		}
+	}
+	return ids, nil
+}
+
+// recordMentions stores the mentions found in text and notifies each
+// mentioned user once. Only members of the task's workspace can be
+// mentioned; anything else is skipped without a trace, so callers cannot
+// learn which users exist. Tasks outside a workspace have no mentions.
+func recordMentions(q querier, m Mention, text string) error {
+	handles := parseMentions(text)
+	if len(handles) == 0 {
+		return nil
+	}
+
+	var workspaceID sql.NullInt64
+	err := q.QueryRow("SELECT workspaceID FROM tasks WHERE id = ?", m.TaskID).Scan(&workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace of task %d: %w", m.TaskID, err)
+	}
+	if !workspaceID.Valid {
+		return nil
+	}
+
+	userIDs, err := resolveMentions(q, workspaceID.Int64, handles)
+	if err != nil {
+		return err
+	}
+
+	source, sourceID := MentionSourceTask, m.TaskID
+	details := map[string]any{}
+	if m.CommentID != nil {
+		source, sourceID = MentionSourceComment, *m.CommentID
+		details["comment_id"] = *m.CommentID
+	}
+
+	for _, userID := range userIDs {
+		res, err := q.Exec("INSERT IGNORE INTO mentions (userID, taskID, source, sourceID, authorID) VALUES (?, ?, ?, ?, ?)",
+			userID, m.TaskID, source, sourceID, m.AuthorID)
+		if err != nil {
+			return fmt.Errorf("failed to record mention: %w", err)
+		}
+		// Editing a text keeps its earlier mentions; only new ones notify.
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		if m.AuthorID != nil && *m.AuthorID == userID {
+			continue
+		}
+
+		mentionID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		details["mention_id"] = mentionID
+		if err := notify(q, userID, NotificationMention, m.TaskID, m.AuthorID, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetMentionsForUser(userID, limit int) ([]*Mention, error) {
+	rows, err := s.db.Query(`SELECT id, userID, taskID, source, sourceID, authorID, createdAt FROM mentions
+		WHERE userID = ? ORDER BY id DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mentions of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	mentions := []*Mention{}
+	for rows.Next() {
+		var m Mention
+		var source string
+		var sourceID int64
+		var authorID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.UserID, &m.TaskID, &source, &sourceID, &authorID, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan mention row: %w", err)
+		}
+		if source == MentionSourceComment {
+			m.CommentID = &sourceID
+		}
+		if authorID.Valid {
+			m.AuthorID = &authorID.Int64
+		}
+		mentions = append(mentions, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return mentions, nil
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"@jane.doe can you look?", []string{"jane.doe"}},
+		{"Thanks @Jane.Doe. And @bob, @jane.doe again", []string{"jane.doe", "bob"}},
+		{"mail jane@example.com instead", nil},
+		{"run `@decorator` and\n```\n@inside\n```\n@outside", []string{"outside"}},
+		{"(@carol) @dave-", []string{"carol", "dave"}},
+		{"no mentions here", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseMentions(tt.text), tt.text)
+	}
+}
+
+func TestCreateCommentNotifiesMentionedMembers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	task := &Task{ID: 1, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, CreatedAt: time.Now()}
+	body := "@jane @john @ghost @me please review"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO comments").
+		WithArgs(int64(1), int64(2), nil, body).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT workspaceID FROM tasks WHERE id = ?").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"workspaceID"}).AddRow(4))
+	// "john" matches two members with different email domains, "ghost" is
+	// not a member of the workspace.
+	mock.ExpectQuery("SELECT u.id, SUBSTRING_INDEX(.+) FROM users u").
+		WithArgs(int64(4), "jane", "john", "ghost", "me").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "handle"}).
+			AddRow(2, "me").
+			AddRow(3, "Jane").
+			AddRow(5, "john").
+			AddRow(6, "john"))
+	mock.ExpectExec("INSERT IGNORE INTO mentions").
+		WithArgs(int64(3), int64(1), MentionSourceComment, int64(9), int64(2)).
+		WillReturnResult(sqlmock.NewResult(20, 1))
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(3), NotificationMention, int64(1), int64(2), []byte(`{"comment_id":9,"mention_id":20}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO mentions").
+		WithArgs(int64(2), int64(1), MentionSourceComment, int64(9), int64(2)).
+		WillReturnResult(sqlmock.NewResult(21, 1))
+	expectWatchers(mock, 1)
+	mock.ExpectCommit()
+	expectReindex(mock, task, body)
+
+	_, err := store.CreateComment(&Comment{TaskID: 1, AuthorID: 2, Body: body})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMentionsIgnoredOutsideWorkspace(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	task := &Task{Name: "Ping", Description: "cc @jane", Status: "TODO", Priority: "P2", AssignedToID: 1}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT workspaceID FROM tasks WHERE id = ?").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"workspaceID"}).AddRow(nil))
+	mock.ExpectExec("INSERT INTO activity").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
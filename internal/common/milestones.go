@@ -0,0 +1,282 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// throughputWindow is how far back finished work counts towards the pace
+// a milestone is projected at.
+const throughputWindow = 28 * 24 * time.Hour
+
+const milestoneColumns = "id, projectID, name, COALESCE(description, ''), targetDate, status, closedAt, COALESCE(releaseNotes, ''), createdAt"
+
+func scanMilestone(row rowScanner) (*Milestone, error) {
+	var m Milestone
+	var targetDate time.Time
+	var closedAt sql.NullTime
+	err := row.Scan(&m.ID, &m.ProjectID, &m.Name, &m.Description, &targetDate, &m.Status, &closedAt,
+		&m.ReleaseNotes, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	m.TargetDate = targetDate.Format(time.DateOnly)
+	if closedAt.Valid {
+		m.ClosedAt = &closedAt.Time
+	}
+	return &m, nil
+}
+
+func (s *Storage) CreateMilestone(m *Milestone) (*Milestone, error) {
+	res, err := s.db.Exec(`INSERT INTO milestones (projectID, name, description, targetDate, status)
+		VALUES (?, ?, ?, ?, ?)`, m.ProjectID, m.Name, m.Description, m.TargetDate, MilestoneOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create milestone: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMilestone(int(id))
+}
+
+func (s *Storage) GetMilestone(id int) (*Milestone, error) {
+	m, err := scanMilestone(s.db.QueryRow("SELECT "+milestoneColumns+" FROM milestones WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestone %d: %w", id, err)
+	}
+	return m, nil
+}
+
+// GetMilestones returns the milestones of the project by target date.
+func (s *Storage) GetMilestones(projectID int) ([]*Milestone, error) {
+	rows, err := s.db.Query("SELECT "+milestoneColumns+" FROM milestones WHERE projectID = ? ORDER BY targetDate, id", projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestones of project %d: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	milestones := []*Milestone{}
+	for rows.Next() {
+		m, err := scanMilestone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan milestone row: %w", err)
+		}
+		milestones = append(milestones, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return milestones, nil
+}
+
+// UpdateMilestone saves the name, description and target date of the
+// milestone.
+func (s *Storage) UpdateMilestone(m *Milestone) error {
+	_, err := s.db.Exec("UPDATE milestones SET name = ?, description = ?, targetDate = ? WHERE id = ?",
+		m.Name, m.Description, m.TargetDate, m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone %d: %w", m.ID, err)
+	}
+	return nil
+}
+
+// GetMilestoneProgress counts the live tasks of the milestone, archived
+// ones included, and projects when the rest will be done from what was
+// finished in the four weeks before now.
+func (s *Storage) GetMilestoneProgress(id int, now time.Time) (*MilestoneProgress, error) {
+	var p MilestoneProgress
+	var recentTasks, recentPoints int
+	since := now.Add(-throughputWindow)
+	err := s.db.QueryRow(`SELECT COUNT(*),
+			COALESCE(SUM(status = 'DONE'), 0),
+			COALESCE(SUM(points), 0),
+			COALESCE(SUM(CASE WHEN status = 'DONE' THEN points END), 0),
+			COALESCE(SUM(status = 'DONE' AND doneAt >= ?), 0),
+			COALESCE(SUM(CASE WHEN status = 'DONE' AND doneAt >= ? THEN points END), 0)
+		FROM tasks WHERE milestoneID = ? AND deletedAt IS NULL`, since, since, id).
+		Scan(&p.TasksTotal, &p.TasksDone, &p.PointsTotal, &p.PointsDone, &recentTasks, &recentPoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progress of milestone %d: %w", id, err)
+	}
+
+	done, total, recent := p.TasksDone, p.TasksTotal, recentTasks
+	if p.PointsTotal > 0 {
+		done, total, recent = p.PointsDone, p.PointsTotal, recentPoints
+	}
+	if total > 0 {
+		p.Percent = done * 100 / total
+	}
+	weeks := throughputWindow.Hours() / (7 * 24)
+	p.Throughput = math.Round(float64(recent)/weeks*10) / 10
+
+	switch {
+	case total > 0 && done == total:
+		p.ProjectedCompletion = now.Format(time.DateOnly)
+	case recent > 0:
+		perDay := float64(recent) / (throughputWindow.Hours() / 24)
+		days := math.Ceil(float64(total-done) / perDay)
+		p.ProjectedCompletion = now.AddDate(0, 0, int(days)).Format(time.DateOnly)
+	}
+	return &p, nil
+}
+
+// SetTaskMilestone links the task to an open milestone, or unlinks it when
+// milestoneID is nil.
+func (s *Storage) SetTaskMilestone(taskID int, milestoneID *int64, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var current sql.NullInt64
+		err := tx.QueryRow("SELECT milestoneID FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", taskID).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", taskID, err)
+		}
+
+		if milestoneID != nil {
+			if current.Valid && current.Int64 == *milestoneID {
+				return nil
+			}
+			var status string
+			err := tx.QueryRow("SELECT status FROM milestones WHERE id = ? FOR SHARE", *milestoneID).Scan(&status)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get milestone %d: %w", *milestoneID, err)
+			}
+			if status == MilestoneClosed {
+				return ErrMilestoneClosed
+			}
+		} else if !current.Valid {
+			return nil
+		}
+
+		if _, err := tx.Exec("UPDATE tasks SET milestoneID = ? WHERE id = ?", milestoneID, taskID); err != nil {
+			return fmt.Errorf("failed to set milestone of task %d: %w", taskID, err)
+		}
+
+		var from any
+		if current.Valid {
+			from = current.Int64
+		}
+		changes := map[string]any{"milestone_id": map[string]any{"from": from, "to": milestoneID}}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityTaskEdited, map[string]any{"changes": changes})
+	})
+}
+
+// CloseMilestone closes an open milestone and writes its release notes:
+// the DONE tasks grouped by label, which it also returns. Tasks that are
+// not done stay linked but are left out of the notes.
+func (s *Storage) CloseMilestone(id int, now time.Time) (*Milestone, []ReleaseNoteGroup, error) {
+	var groups []ReleaseNoteGroup
+	err := s.withTx(func(tx *sql.Tx) error {
+		m, err := scanMilestone(tx.QueryRow("SELECT "+milestoneColumns+" FROM milestones WHERE id = ? FOR UPDATE", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get milestone %d: %w", id, err)
+		}
+		if m.Status == MilestoneClosed {
+			return ErrMilestoneClosed
+		}
+
+		rows, err := tx.Query("SELECT "+taskColumns+` FROM tasks t
+			WHERE t.milestoneID = ? AND t.status = 'DONE' AND t.deletedAt IS NULL
+			ORDER BY t.doneAt, t.id`, id)
+		if err != nil {
+			return fmt.Errorf("failed to get tasks of milestone %d: %w", id, err)
+		}
+		tasks, err := scanTasks(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		groups = releaseNotes(tasks)
+		_, err = tx.Exec("UPDATE milestones SET status = ?, closedAt = ?, releaseNotes = ? WHERE id = ?",
+			MilestoneClosed, now, renderReleaseNotes(m.Name, groups), id)
+		if err != nil {
+			return fmt.Errorf("failed to close milestone %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := s.GetMilestone(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, groups, nil
+}
+
+// releaseNotes groups the tasks by label name, in alphabetical order, with
+// the tasks without labels last. A task with several labels is listed
+// under each of them.
+func releaseNotes(tasks []*Task) []ReleaseNoteGroup {
+	byLabel := map[string][]ReleaseNote{}
+	var unlabeled []ReleaseNote
+	for _, t := range tasks {
+		note := ReleaseNote{ID: t.ID, Key: t.Key, Name: t.Name}
+		if len(t.Labels) == 0 {
+			unlabeled = append(unlabeled, note)
+			continue
+		}
+		for _, l := range t.Labels {
+			byLabel[l.Name] = append(byLabel[l.Name], note)
+		}
+	}
+
+	labels := make([]string, 0, len(byLabel))
+	for label := range byLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	groups := make([]ReleaseNoteGroup, 0, len(labels)+1)
+	for _, label := range labels {
+		groups = append(groups, ReleaseNoteGroup{Label: label, Tasks: byLabel[label]})
+	}
+	if len(unlabeled) > 0 {
+		groups = append(groups, ReleaseNoteGroup{Tasks: unlabeled})
+	}
+	return groups
+}
+
+// renderReleaseNotes writes the groups as Markdown, one section per label.
+func renderReleaseNotes(name string, groups []ReleaseNoteGroup) string {
+	var b strings.Builder
+	b.WriteString("# " + name + "\n")
+	if len(groups) == 0 {
+		b.WriteString("\nNo finished tasks.\n")
+	}
+	for _, g := range groups {
+		label := g.Label
+		if label == "" {
+			label = "Other"
+		}
+		b.WriteString("\n## " + label + "\n\n")
+		for _, note := range g.Tasks {
+			ref := note.Key
+			if ref == "" {
+				ref = "#" + strconv.FormatInt(note.ID, 10)
+			}
+			b.WriteString("- " + ref + " " + note.Name + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGetMilestoneProgressProjectsCompletion(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-28 * 24 * time.Hour)
+
+	// 20 of 50 points are done, 14 of them in the last four weeks: half a
+	// point a day leaves 60 days for the remaining 30.
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\),(.+) FROM tasks WHERE milestoneID = \\? AND deletedAt IS NULL").
+		WithArgs(since, since, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "done", "points", "pointsDone", "recent", "recentPoints"}).
+			AddRow(12, 5, 50, 20, 3, 14))
+
+	p, err := store.GetMilestoneProgress(3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, p.TasksTotal)
+	assert.Equal(t, 5, p.TasksDone)
+	assert.Equal(t, 40, p.Percent)
+	assert.Equal(t, 3.5, p.Throughput)
+	assert.Equal(t, "2025-04-30", p.ProjectedCompletion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMilestoneProgressWithoutRecentWork(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\),(.+) FROM tasks WHERE milestoneID = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"total", "done", "points", "pointsDone", "recent", "recentPoints"}).
+			AddRow(4, 1, 0, 0, 0, 0))
+
+	p, err := store.GetMilestoneProgress(3, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 25, p.Percent)
+	assert.Empty(t, p.ProjectedCompletion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseMilestoneWritesReleaseNotes(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+	targetDate := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	milestoneColumnNames := []string{"id", "projectID", "name", "description", "targetDate", "status", "closedAt", "releaseNotes", "createdAt"}
+
+	projectID, one, two := int64(4), int64(1), int64(2)
+	backend := Label{ID: 1, WorkspaceID: 2, Name: "backend"}
+	ui := Label{ID: 2, WorkspaceID: 2, Name: "ui"}
+	tasks := []*Task{
+		{ID: 9, Name: "Fix login", Status: "DONE", ProjectID: &projectID, Number: &one, Key: "API-1", Labels: []Label{ui, backend}},
+		{ID: 10, Name: "Faster search", Status: "DONE", ProjectID: &projectID, Number: &two, Key: "API-2", Labels: []Label{backend}},
+		{ID: 11, Name: "Update docs", Status: "DONE"},
+	}
+	notes := "# 1.4\n\n## backend\n\n- API-1 Fix login\n- API-2 Faster search\n\n## ui\n\n- API-1 Fix login\n\n## Other\n\n- #11 Update docs\n"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM milestones WHERE id = \\? FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(milestoneColumnNames).
+			AddRow(3, 4, "1.4", "", targetDate, MilestoneOpen, nil, "", now))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.milestoneID = \\? AND t.status = 'DONE'").
+		WithArgs(3).
+		WillReturnRows(taskRows(tasks...))
+	mock.ExpectExec("UPDATE milestones SET status = \\?, closedAt = \\?, releaseNotes = \\? WHERE id = \\?").
+		WithArgs(MilestoneClosed, now, notes, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM milestones WHERE id = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(milestoneColumnNames).
+			AddRow(3, 4, "1.4", "", targetDate, MilestoneClosed, now, notes, now))
+
+	m, groups, err := store.CloseMilestone(3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, MilestoneClosed, m.Status)
+	assert.Equal(t, notes, m.ReleaseNotes)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, "backend", groups[0].Label)
+	assert.Len(t, groups[0].Tasks, 2)
+	assert.Equal(t, "", groups[2].Label)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTaskMilestoneRejectsClosedMilestone(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	milestoneID := int64(3)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT milestoneID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"milestoneID"}).AddRow(nil))
+	mock.ExpectQuery("SELECT status FROM milestones WHERE id = \\? FOR SHARE").
+		WithArgs(milestoneID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(MilestoneClosed))
+	mock.ExpectRollback()
+
+	err := store.SetTaskMilestone(9, &milestoneID, 3)
+	assert.ErrorIs(t, err, ErrMilestoneClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
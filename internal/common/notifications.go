@@ -0,0 +1,52 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// notify queues a notification for the user. It runs on q so callers can
+// make it part of the change that caused it.
+func notify(q querier, userID int64, kind string, taskID int64, actorID *int64, details any) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec("INSERT INTO notifications (userID, type, taskID, actorID, details) VALUES (?, ?, ?, ?, ?)",
+		userID, kind, taskID, actorID, encoded)
+	if err != nil {
+		return fmt.Errorf("failed to notify user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// GetNotifications returns the user's latest notifications, newest first.
+func (s *Storage) GetNotifications(userID, limit int) ([]*Notification, error) {
+	rows, err := s.db.Query(`SELECT id, userID, type, taskID, actorID, details, createdAt FROM notifications
+		WHERE userID = ? ORDER BY id DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		var n Notification
+		var actorID sql.NullInt64
+		var details []byte
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.TaskID, &actorID, &details, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		if actorID.Valid {
+			n.ActorID = &actorID.Int64
+		}
+		n.Details = details
+		notifications = append(notifications, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return notifications, nil
+}
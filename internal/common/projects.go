@@ -0,0 +1,382 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const projectColumns = "id, workspaceID, projectKey, name, wipLimits, createdAt"
+
+func scanProject(row rowScanner) (*Project, error) {
+	var p Project
+	var wipLimits []byte
+	if err := row.Scan(&p.ID, &p.WorkspaceID, &p.Key, &p.Name, &wipLimits, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if wipLimits != nil {
+		if err := json.Unmarshal(wipLimits, &p.WIPLimits); err != nil {
+			return nil, fmt.Errorf("failed to decode WIP limits of project %d: %w", p.ID, err)
+		}
+	}
+	return &p, nil
+}
+
+func taskKey(projectKey string, number int64) string {
+	return projectKey + "-" + strconv.FormatInt(number, 10)
+}
+
+func (s *Storage) CreateProject(p *Project) (*Project, error) {
+	res, err := s.db.Exec("INSERT INTO projects (workspaceID, projectKey, name) VALUES (?, ?, ?)", p.WorkspaceID, p.Key, p.Name)
+	if isDuplicateKey(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProject(int(id))
+}
+
+func (s *Storage) GetProject(id int) (*Project, error) {
+	p, err := scanProject(s.db.QueryRow("SELECT "+projectColumns+" FROM projects WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project %d: %w", id, err)
+	}
+	return p, nil
+}
+
+func (s *Storage) GetProjects(workspaceID int) ([]*Project, error) {
+	rows, err := s.db.Query("SELECT "+projectColumns+" FROM projects WHERE workspaceID = ? ORDER BY projectKey", workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects of workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	projects := []*Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project row: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return projects, nil
+}
+
+// UpdateProject saves the name and WIP limits of the project. The key
+// cannot change, since task keys are built from it.
+func (s *Storage) UpdateProject(p *Project) error {
+	var wipLimits []byte
+	if len(p.WIPLimits) > 0 {
+		var err error
+		if wipLimits, err = json.Marshal(p.WIPLimits); err != nil {
+			return fmt.Errorf("failed to encode WIP limits of project %d: %w", p.ID, err)
+		}
+	}
+	_, err := s.db.Exec("UPDATE projects SET name = ?, wipLimits = ? WHERE id = ?", p.Name, wipLimits, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update project %d: %w", p.ID, err)
+	}
+	return nil
+}
+
+// GetTaskByKey returns the task with the given project key and number in
+// the workspaces of userID. Keys are unique per workspace, so a key found
+// in more than one of them is ErrAmbiguousKey. A task that has since moved
+// to another project is still found by its old key; its Key then differs
+// from the one asked for.
+func (s *Storage) GetTaskByKey(projectKey string, number int64, userID int) (*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		JOIN (SELECT COALESCE(
+				(SELECT c.id FROM tasks c WHERE c.projectID = p.id AND c.number = ?),
+				(SELECT r.taskID FROM task_key_redirects r WHERE r.projectID = p.id AND r.number = ?)) AS id
+			FROM projects p
+			WHERE p.projectKey = ? AND p.workspaceID IN (SELECT workspaceID FROM workspace_members WHERE userID = ?)) k ON k.id = t.id
+		WHERE t.deletedAt IS NULL`, number, number, projectKey, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task %s: %w", taskKey(projectKey, number), err)
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get task %s: %w", taskKey(projectKey, number), err)
+	}
+
+	switch len(tasks) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return tasks[0], nil
+	default:
+		return nil, ErrAmbiguousKey
+	}
+}
+
+// MoveTask moves a top-level task and its subtasks to another project, or
+// out of their project when projectID is nil. Without a project the tasks
+// move to workspaceID. Tasks whose status is a key of statusMap change to
+// its value.
+func (s *Storage) MoveTask(id int, workspaceID int64, projectID *int64, statusMap map[string]string, actorID int) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		return moveTask(tx, int64(id), workspaceID, projectID, statusMap, int64(actorID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTask(id)
+}
+
+// allocateTaskNumber takes the next task number of the project and
+// returns it with the task key it makes. The project row stays locked
+// until the transaction ends, so concurrent creates get distinct numbers.
+func allocateTaskNumber(q querier, projectID int64) (int64, string, error) {
+	var number int64
+	var projectKey string
+	err := q.QueryRow("SELECT nextNumber, projectKey FROM projects WHERE id = ? FOR UPDATE", projectID).
+		Scan(&number, &projectKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrNotFound
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get project %d: %w", projectID, err)
+	}
+
+	if _, err := q.Exec("UPDATE projects SET nextNumber = nextNumber + 1 WHERE id = ?", projectID); err != nil {
+		return 0, "", fmt.Errorf("failed to number task in project %d: %w", projectID, err)
+	}
+	return number, taskKey(projectKey, number), nil
+}
+
+// moveTask moves a top-level task and its subtasks to another workspace or
+// project within tx. With a project, the tasks move to the project's
+// workspace and get new numbers in it; the keys they had are kept as
+// redirects. Labels of the old workspace are taken off the moved tasks, and
+// so are milestones, custom field values and unfinished sprints, which
+// belong to the old project. Statuses change as statusMap says, and the
+// target project's WIP limits apply to the statuses the tasks end up in.
+func moveTask(tx *sql.Tx, id, workspaceID int64, projectID *int64, statusMap map[string]string, actorID int64) error {
+	var parentID, fromWorkspaceID, fromProjectID, fromNumber sql.NullInt64
+	err := tx.QueryRow("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", id).
+		Scan(&parentID, &fromWorkspaceID, &fromProjectID, &fromNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get task %d: %w", id, err)
+	}
+	if parentID.Valid {
+		return ErrMoveSubtask
+	}
+
+	if projectID != nil {
+		err := tx.QueryRow("SELECT workspaceID FROM projects WHERE id = ?", *projectID).Scan(&workspaceID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get project %d: %w", *projectID, err)
+		}
+	}
+	sameProject := fromProjectID.Valid == (projectID != nil) && (projectID == nil || fromProjectID.Int64 == *projectID)
+	if fromWorkspaceID.Valid && fromWorkspaceID.Int64 == workspaceID && sameProject {
+		return nil
+	}
+
+	ids, err := trashedSubtree(tx, id, nil)
+	if err != nil {
+		return err
+	}
+	in := "(" + placeholders(len(ids)) + ")"
+	if projectID != nil && !sameProject {
+		if err := checkMoveWIPLimits(tx, *projectID, ids, statusMap); err != nil {
+			return err
+		}
+	}
+
+	var fromKey any
+	if fromNumber.Valid {
+		var projectKey string
+		err := tx.QueryRow("SELECT projectKey FROM projects WHERE id = ?", fromProjectID.Int64).Scan(&projectKey)
+		if err != nil {
+			return fmt.Errorf("failed to get project %d: %w", fromProjectID.Int64, err)
+		}
+		fromKey = taskKey(projectKey, fromNumber.Int64)
+	}
+
+	_, err = tx.Exec(`INSERT INTO task_key_redirects (projectID, number, taskID)
+		SELECT projectID, number, id FROM tasks WHERE id IN `+in+` AND number IS NOT NULL`, int64Args(ids)...)
+	if err != nil {
+		return fmt.Errorf("failed to keep keys of task %d: %w", id, err)
+	}
+	args := append([]any{workspaceID, projectID}, int64Args(ids)...)
+	_, err = tx.Exec(`UPDATE tasks SET workspaceID = ?, projectID = ?, number = NULL, boardRank = NULL, milestoneID = NULL
+		WHERE id IN `+in, args...)
+	if err != nil {
+		return fmt.Errorf("failed to move task %d: %w", id, err)
+	}
+	// Custom fields belong to the project the tasks leave.
+	if _, err := tx.Exec("DELETE FROM task_field_values WHERE taskID IN "+in, int64Args(ids)...); err != nil {
+		return fmt.Errorf("failed to clear custom fields of task %d: %w", id, err)
+	}
+	// So do planned and active sprints; completed ones keep their history.
+	if !sameProject {
+		args := append(int64Args(ids), SprintCompleted)
+		_, err := tx.Exec(`DELETE st FROM sprint_tasks st JOIN sprints sp ON sp.id = st.sprintID
+			WHERE st.taskID IN `+in+` AND sp.status != ?`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to remove task %d from sprints: %w", id, err)
+		}
+	}
+
+	// The moved tasks go to the bottom of their columns on the board.
+	var toKey any
+	if projectID != nil {
+		for _, taskID := range ids {
+			number, key, err := allocateTaskNumber(tx, *projectID)
+			if err != nil {
+				return err
+			}
+			boardRank, err := appendRank(tx, *projectID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE tasks SET number = ?, boardRank = ? WHERE id = ?", number, boardRank, taskID); err != nil {
+				return fmt.Errorf("failed to number task %d: %w", taskID, err)
+			}
+			if taskID == id {
+				toKey = key
+			}
+		}
+	}
+
+	args = append(int64Args(ids), workspaceID)
+	_, err = tx.Exec(`DELETE tl FROM task_labels tl JOIN labels l ON l.id = tl.labelID
+		WHERE tl.taskID IN `+in+` AND l.workspaceID != ?`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove labels of task %d: %w", id, err)
+	}
+
+	var from, fromProject any
+	if fromWorkspaceID.Valid {
+		from = fromWorkspaceID.Int64
+	}
+	if fromProjectID.Valid {
+		fromProject = fromProjectID.Int64
+	}
+	details := map[string]any{
+		"workspace_id": map[string]any{"from": from, "to": workspaceID},
+		"project_id":   map[string]any{"from": fromProject, "to": projectID},
+		"key":          map[string]any{"from": fromKey, "to": toKey},
+		"task_ids":     ids,
+	}
+	if err := recordActivity(tx, id, actorID, ActivityTaskMoved, details); err != nil {
+		return err
+	}
+	return mapStatuses(tx, ids, statusMap, actorID)
+}
+
+// checkMoveWIPLimits returns ErrWIPLimit when the columns of the project
+// board have no room for the tasks moving in, in the statuses statusMap
+// gives them.
+func checkMoveWIPLimits(tx *sql.Tx, projectID int64, ids []int64, statusMap map[string]string) error {
+	rows, err := tx.Query(`SELECT status, COUNT(*) FROM tasks
+		WHERE id IN (`+placeholders(len(ids))+`) AND archivedAt IS NULL GROUP BY status`, int64Args(ids)...)
+	if err != nil {
+		return fmt.Errorf("failed to get statuses of moved tasks: %w", err)
+	}
+	incoming := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan status row: %w", err)
+		}
+		if to, ok := statusMap[status]; ok {
+			status = to
+		}
+		incoming[status] += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	statuses := make([]string, 0, len(incoming))
+	for status := range incoming {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		if err := checkWIPLimit(tx, projectID, status, incoming[status]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapStatuses changes the status of each task that has a key of statusMap
+// to its value, for projects that use their board columns differently.
+// Every task changes at most once, so {"IN_TESTING": "DONE", "DONE":
+// "IN_TESTING"} swaps the two. Each change follows the rules of any other
+// status change, so a task with open subtasks does not become DONE.
+func mapStatuses(tx *sql.Tx, ids []int64, statusMap map[string]string, actorID int64) error {
+	if len(statusMap) == 0 {
+		return nil
+	}
+	rows, err := tx.Query("SELECT "+taskColumns+" FROM tasks t WHERE t.id IN ("+placeholders(len(ids))+") ORDER BY t.id", int64Args(ids)...)
+	if err != nil {
+		return fmt.Errorf("failed to get moved tasks: %w", err)
+	}
+	tasks, err := scanTasks(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, t := range tasks {
+		to, ok := statusMap[t.Status]
+		if !ok || to == t.Status {
+			continue
+		}
+		if err := checkStatusChange(tx, t, to, StatusUpdate{ActorID: actorID}); err != nil {
+			return err
+		}
+		var doneAt any
+		if to == "DONE" {
+			doneAt = now
+		}
+		if _, err := tx.Exec("UPDATE tasks SET status = ?, doneAt = ? WHERE id = ?", to, doneAt, t.ID); err != nil {
+			return fmt.Errorf("failed to update status of task %d: %w", t.ID, err)
+		}
+		err := recordActivity(tx, t.ID, actorID, ActivityStatusChanged, map[string]any{"from": t.Status, "to": to})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
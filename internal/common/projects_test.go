@@ -0,0 +1,348 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateTaskTakesNextProjectNumber(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	workspaceID, projectID, number := int64(2), int64(4), int64(7)
+	task := &Task{Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID, ProjectID: &projectID}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT nextNumber, projectKey FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"nextNumber", "projectKey"}).AddRow(7, "API"))
+	mock.ExpectExec("UPDATE projects SET nextNumber = nextNumber \\+ 1 WHERE id = \\?").
+		WithArgs(projectID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT MAX\\(boardRank\\) FROM tasks WHERE projectID = \\?").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"MAX(boardRank)"}).AddRow("i00000"))
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Fix login", "", "TODO", "P2", nil, int64(1), &workspaceID, nil, 0, &projectID, &number, "i00100", nil).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(9), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	created, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "API-7", created.Key)
+	assert.Equal(t, number, *created.Number)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskByKeyFollowsRedirects(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	projectID, number := int64(5), int64(1)
+	task := &Task{ID: 9, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, ProjectID: &projectID,
+		Number: &number, Key: "WEB-1", CreatedAt: time.Now()}
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+JOIN \\(SELECT COALESCE(.+task_key_redirects.+)\\s+FROM projects p\\s+"+
+		"WHERE p.projectKey = \\? AND p.workspaceID IN \\(SELECT workspaceID FROM workspace_members WHERE userID = \\?\\)\\) k ON k.id = t.id").
+		WithArgs(int64(7), int64(7), "API", 3).
+		WillReturnRows(taskRows(task))
+
+	got, err := store.GetTaskByKey("API", 7, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEB-1", got.Key)
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+JOIN \\(SELECT COALESCE").
+		WithArgs(int64(8), int64(8), "API", 3).
+		WillReturnRows(taskRows())
+
+	_, err = store.GetTaskByKey("API", 8, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTaskByKeyInSeveralWorkspaces(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	first, second := int64(2), int64(6)
+
+	mock.ExpectQuery("SELECT (.+) FROM tasks t\\s+JOIN \\(SELECT COALESCE").
+		WithArgs(int64(7), int64(7), "API", 3).
+		WillReturnRows(taskRows(
+			&Task{ID: 9, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1, WorkspaceID: &first, CreatedAt: time.Now()},
+			&Task{ID: 14, Name: "Rate limits", Status: "TODO", Priority: "P2", AssignedToID: 3, WorkspaceID: &second, CreatedAt: time.Now()}))
+
+	_, err := store.GetTaskByKey("API", 7, 3)
+	assert.ErrorIs(t, err, ErrAmbiguousKey)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveTaskKeepsOldKeys(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	projectID := int64(5)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(nil, 2, 4, 7))
+	mock.ExpectQuery("SELECT workspaceID FROM projects WHERE id = \\?").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"workspaceID"}).AddRow(2))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(9), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectQuery("SELECT status, COUNT\\(\\*\\) FROM tasks\\s+WHERE id IN \\(\\?, \\?\\) AND archivedAt IS NULL GROUP BY status").
+		WithArgs(int64(9), int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "COUNT(*)"}).AddRow("TODO", 2))
+	mock.ExpectQuery("SELECT wipLimits FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"wipLimits"}).AddRow([]byte(`{"TODO": 5}`)))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks\\s+WHERE projectID = \\? AND status = \\?").
+		WithArgs(projectID, "TODO").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(3))
+	mock.ExpectQuery("SELECT projectKey FROM projects WHERE id = \\?").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"projectKey"}).AddRow("API"))
+	mock.ExpectExec("INSERT INTO task_key_redirects \\(projectID, number, taskID\\) SELECT projectID, number, id FROM tasks WHERE id IN \\(\\?, \\?\\) AND number IS NOT NULL").
+		WithArgs(int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE tasks SET workspaceID = \\?, projectID = \\?, number = NULL, boardRank = NULL, milestoneID = NULL WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(2), &projectID, int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM task_field_values WHERE taskID IN \\(\\?, \\?\\)").
+		WithArgs(int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE st FROM sprint_tasks st JOIN sprints sp ON sp.id = st.sprintID\\s+WHERE st.taskID IN \\(\\?, \\?\\) AND sp.status != \\?").
+		WithArgs(int64(9), int64(10), SprintCompleted).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	last := []any{nil, "i00000"}
+	ranks := []string{"i00000", "i00100"}
+	for i, id := range []int64{9, 10} {
+		mock.ExpectQuery("SELECT nextNumber, projectKey FROM projects WHERE id = \\? FOR UPDATE").
+			WithArgs(projectID).
+			WillReturnRows(sqlmock.NewRows([]string{"nextNumber", "projectKey"}).AddRow(i+1, "WEB"))
+		mock.ExpectExec("UPDATE projects SET nextNumber = nextNumber \\+ 1").
+			WithArgs(projectID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT MAX\\(boardRank\\) FROM tasks WHERE projectID = \\?").
+			WithArgs(projectID).
+			WillReturnRows(sqlmock.NewRows([]string{"MAX(boardRank)"}).AddRow(last[i]))
+		mock.ExpectExec("UPDATE tasks SET number = \\?, boardRank = \\? WHERE id = \\?").
+			WithArgs(int64(i+1), ranks[i], id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("DELETE tl FROM task_labels tl JOIN labels l").
+		WithArgs(int64(9), int64(10), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityTaskMoved,
+			[]byte(`{"key":{"from":"API-7","to":"WEB-1"},"project_id":{"from":4,"to":5},"task_ids":[9,10],"workspace_id":{"from":2,"to":2}}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(9).
+		WillReturnRows(taskRows(&Task{ID: 9, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1}))
+
+	_, err := store.MoveTask(9, 0, &projectID, nil, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveTaskStatusMapKeepsStatusRules(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	workspaceID, parentID := int64(3), int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(nil, 2, nil, nil))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(9), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectExec("INSERT INTO task_key_redirects").
+		WithArgs(int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE tasks SET workspaceID = \\?, projectID = \\?").
+		WithArgs(int64(3), nil, int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM task_field_values").
+		WithArgs(int64(9), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE tl FROM task_labels tl JOIN labels l").
+		WithArgs(int64(9), int64(10), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityTaskMoved, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN \\(\\?, \\?\\) ORDER BY t.id").
+		WithArgs(int64(9), int64(10)).
+		WillReturnRows(taskRows(
+			&Task{ID: 9, Name: "Release", Status: "TODO", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+				Progress: &Progress{Total: 1}, CreatedAt: time.Now()},
+			&Task{ID: 10, Name: "Changelog", Status: "IN_PROGRESS", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+				ParentID: &parentID, CreatedAt: time.Now()}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE parentID = \\? AND status != 'DONE'").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := store.MoveTask(9, 3, nil, map[string]string{"TODO": "DONE"}, 3)
+	assert.ErrorIs(t, err, ErrOpenSubtasks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveTaskRespectsWIPLimits(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	projectID := int64(5)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(nil, 2, nil, nil))
+	mock.ExpectQuery("SELECT workspaceID FROM projects WHERE id = \\?").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"workspaceID"}).AddRow(2))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(9), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectQuery("SELECT status, COUNT\\(\\*\\) FROM tasks").
+		WithArgs(int64(9), int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "COUNT(*)"}).AddRow("IN_TESTING", 1).AddRow("TODO", 1))
+	mock.ExpectQuery("SELECT wipLimits FROM projects WHERE id = \\? FOR UPDATE").
+		WithArgs(projectID).
+		WillReturnRows(sqlmock.NewRows([]string{"wipLimits"}).AddRow([]byte(`{"IN_PROGRESS": 2}`)))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks\\s+WHERE projectID = \\? AND status = \\?").
+		WithArgs(projectID, "IN_PROGRESS").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2))
+	mock.ExpectRollback()
+
+	_, err := store.MoveTask(9, 0, &projectID, map[string]string{"IN_TESTING": "IN_PROGRESS"}, 3)
+	assert.ErrorIs(t, err, ErrWIPLimit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveTaskLeavesUnfinishedSprints(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(nil, 2, 4, 7))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(9), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery("SELECT projectKey FROM projects WHERE id = \\?").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"projectKey"}).AddRow("API"))
+	mock.ExpectExec("INSERT INTO task_key_redirects").
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE tasks SET workspaceID = \\?, projectID = \\?").
+		WithArgs(int64(2), nil, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_field_values").
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE st FROM sprint_tasks st JOIN sprints sp ON sp.id = st.sprintID\\s+WHERE st.taskID IN \\(\\?\\) AND sp.status != \\?").
+		WithArgs(int64(9), SprintCompleted).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE tl FROM task_labels tl JOIN labels l").
+		WithArgs(int64(9), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityTaskMoved, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(9).
+		WillReturnRows(taskRows(&Task{ID: 9, Name: "Fix login", Status: "TODO", Priority: "P2", AssignedToID: 1}))
+
+	_, err := store.MoveTask(9, 2, nil, nil, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveTaskMapsStatuses(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	workspaceID, parentID, subtaskID := int64(3), int64(9), int64(10)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, workspaceID, projectID, number FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "workspaceID", "projectID", "number"}).AddRow(nil, 2, nil, nil))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(9), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10).AddRow(11))
+	mock.ExpectExec("INSERT INTO task_key_redirects").
+		WithArgs(int64(9), int64(10), int64(11)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE tasks SET workspaceID = \\?, projectID = \\?").
+		WithArgs(int64(3), nil, int64(9), int64(10), int64(11)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM task_field_values").
+		WithArgs(int64(9), int64(10), int64(11)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE tl FROM task_labels tl JOIN labels l").
+		WithArgs(int64(9), int64(10), int64(11), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityTaskMoved, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN \\(\\?, \\?, \\?\\) ORDER BY t.id").
+		WithArgs(int64(9), int64(10), int64(11)).
+		WillReturnRows(taskRows(
+			&Task{ID: 9, Name: "Release", Status: "IN_TESTING", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+				Progress: &Progress{Done: 1, Total: 1, Percent: 100}, CreatedAt: time.Now()},
+			&Task{ID: 10, Name: "Changelog", Status: "DONE", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+				ParentID: &parentID, CreatedAt: time.Now()},
+			&Task{ID: 11, Name: "Wording", Status: "TODO", Priority: "P2", AssignedToID: 1, WorkspaceID: &workspaceID,
+				ParentID: &subtaskID, CreatedAt: time.Now()}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE parentID = \\? AND status != 'DONE'").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\? WHERE id = \\?").
+		WithArgs("DONE", sqlmock.AnyArg(), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(9), int64(3), ActivityStatusChanged, []byte(`{"from":"IN_TESTING","to":"DONE"}`)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\? WHERE id = \\?").
+		WithArgs("IN_TESTING", nil, int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(10), int64(3), ActivityStatusChanged, []byte(`{"from":"DONE","to":"IN_TESTING"}`)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(9).
+		WillReturnRows(taskRows(&Task{ID: 9, Name: "Fix login", Status: "DONE", Priority: "P2", AssignedToID: 1}))
+
+	_, err := store.MoveTask(9, 3, nil, map[string]string{"IN_TESTING": "DONE", "DONE": "IN_TESTING"}, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
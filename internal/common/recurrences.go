@@ -0,0 +1,186 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/recur"
+	"time"
+)
+
+const recurrenceColumns = `r.id, r.taskID, r.rule, r.startAt, r.name, COALESCE(r.description, ''), r.priority,
+	r.assignedToID, r.workspaceID, r.createdAt`
+
+// SetTaskRecurrence makes the task recurring, or replaces its rule. The
+// task's current fields become the template of later occurrences.
+func (s *Storage) SetTaskRecurrence(rec *Recurrence, actorID int) (*Recurrence, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var fromRule *string
+		var fromStartAt *time.Time
+		err := tx.QueryRow("SELECT rule, startAt FROM task_recurrences WHERE taskID = ? FOR UPDATE", rec.TaskID).
+			Scan(&fromRule, &fromStartAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get recurrence of task %d: %w", rec.TaskID, err)
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO task_recurrences (taskID, rule, startAt, name, description, priority, assignedToID, workspaceID)
+			SELECT id, ?, ?, name, description, priority, assignedToID, workspaceID FROM tasks WHERE id = ?
+			ON DUPLICATE KEY UPDATE rule = VALUES(rule), startAt = VALUES(startAt), name = VALUES(name),
+				description = VALUES(description), priority = VALUES(priority),
+				assignedToID = VALUES(assignedToID), workspaceID = VALUES(workspaceID)`,
+			rec.Rule, rec.StartAt, rec.TaskID)
+		if err != nil {
+			return fmt.Errorf("failed to set recurrence of task %d: %w", rec.TaskID, err)
+		}
+		// Nothing is inserted for a missing task; GetTaskRecurrence then
+		// reports it.
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return recordActivity(tx, rec.TaskID, int64(actorID), ActivityRecurrenceSet, map[string]any{
+			"rule":     map[string]any{"from": fromRule, "to": rec.Rule},
+			"start_at": map[string]any{"from": fromStartAt, "to": rec.StartAt},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTaskRecurrence(int(rec.TaskID))
+}
+
+// GetTaskRecurrence returns the recurrence whose current occurrence is the
+// task.
+func (s *Storage) GetTaskRecurrence(taskID int) (*Recurrence, error) {
+	rec, err := scanRecurrence(s.db.QueryRow("SELECT "+recurrenceColumns+" FROM task_recurrences r WHERE r.taskID = ?", taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurrence of task %d: %w", taskID, err)
+	}
+	return rec, nil
+}
+
+func (s *Storage) DeleteTaskRecurrence(taskID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var rule string
+		err := tx.QueryRow("SELECT rule FROM task_recurrences WHERE taskID = ? FOR UPDATE", taskID).Scan(&rule)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get recurrence of task %d: %w", taskID, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM task_recurrences WHERE taskID = ?", taskID); err != nil {
+			return fmt.Errorf("failed to delete recurrence of task %d: %w", taskID, err)
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivityRecurrenceRemoved, map[string]any{"rule": rule})
+	})
+}
+
+// AdvanceRecurrences creates the next occurrence of up to limit recurrences
+// whose current task is DONE or past due at now, and returns the created
+// tasks. Occurrences that were missed while a task stayed open are skipped
+// rather than created in bulk. Series that COUNT or UNTIL have ended are
+// removed.
+//
+// The recurrences are locked and moved to their new task in one
+// transaction, and rows locked by another server are skipped, so every
+// occurrence is created exactly once no matter how many schedulers run.
+func (s *Storage) AdvanceRecurrences(now time.Time, limit int) ([]*Task, error) {
+	var created []*Task
+	err := s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT `+recurrenceColumns+`, t.dueAt, t.projectID
+			FROM task_recurrences r JOIN tasks t ON t.id = r.taskID
+			WHERE t.deletedAt IS NULL AND (t.status = 'DONE' OR t.dueAt <= ?)
+			ORDER BY r.id LIMIT ?
+			FOR UPDATE SKIP LOCKED`, now, limit)
+		if err != nil {
+			return fmt.Errorf("failed to get due recurrences: %w", err)
+		}
+
+		type due struct {
+			rec       *Recurrence
+			dueAt     sql.NullTime
+			projectID sql.NullInt64
+		}
+		var pending []due
+		for rows.Next() {
+			var d due
+			d.rec, err = scanRecurrence(rows, &d.dueAt, &d.projectID)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan recurrence row: %w", err)
+			}
+			pending = append(pending, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over rows: %w", err)
+		}
+
+		for _, d := range pending {
+			after := now
+			if d.dueAt.Valid && d.dueAt.Time.After(now) {
+				after = d.dueAt.Time
+			}
+
+			rule, err := recur.Parse(d.rec.Rule)
+			if err != nil {
+				return fmt.Errorf("recurrence %d: %w", d.rec.ID, err)
+			}
+			next, ok := rule.Next(d.rec.StartAt, after)
+			if !ok {
+				if _, err := tx.Exec("DELETE FROM task_recurrences WHERE id = ?", d.rec.ID); err != nil {
+					return fmt.Errorf("failed to end recurrence %d: %w", d.rec.ID, err)
+				}
+				continue
+			}
+
+			task := &Task{
+				Name:         d.rec.Name,
+				Description:  d.rec.Description,
+				Status:       "TODO",
+				Priority:     d.rec.Priority,
+				DueAt:        &next,
+				AssignedToID: d.rec.AssignedToID,
+				WorkspaceID:  d.rec.WorkspaceID,
+			}
+			// The next occurrence stays in the project of the current one.
+			if d.projectID.Valid {
+				task.ProjectID = &d.projectID.Int64
+			}
+			if err := insertTask(tx, task); err != nil {
+				return err
+			}
+			err = recordAutomaticActivity(tx, task.ID, ActivityTaskCreated, map[string]any{"recurrence_id": d.rec.ID})
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE task_recurrences SET taskID = ? WHERE id = ?", task.ID, d.rec.ID); err != nil {
+				return fmt.Errorf("failed to advance recurrence %d: %w", d.rec.ID, err)
+			}
+			created = append(created, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexTasks(created)
+	return created, nil
+}
+
+func scanRecurrence(row rowScanner, extra ...any) (*Recurrence, error) {
+	rec := &Recurrence{}
+	dest := []any{&rec.ID, &rec.TaskID, &rec.Rule, &rec.StartAt, &rec.Name, &rec.Description, &rec.Priority,
+		&rec.AssignedToID, &rec.WorkspaceID, &rec.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var recurrenceColumnNames = []string{"id", "taskID", "rule", "startAt", "name", "description", "priority",
+	"assignedToID", "workspaceID", "createdAt", "dueAt", "projectID"}
+
+func TestAdvanceRecurrencesCreatesNextOccurrence(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	next := time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM task_recurrences r JOIN tasks t (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows(recurrenceColumnNames).
+			AddRow(3, 7, "FREQ=WEEKLY", start, "Weekly report", "", "P1", 1, nil, start, start, nil))
+	task := &Task{Name: "Weekly report", Status: "TODO", Priority: "P1", DueAt: &next, AssignedToID: 1}
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(8), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(8), nil, ActivityTaskCreated, []byte(`{"recurrence_id":3}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE task_recurrences SET taskID = \\? WHERE id = ?").
+		WithArgs(int64(8), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO task_search").
+		WithArgs(int64(8), "Weekly report", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	created, err := store.AdvanceRecurrences(now, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, created, 1) {
+		assert.Equal(t, int64(8), created[0].ID)
+		assert.Equal(t, next, *created[0].DueAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvanceRecurrencesEndsSeries(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM task_recurrences r JOIN tasks t").
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows(recurrenceColumnNames).
+			AddRow(3, 7, "FREQ=DAILY;COUNT=2", start, "Audit", "", "P2", 1, nil, start, start.AddDate(0, 0, 1), nil))
+	mock.ExpectExec("DELETE FROM task_recurrences WHERE id = ?").
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	created, err := store.AdvanceRecurrences(now, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTaskRecurrenceRecordsRule(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rule FROM task_recurrences WHERE taskID = \\? FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"rule"}).AddRow("FREQ=WEEKLY;BYDAY=MO"))
+	mock.ExpectExec("DELETE FROM task_recurrences WHERE taskID = ?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityRecurrenceRemoved, []byte(`{"rule":"FREQ=WEEKLY;BYDAY=MO"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.DeleteTaskRecurrence(1, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
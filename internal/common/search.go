@@ -0,0 +1,182 @@
+package common
+
+import (
+	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"log"
+	"strings"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchOverfetch is how many more hits than asked for SearchTasks reads
+// from the index at a time, since hits the user cannot see are dropped.
+const searchOverfetch = 4
+
+// searchRounds is how many times SearchTasks asks the index for hits before
+// it settles for fewer results than the limit.
+const searchRounds = 3
+
+func taskDocument(task *Task, comments []string) search.Document {
+	return search.Document{
+		TaskID:      task.ID,
+		Name:        task.Name,
+		Description: task.Description,
+		Comments:    comments,
+	}
+}
+
+// reindexTask rebuilds the task's search document from its current name,
+// description and comments.
+func (s *Storage) reindexTask(taskID int64) error {
+	task, err := s.GetTask(int(taskID))
+	if err != nil {
+		return fmt.Errorf("failed to reindex task %d: %w", taskID, err)
+	}
+
+	rows, err := s.db.Query("SELECT body FROM comments WHERE taskID = ? AND deletedAt IS NULL ORDER BY id", taskID)
+	if err != nil {
+		return fmt.Errorf("failed to reindex task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	var comments []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, body)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return s.index.Index(taskDocument(task, comments))
+}
+
+// reindexTasks rebuilds the search documents of the tasks. It runs once
+// their change is committed, so failures are logged instead of failing the
+// change; a document that is behind catches up on the task's next edit.
+func (s *Storage) reindexTasks(ids ...int64) {
+	for _, id := range ids {
+		if err := s.reindexTask(id); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// indexTasks adds the search documents of newly created tasks, logging
+// failures like reindexTasks.
+func (s *Storage) indexTasks(tasks []*Task) {
+	for _, task := range tasks {
+		if err := s.index.Index(taskDocument(task, nil)); err != nil {
+			log.Printf("failed to index task %d: %v", task.ID, err)
+		}
+	}
+}
+
+// SearchTasks returns the best matches of the query among the tasks the
+// user can see, at most limit of them and never more than maxSearchLimit.
+func (s *Storage) SearchTasks(query string, limit, userID int) ([]*SearchResult, error) {
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	// The index knows nothing about workspaces, so it is asked for more
+	// hits until enough of them are visible, it runs out or searchRounds
+	// is reached.
+	results := []*SearchResult{}
+	n := limit * searchOverfetch
+	for round := 1; ; round++ {
+		hits, err := s.index.Search(q, n)
+		if err != nil {
+			return nil, err
+		}
+		if results, err = s.visibleResults(hits, userID); err != nil {
+			return nil, err
+		}
+		if len(results) >= limit || len(hits) < n || round == searchRounds {
+			break
+		}
+		n *= searchOverfetch
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// visibleResults pairs the hits with their tasks, in the order of the hits,
+// dropping those the user cannot see.
+func (s *Storage) visibleResults(hits []search.Hit, userID int) ([]*SearchResult, error) {
+	if len(hits) == 0 {
+		return []*SearchResult{}, nil
+	}
+
+	ids := make([]int64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.TaskID
+	}
+	query := "SELECT " + taskColumns + " FROM tasks t WHERE t.id IN (" + placeholders(len(ids)) + ") AND t.deletedAt IS NULL AND " + visibleTaskScope
+	tasks, err := s.tasksByID(query, append(int64Args(ids), userID, userID, userID)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index may briefly lag behind the tasks table, hits without a
+	// task row are dropped.
+	results := make([]*SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		task, ok := tasks[hit.TaskID]
+		if !ok {
+			continue
+		}
+		results = append(results, &SearchResult{Task: task, Score: hit.Score, Highlights: hit.Highlights})
+	}
+	return results, nil
+}
+
+func (s *Storage) getTasksByIDs(ids []int64) (map[int64]*Task, error) {
+	query := "SELECT " + taskColumns + " FROM tasks t WHERE t.id IN (" + placeholders(len(ids)) + ") AND t.deletedAt IS NULL"
+	return s.tasksByID(query, int64Args(ids)...)
+}
+
+func (s *Storage) tasksByID(query string, args ...any) (map[int64]*Task, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	return byID, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func int64Args(ids []int64) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSearchTasksKeepsIndexInSync(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	task := &Task{Name: "Fix login page", Description: "Users cannot log in", Status: "TODO", Priority: "P2", AssignedToID: 1}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+
+	task.CreatedAt = time.Now()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN \\(\\?\\) AND t.deletedAt IS NULL AND \\(t.workspaceID IN").
+		WithArgs(int64(7), 1, 1, 1).
+		WillReturnRows(taskRows(task))
+
+	results, err := store.SearchTasks("login", 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(7), results[0].Task.ID)
+	assert.Equal(t, "name", results[0].Highlights[0].Field)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchTasksWithoutHits(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+
+	results, err := store.SearchTasks("nothing", 10, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = store.SearchTasks("   ", 10, 1)
+	assert.ErrorIs(t, err, search.ErrEmptyQuery)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchTasksDropsTasksTheUserCannotSee(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	index := search.NewMemoryIndex()
+	store := NewStoreWithIndex(db, index)
+	assert.NoError(t, index.Index(search.Document{TaskID: 7, Name: "Login page of the other workspace"}))
+	assert.NoError(t, index.Index(search.Document{TaskID: 8, Name: "Fix login page"}))
+
+	visible := &Task{ID: 8, Name: "Fix login page", Status: "TODO", Priority: "P2", AssignedToID: 3, CreatedAt: time.Now()}
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN (.+) AND \\(t.workspaceID IN").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3, 3, 3).
+		WillReturnRows(taskRows(visible))
+
+	results, err := store.SearchTasks("login", 1, 3)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(8), results[0].Task.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchTasksStopsAfterSearchRounds(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	index := search.NewMemoryIndex()
+	store := NewStoreWithIndex(db, index)
+	for id := int64(1); id <= 100; id++ {
+		assert.NoError(t, index.Index(search.Document{TaskID: id, Name: "Login page of the other workspace"}))
+	}
+
+	// None of the hits is visible, so every round asks for more of them.
+	for range searchRounds {
+		mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id IN (.+) AND \\(t.workspaceID IN").
+			WillReturnRows(taskRows())
+	}
+
+	results, err := store.SearchTasks("login", 1, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// failingIndex is an index that is down.
+type failingIndex struct{}
+
+func (failingIndex) Index(search.Document) error {
+	return errors.New("index is down")
+}
+
+func (failingIndex) Remove(int64) error {
+	return errors.New("index is down")
+}
+
+func (failingIndex) Search(search.Query, int) ([]search.Hit, error) {
+	return nil, errors.New("index is down")
+}
+
+func TestCreateTaskSucceedsWhenIndexingFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, failingIndex{})
+
+	task := &Task{Name: "Fix login page", Status: "TODO", Priority: "P2", AssignedToID: 1}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	created, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), created.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
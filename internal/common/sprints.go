@@ -0,0 +1,336 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const sprintColumns = "id, projectID, name, COALESCE(goal, ''), startDate, endDate, status, startedAt, completedAt, createdAt"
+
+func scanSprint(row rowScanner) (*Sprint, error) {
+	var sp Sprint
+	var startDate, endDate time.Time
+	var startedAt, completedAt sql.NullTime
+	err := row.Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Goal, &startDate, &endDate, &sp.Status,
+		&startedAt, &completedAt, &sp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	sp.StartDate = startDate.Format(time.DateOnly)
+	sp.EndDate = endDate.Format(time.DateOnly)
+	if startedAt.Valid {
+		sp.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		sp.CompletedAt = &completedAt.Time
+	}
+	return &sp, nil
+}
+
+func (s *Storage) CreateSprint(sp *Sprint) (*Sprint, error) {
+	res, err := s.db.Exec(`INSERT INTO sprints (projectID, name, goal, startDate, endDate, status)
+		VALUES (?, ?, ?, ?, ?, ?)`, sp.ProjectID, sp.Name, sp.Goal, sp.StartDate, sp.EndDate, SprintPlanned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sprint: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSprint(int(id))
+}
+
+func (s *Storage) GetSprint(id int) (*Sprint, error) {
+	sp, err := scanSprint(s.db.QueryRow("SELECT "+sprintColumns+" FROM sprints WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sprint %d: %w", id, err)
+	}
+	return sp, nil
+}
+
+// GetSprints returns the sprints of the project, the most recent first.
+func (s *Storage) GetSprints(projectID int) ([]*Sprint, error) {
+	rows, err := s.db.Query("SELECT "+sprintColumns+" FROM sprints WHERE projectID = ? ORDER BY startDate DESC, id DESC", projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sprints of project %d: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	sprints := []*Sprint{}
+	for rows.Next() {
+		sp, err := scanSprint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sprint row: %w", err)
+		}
+		sprints = append(sprints, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return sprints, nil
+}
+
+// UpdateSprint saves the name, goal and dates of the sprint.
+func (s *Storage) UpdateSprint(sp *Sprint) error {
+	_, err := s.db.Exec("UPDATE sprints SET name = ?, goal = ?, startDate = ?, endDate = ? WHERE id = ?",
+		sp.Name, sp.Goal, sp.StartDate, sp.EndDate, sp.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update sprint %d: %w", sp.ID, err)
+	}
+	return nil
+}
+
+// GetSprintTasks returns the live tasks of the sprint. A completed sprint
+// keeps the tasks it had, including those carried over to another one.
+func (s *Storage) GetSprintTasks(sprintID int) ([]*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		JOIN sprint_tasks st ON st.taskID = t.id
+		WHERE st.sprintID = ? AND t.deletedAt IS NULL
+		ORDER BY t.boardRank, t.id`, sprintID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks of sprint %d: %w", sprintID, err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
+
+// GetBacklog returns the unfinished tasks of the project that are in no
+// planned or active sprint.
+func (s *Storage) GetBacklog(projectID int) ([]*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		WHERE t.projectID = ? AND t.status != 'DONE' AND t.deletedAt IS NULL AND t.archivedAt IS NULL
+		AND NOT EXISTS (SELECT 1 FROM sprint_tasks st JOIN sprints sp ON sp.id = st.sprintID
+			WHERE st.taskID = t.id AND sp.status != ?)
+		ORDER BY t.boardRank, t.id`, projectID, SprintCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlog of project %d: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
+
+// AddSprintTask adds the task to a planned or active sprint. Adding a task
+// to an active sprint fails with ErrInActiveSprint while the task is in
+// another active sprint.
+func (s *Storage) AddSprintTask(sprintID, taskID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if err := lockTask(tx, int64(taskID)); err != nil {
+			return err
+		}
+		status, err := lockSprint(tx, int64(sprintID))
+		if err != nil {
+			return err
+		}
+		if status == SprintCompleted {
+			return ErrSprintState
+		}
+		if status == SprintActive {
+			if err := checkActiveSprints(tx, int64(sprintID), []int64{int64(taskID)}); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.Exec("INSERT IGNORE INTO sprint_tasks (sprintID, taskID) VALUES (?, ?)", sprintID, taskID)
+		if err != nil {
+			return fmt.Errorf("failed to add task %d to sprint %d: %w", taskID, sprintID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivitySprintAdded, map[string]any{"sprint_id": sprintID})
+	})
+}
+
+// RemoveSprintTask takes the task out of a planned or active sprint.
+func (s *Storage) RemoveSprintTask(sprintID, taskID, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		status, err := lockSprint(tx, int64(sprintID))
+		if err != nil {
+			return err
+		}
+		if status == SprintCompleted {
+			return ErrSprintState
+		}
+
+		res, err := tx.Exec("DELETE FROM sprint_tasks WHERE sprintID = ? AND taskID = ?", sprintID, taskID)
+		if err != nil {
+			return fmt.Errorf("failed to remove task %d from sprint %d: %w", taskID, sprintID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return recordActivity(tx, int64(taskID), int64(actorID), ActivitySprintRemoved, map[string]any{"sprint_id": sprintID})
+	})
+}
+
+// StartSprint makes a planned sprint active. It fails with
+// ErrInActiveSprint while one of its tasks is in another active sprint.
+func (s *Storage) StartSprint(id int, now time.Time) (*Sprint, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		status, err := lockSprint(tx, int64(id))
+		if err != nil {
+			return err
+		}
+		if status != SprintPlanned {
+			return ErrSprintState
+		}
+
+		ids, err := sprintTaskIDs(tx, int64(id), "")
+		if err != nil {
+			return err
+		}
+		if err := checkActiveSprints(tx, int64(id), ids); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE sprints SET status = ?, startedAt = ? WHERE id = ?", SprintActive, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to start sprint %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSprint(id)
+}
+
+// CompleteSprint completes an active sprint and returns the ids of its
+// unfinished tasks. They are carried over to the sprint nextSprintID, a
+// planned or active sprint of the same project, or go back to the backlog
+// when nextSprintID is nil. The completed sprint keeps all of its tasks.
+func (s *Storage) CompleteSprint(id int, nextSprintID *int64, actorID int, now time.Time) ([]int64, error) {
+	var unfinished []int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		status, err := lockSprint(tx, int64(id))
+		if err != nil {
+			return err
+		}
+		if status != SprintActive {
+			return ErrSprintState
+		}
+
+		unfinished, err = sprintTaskIDs(tx, int64(id), "DONE")
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE sprints SET status = ?, completedAt = ? WHERE id = ?", SprintCompleted, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to complete sprint %d: %w", id, err)
+		}
+		if nextSprintID == nil || len(unfinished) == 0 {
+			return nil
+		}
+
+		status, err = lockSprint(tx, *nextSprintID)
+		if err != nil {
+			return err
+		}
+		if status == SprintCompleted {
+			return ErrSprintState
+		}
+		for _, taskID := range unfinished {
+			res, err := tx.Exec("INSERT IGNORE INTO sprint_tasks (sprintID, taskID) VALUES (?, ?)", *nextSprintID, taskID)
+			if err != nil {
+				return fmt.Errorf("failed to carry task %d over to sprint %d: %w", taskID, *nextSprintID, err)
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+			details := map[string]any{"sprint_id": *nextSprintID, "from_sprint_id": id}
+			if err := recordActivity(tx, taskID, int64(actorID), ActivitySprintAdded, details); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unfinished == nil {
+		unfinished = []int64{}
+	}
+	return unfinished, nil
+}
+
+// lockSprint locks the sprint row until the transaction ends and returns
+// its status.
+func lockSprint(tx *sql.Tx, id int64) (string, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM sprints WHERE id = ? FOR UPDATE", id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get sprint %d: %w", id, err)
+	}
+	return status, nil
+}
+
+// sprintTaskIDs returns the live tasks of the sprint, leaving out those
+// with the status except, and locks them.
+func sprintTaskIDs(tx *sql.Tx, sprintID int64, except string) ([]int64, error) {
+	rows, err := tx.Query(`SELECT t.id FROM tasks t JOIN sprint_tasks st ON st.taskID = t.id
+		WHERE st.sprintID = ? AND t.status != ? AND t.deletedAt IS NULL
+		ORDER BY t.id FOR UPDATE`, sprintID, except)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks of sprint %d: %w", sprintID, err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return ids, nil
+}
+
+// checkActiveSprints returns ErrInActiveSprint when one of the tasks is in
+// an active sprint other than sprintID. The callers hold the locks on the
+// tasks, so no other sprint takes them in the meantime.
+func checkActiveSprints(tx *sql.Tx, sprintID int64, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := append([]any{SprintActive, sprintID}, int64Args(ids)...)
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sprint_tasks st JOIN sprints sp ON sp.id = st.sprintID
+		WHERE sp.status = ? AND sp.id != ? AND st.taskID IN (`+placeholders(len(ids))+`)`, args...).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("failed to check sprints of tasks: %w", err)
+	}
+	if n > 0 {
+		return ErrInActiveSprint
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStartSprintWithTaskInAnotherActiveSprint(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM sprints WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(SprintPlanned))
+	mock.ExpectQuery("SELECT t.id FROM tasks t JOIN sprint_tasks st (.+) FOR UPDATE").
+		WithArgs(int64(5), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM sprint_tasks st JOIN sprints sp (.+) IN \\(\\?, \\?\\)").
+		WithArgs(SprintActive, int64(5), int64(9), int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := store.StartSprint(5, time.Now())
+	assert.ErrorIs(t, err, ErrInActiveSprint)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCompleteSprintCarriesOverUnfinishedTasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+	next := int64(6)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM sprints WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(SprintActive))
+	mock.ExpectQuery("SELECT t.id FROM tasks t JOIN sprint_tasks st (.+) FOR UPDATE").
+		WithArgs(int64(5), "DONE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("UPDATE sprints SET status = \\?, completedAt = \\? WHERE id = \\?").
+		WithArgs(SprintCompleted, now, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT status FROM sprints WHERE id = \\? FOR UPDATE").
+		WithArgs(next).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(SprintPlanned))
+	mock.ExpectExec("INSERT IGNORE INTO sprint_tasks \\(sprintID, taskID\\) VALUES \\(\\?, \\?\\)").
+		WithArgs(next, int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(10), int64(3), ActivitySprintAdded, []byte(`{"from_sprint_id":5,"sprint_id":6}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	carried, err := store.CompleteSprint(5, &next, 3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{10}, carried)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTaskToCompletedSprint(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery("SELECT status FROM sprints WHERE id = \\? FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(SprintCompleted))
+	mock.ExpectRollback()
+
+	err := store.AddSprintTask(5, 9, 3)
+	assert.ErrorIs(t, err, ErrSprintState)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,7 +2,12 @@ package common
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"slices"
 	"time"
 )
 
@@ -12,21 +17,442 @@ type Store interface {
 
 	GetUserByID(id int) (*User, error)
 
-	CreateTask(task *Task) (*Task, error)
+	CreateTask(task *Task, actorID int) (*Task, error)
 
 	GetTask(id int) (*Task, error)
 
-	UpdateTaskStatusByID(id int) (*Task, error)
+	UpdateTaskStatusByID(id int, opts StatusUpdate) (*Task, error)
+
+	UpdateTask(id int, patch TaskPatch, actorID int) (*Task, error)
 
 	GetTasksAssignedToUser(id int) ([]*Task, error)
+
+	QueryTasks(q *query.Query, userID int) ([]*Task, error)
+
+	ApplyBulk(action BulkAction, ids []int64, atomic bool) ([]error, error)
+
+	// Trash
+	DeleteTask(id, actorID int) error
+
+	GetTrashedTask(id int) (*Task, error)
+
+	RestoreTask(id, actorID int) (*Task, error)
+
+	GetTrash(userID int) ([]*Task, error)
+
+	PurgeTrash(before time.Time, limit int) (int, []string, error)
+
+	// Archive
+	ArchiveTask(id, actorID int) (*Task, error)
+
+	UnarchiveTask(id, actorID int) (*Task, error)
+
+	AutoArchiveTasks(now time.Time) (int64, error)
+
+	ExportArchive(workspaceID int, fn func(*Task) error) error
+
+	ExportProjectArchive(projectID int, fn func(*Task) error) error
+
+	PurgeArchive(projectID int) (int, []string, error)
+
+	// Projects
+	CreateProject(p *Project) (*Project, error)
+
+	GetProject(id int) (*Project, error)
+
+	GetProjects(workspaceID int) ([]*Project, error)
+
+	UpdateProject(p *Project) error
+
+	GetTaskByKey(projectKey string, number int64, userID int) (*Task, error)
+
+	MoveTask(id int, workspaceID int64, projectID *int64, statusMap map[string]string, actorID int) (*Task, error)
+
+	// Boards
+	GetBoard(projectID int) ([]*Task, error)
+
+	MoveOnBoard(id int, move BoardMove) (*Task, error)
+
+	// Milestones
+	CreateMilestone(m *Milestone) (*Milestone, error)
+
+	GetMilestone(id int) (*Milestone, error)
+
+	GetMilestones(projectID int) ([]*Milestone, error)
+
+	UpdateMilestone(m *Milestone) error
+
+	GetMilestoneProgress(id int, now time.Time) (*MilestoneProgress, error)
+
+	SetTaskMilestone(taskID int, milestoneID *int64, actorID int) error
+
+	CloseMilestone(id int, now time.Time) (*Milestone, []ReleaseNoteGroup, error)
+
+	// Custom fields
+	CreateCustomField(f *CustomField) (*CustomField, error)
+
+	GetCustomField(id int) (*CustomField, error)
+
+	GetCustomFields(projectID int) ([]*CustomField, error)
+
+	UpdateCustomField(f *CustomField) error
+
+	DeleteCustomField(id int) error
+
+	// Sprints
+	CreateSprint(sp *Sprint) (*Sprint, error)
+
+	GetSprint(id int) (*Sprint, error)
+
+	GetSprints(projectID int) ([]*Sprint, error)
+
+	UpdateSprint(sp *Sprint) error
+
+	GetSprintTasks(sprintID int) ([]*Task, error)
+
+	GetBacklog(projectID int) ([]*Task, error)
+
+	AddSprintTask(sprintID, taskID, actorID int) error
+
+	RemoveSprintTask(sprintID, taskID, actorID int) error
+
+	StartSprint(id int, now time.Time) (*Sprint, error)
+
+	CompleteSprint(id int, nextSprintID *int64, actorID int, now time.Time) ([]int64, error)
+
+	// Subtasks
+	GetSubtasks(parentID int) ([]*Task, error)
+
+	SetTaskParent(id int, parentID *int64, actorID int) error
+
+	ReorderSubtasks(parentID int, ids []int64) error
+
+	// Templates
+	CreateTemplate(t *Template) (*Template, error)
+
+	GetTemplate(id int) (*Template, error)
+
+	GetTemplates(workspaceID int) ([]*Template, error)
+
+	UpdateTemplate(t *Template) error
+
+	DeleteTemplate(id int) error
+
+	CreateTaskTree(tree *TaskTree, actorID int) (*Task, error)
+
+	// Cloning and merging
+	CloneTask(id int, opts CloneOptions) (*Task, error)
+
+	MergeTask(id, targetID, actorID int) (*Task, error)
+
+	// Dependencies
+	AddTaskDependency(blockerID, blockedID, actorID int) error
+
+	RemoveTaskDependency(blockerID, blockedID, actorID int) error
+
+	GetTaskDependencies(taskID int) (*DependencyGraph, error)
+
+	// Assignees and watchers
+	AddTaskAssignee(taskID, userID, actorID int) error
+
+	RemoveTaskAssignee(taskID, userID, actorID int) error
+
+	GetTaskWatchers(taskID int) ([]int64, error)
+
+	AddTaskWatcher(taskID, userID, actorID int) error
+
+	RemoveTaskWatcher(taskID, userID, actorID int) error
+
+	// Recurrences
+	SetTaskRecurrence(rec *Recurrence, actorID int) (*Recurrence, error)
+
+	GetTaskRecurrence(taskID int) (*Recurrence, error)
+
+	DeleteTaskRecurrence(taskID, actorID int) error
+
+	AdvanceRecurrences(now time.Time, limit int) ([]*Task, error)
+
+	// Checklists
+	GetChecklist(taskID int) ([]*ChecklistItem, error)
+
+	GetChecklistItem(id int) (*ChecklistItem, error)
+
+	AddChecklistItem(item *ChecklistItem, actorID int) (*ChecklistItem, error)
+
+	UpdateChecklistItem(item *ChecklistItem, actorID int) error
+
+	ReorderChecklist(taskID int, ids []int64, actorID int) error
+
+	DeleteChecklistItem(id, actorID int) error
+
+	// Jobs and reminders
+	PlanJobs(now time.Time) error
+
+	RunDueJobs(now time.Time, limit int) (int, error)
+
+	GetReminderSettings(userID int) ([]time.Duration, error)
+
+	SetReminderSettings(userID int, before []time.Duration) error
+
+	// Time tracking
+	StartTimer(taskID, userID int, now time.Time) (*Worklog, error)
+
+	StopTimer(userID int, now time.Time) (*Worklog, error)
+
+	GetRunningTimer(userID int) (*Worklog, error)
+
+	CreateWorklog(w *Worklog) (*Worklog, error)
+
+	GetWorklog(id int) (*Worklog, error)
+
+	GetWorklogs(taskID int) ([]*Worklog, error)
+
+	DeleteWorklog(id int) error
+
+	GetTimeReport(filter TimeReportFilter) ([]*TimeReportRow, error)
+
+	// Teams
+	CreateTeam(team *Team, creatorID int) (*Team, error)
+
+	GetTeamsForUser(userID int) ([]*Team, error)
+
+	GetTeamMember(teamID, userID int) (*TeamMember, error)
+
+	GetTeamMembers(teamID int) ([]*TeamMember, error)
+
+	AddTeamMember(member *TeamMember) error
+
+	// Workspaces
+	CreateWorkspace(ws *Workspace, creatorID int) (*Workspace, error)
+
+	GetWorkspace(id int) (*Workspace, error)
+
+	GetWorkspacesForUser(userID int) ([]*Workspace, error)
+
+	UpdateWorkspace(ws *Workspace) error
+
+	GetWorkspaceMember(workspaceID, userID int) (*WorkspaceMember, error)
+
+	GetWorkspaceMembers(workspaceID int) ([]*WorkspaceMember, error)
+
+	AddWorkspaceMember(member *WorkspaceMember) error
+
+	// Labels
+	CreateLabel(l *Label) (*Label, error)
+
+	GetLabel(id int) (*Label, error)
+
+	GetLabels(workspaceID int) ([]*Label, error)
+
+	UpdateLabel(l *Label) error
+
+	DeleteLabel(id int) error
+
+	AddTaskLabel(taskID, labelID, actorID int) error
+
+	RemoveTaskLabel(taskID, labelID, actorID int) error
+
+	// Comments
+	CreateComment(c *Comment) (*Comment, error)
+
+	GetComment(id int) (*Comment, error)
+
+	GetComments(taskID, after, limit int) ([]*Comment, error)
+
+	UpdateComment(id int, body string) (*Comment, error)
+
+	DeleteComment(id int) error
+
+	GetCommentRevisions(commentID int) ([]*CommentRevision, error)
+
+	// Activity
+	GetTaskActivity(taskID, before, limit int) ([]*Activity, error)
+
+	// Attachments
+	CreateAttachment(a *Attachment) (*Attachment, error)
+
+	GetAttachment(id int) (*Attachment, error)
+
+	GetAttachments(taskID int) ([]*Attachment, error)
+
+	DeleteAttachment(id, actorID int) error
+
+	// Mentions and notifications
+	GetMentionsForUser(userID, limit int) ([]*Mention, error)
+
+	GetNotifications(userID, limit int) ([]*Notification, error)
+
+	// Saved views
+	CreateView(v *View) (*View, error)
+
+	GetView(id, userID int) (*View, error)
+
+	GetViewsForUser(userID int) ([]*View, error)
+
+	UpdateView(v *View) error
+
+	DeleteView(id int) error
+
+	SetViewPinned(viewID, userID int, pinned bool) error
+
+	// Search
+	SearchTasks(query string, limit, userID int) ([]*SearchResult, error)
 }
 
 type Storage struct {
-	db *sql.DB
+	db              *sql.DB
+	index           search.Index
+	maxDepth        int
+	escalationDelay time.Duration
 }
 
+// NewStore keeps the search index in the task_search table of the same database.
 func NewStore(db *sql.DB) *Storage {
-	return &Storage{db: db}
+	return NewStoreWithIndex(db, search.NewMySQLIndex(db))
+}
+
+func NewStoreWithIndex(db *sql.DB, index search.Index) *Storage {
+	return &Storage{db: db, index: index, maxDepth: Envs.MaxTaskDepth, escalationDelay: Envs.EscalationDelay}
+}
+
+// withTx runs fn in a transaction, committing when it returns nil and rolling
+// back otherwise.
+func (s *Storage) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// visibleTaskScope limits a task query to the tasks the user can see: those
+// of the workspaces they belong to and the personal tasks assigned to them.
+// It takes the user's id three times.
+const visibleTaskScope = `(t.workspaceID IN (SELECT workspaceID FROM workspace_members WHERE userID = ?)
+	OR t.workspaceID IS NULL AND (t.assignedToID = ?
+		OR EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.taskID = t.id AND ta.userID = ?)))`
+
+// taskColumns is the column list every task query selects, in the order
+// scanTask expects them.
+const taskColumns = `t.id, t.name, COALESCE(t.description, ''), t.status, t.priority, t.dueAt, t.assignedToID,
+	t.workspaceID, t.parentID, t.position, t.createdAt,
+	(SELECT JSON_ARRAYAGG(ta.userID) FROM task_assignees ta WHERE ta.taskID = t.id),
+	(SELECT JSON_ARRAYAGG(JSON_OBJECT('id', l.id, 'workspace_id', l.workspaceID, 'name', l.name, 'color', l.color))
+		FROM task_labels tl JOIN labels l ON l.id = tl.labelID WHERE tl.taskID = t.id),
+	(SELECT COUNT(*) FROM tasks c WHERE c.parentID = t.id AND c.deletedAt IS NULL),
+	(SELECT COUNT(*) FROM tasks c WHERE c.parentID = t.id AND c.deletedAt IS NULL AND c.status = 'DONE'),
+	(SELECT COUNT(*) FROM checklist_items ci WHERE ci.taskID = t.id),
+	(SELECT COUNT(*) FROM checklist_items ci WHERE ci.taskID = t.id AND ci.done),
+	t.doneAt, t.archivedAt, t.deletedAt, t.deletedByID,
+	t.projectID, t.number, (SELECT p.projectKey FROM projects p WHERE p.id = t.projectID),
+	t.milestoneID, t.points,
+	(SELECT JSON_ARRAYAGG(JSON_OBJECT('name', cf.name, 'type', cf.fieldType, 'text', fv.textValue, 'number', fv.numberValue,
+			'date', fv.dateValue, 'user', fv.userID))
+		FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id),
+	t.duplicateOfID`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner) (*Task, error) {
+	var t Task
+	var dueAt, doneAt, archivedAt, deletedAt sql.NullTime
+	var workspaceID, parentID, deletedByID, projectID, number, milestoneID, points, duplicateOfID sql.NullInt64
+	var projectKey sql.NullString
+	var assignees, labels, fields []byte
+	var subtasks, subtasksDone, items, itemsDone int
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.Status, &t.Priority, &dueAt, &t.AssignedToID,
+		&workspaceID, &parentID, &t.Position, &t.CreatedAt, &assignees, &labels, &subtasks, &subtasksDone,
+		&items, &itemsDone, &doneAt, &archivedAt, &deletedAt, &deletedByID, &projectID, &number, &projectKey,
+		&milestoneID, &points, &fields, &duplicateOfID)
+	if err != nil {
+		return nil, err
+	}
+	if assignees != nil {
+		if err := json.Unmarshal(assignees, &t.AssigneeIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode task assignees: %w", err)
+		}
+	}
+	if parentID.Valid {
+		t.ParentID = &parentID.Int64
+	}
+	if subtasks > 0 {
+		t.Progress = &Progress{Done: subtasksDone, Total: subtasks, Percent: subtasksDone * 100 / subtasks}
+	}
+	if items > 0 {
+		t.Checklist = &Progress{Done: itemsDone, Total: items, Percent: itemsDone * 100 / items}
+	}
+	if dueAt.Valid {
+		t.DueAt = &dueAt.Time
+	}
+	if workspaceID.Valid {
+		t.WorkspaceID = &workspaceID.Int64
+	}
+	if doneAt.Valid {
+		t.DoneAt = &doneAt.Time
+	}
+	if archivedAt.Valid {
+		t.ArchivedAt = &archivedAt.Time
+	}
+	if deletedAt.Valid {
+		t.DeletedAt = &deletedAt.Time
+	}
+	if deletedByID.Valid {
+		t.DeletedByID = &deletedByID.Int64
+	}
+	if projectID.Valid {
+		t.ProjectID = &projectID.Int64
+	}
+	if number.Valid && projectKey.Valid {
+		t.Number = &number.Int64
+		t.Key = taskKey(projectKey.String, number.Int64)
+	}
+	if milestoneID.Valid {
+		t.MilestoneID = &milestoneID.Int64
+	}
+	if points.Valid {
+		p := int(points.Int64)
+		t.Points = &p
+	}
+	if duplicateOfID.Valid {
+		t.DuplicateOfID = &duplicateOfID.Int64
+	}
+	if labels != nil {
+		if err := json.Unmarshal(labels, &t.Labels); err != nil {
+			return nil, fmt.Errorf("failed to decode task labels: %w", err)
+		}
+	}
+	if fields != nil {
+		if t.CustomFields, err = decodeFieldValues(fields); err != nil {
+			return nil, fmt.Errorf("failed to decode task custom fields: %w", err)
+		}
+	}
+	return &t, nil
+}
+
+func scanTasks(rows *sql.Rows) ([]*Task, error) {
+	var tasks []*Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return tasks, nil
 }
 
 func (s *Storage) CreateUser(u *User) (*User, error) {
@@ -53,71 +479,304 @@ func (s *Storage) GetUserByID(id int) (*User, error) {
 	return &u, nil
 }
 
-func (s *Storage) CreateTask(task *Task) (*Task, error) {
-	rows, err := s.db.Exec("INSERT INTO tasks (name, status, assignedToID) VALUES (?, ?, ?)",
-		task.Name, task.Status, task.AssignedToID)
+// CreateTask stores the task. AssignedToID becomes the primary assignee
+// and AssigneeIDs adds more. A task with a ParentID is added as the last
+// subtask of its parent.
+func (s *Storage) CreateTask(task *Task, actorID int) (*Task, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		if task.ParentID != nil {
+			err = s.insertSubtask(tx, task)
+		} else {
+			err = insertTask(tx, task)
+		}
+		if err != nil {
+			return err
+		}
+		return recordActivity(tx, task.ID, int64(actorID), ActivityTaskCreated, map[string]any{"parent_id": task.ParentID})
+	})
 	if err != nil {
-		fmt.Printf(err.Error())
 		return nil, err
 	}
+
+	s.indexTasks([]*Task{task})
+	return task, nil
+}
+
+func insertTask(q querier, task *Task) error {
+	task.Number, task.Key = nil, ""
+	var boardRank any
+	if task.ProjectID != nil {
+		number, key, err := allocateTaskNumber(q, *task.ProjectID)
+		if err != nil {
+			return err
+		}
+		task.Number, task.Key = &number, key
+		if boardRank, err = appendRank(q, *task.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	rows, err := q.Exec(`INSERT INTO tasks (name, description, status, priority, dueAt, assignedToID, workspaceID, parentID, position,
+			projectID, number, boardRank, points)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		task.Name, task.Description, task.Status, task.Priority, task.DueAt, task.AssignedToID, task.WorkspaceID,
+		task.ParentID, task.Position, task.ProjectID, task.Number, boardRank, task.Points)
+	if err != nil {
+		fmt.Printf(err.Error())
+		return err
+	}
 	id, err := rows.LastInsertId()
 	if err != nil {
 		fmt.Printf(err.Error())
-		return nil, err
+		return err
 	}
 	task.ID = id
 	task.CreatedAt = time.Now()
-	return task, nil
+
+	assignees := []int64{task.AssignedToID}
+	for _, userID := range task.AssigneeIDs {
+		if !slices.Contains(assignees, userID) {
+			assignees = append(assignees, userID)
+		}
+	}
+	for _, userID := range assignees {
+		if _, err := q.Exec("INSERT INTO task_assignees (taskID, userID) VALUES (?, ?)", id, userID); err != nil {
+			return fmt.Errorf("failed to assign task %d: %w", id, err)
+		}
+	}
+	task.AssigneeIDs = assignees
+
+	if values := task.CustomFields; len(values) > 0 {
+		task.CustomFields = nil
+		if _, err := setTaskFields(q, task, values); err != nil {
+			return err
+		}
+	}
+
+	return recordMentions(q, Mention{TaskID: id}, task.Description)
 }
 
 func (s *Storage) GetTask(id int) (*Task, error) {
-	var t Task
-	err := s.db.QueryRow("SELECT id, name, status, assignedToID, createdAt FROM tasks WHERE id = ?", id).
-		Scan(&t.ID, &t.Name, &t.Status, &t.AssignedToID, &t.CreatedAt)
+	return scanTask(s.db.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL", id))
+}
+
+// nextStatus is the status each status moves to.
+var nextStatus = map[string]string{
+	"TODO":        "IN_PROGRESS",
+	"IN_PROGRESS": "IN_TESTING",
+	"IN_TESTING":  "DONE",
+}
+
+// UpdateTaskStatusByID moves the task to its next status. A task cannot
+// start while a task blocking it is unfinished, and a task with open
+// subtasks, or with open checklist items in a workspace with
+// ChecklistBlocksDone, only becomes DONE when opts.Force is set. The task
+// row is locked while it changes, so the activity feed records the status
+// it actually had.
+func (s *Storage) UpdateTaskStatusByID(id int, opts StatusUpdate) (*Task, error) {
+	var task *Task
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		task, err = s.advanceStatus(tx, int64(id), opts)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &t, err
+	return task, nil
 }
 
-func (s *Storage) UpdateTaskStatusByID(id int) (*Task, error) {
-	task, err := s.GetTask(id)
+// advanceStatus moves the task to its next status within tx.
+func (s *Storage) advanceStatus(tx *sql.Tx, id int64, opts StatusUpdate) (*Task, error) {
+	task, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL FOR UPDATE", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		return &Task{}, fmt.Errorf("Task with id %d does not exist", id)
+		return nil, fmt.Errorf("failed to get task %d: %w", id, err)
 	}
 
-	switch task.Status {
-	case "TODO":
-		task.Status = "IN_PROGRESS"
-		break
-	case "IN_PROGRESS":
-		task.Status = "DONE"
-		break
-	case "DONE":
-		return nil, fmt.Errorf("Task with id %d is already done", id)
+	from := task.Status
+	to, ok := nextStatus[from]
+	if !ok {
+		return nil, ErrTaskDone
+	}
+	if opts.To != "" && opts.To != to {
+		return nil, ErrInvalidTransition
+	}
+	if err := checkStatusChange(tx, task, to, opts); err != nil {
+		return nil, err
 	}
 
-	query := `
-		UPDATE tasks
-		SET status = CASE status
-			WHEN 'TODO' THEN 'IN_PROGRESS'
-			WHEN 'IN_PROGRESS' THEN 'IN_TESTING'
-			WHEN 'IN_TESTING' THEN 'DONE'
-			ELSE status
-		END
-		WHERE id = ? AND status != 'DONE'; -- Prevent updating if already DONE
-	`
+	if to == "DONE" {
+		now := time.Now()
+		task.DoneAt = &now
+	}
+	if _, err := tx.Exec("UPDATE tasks SET status = ?, doneAt = ? WHERE id = ?", to, task.DoneAt, id); err != nil {
+		return nil, fmt.Errorf("failed to update task status: %w", err)
+	}
+	task.Status = to
 
-	_, err = s.db.Exec(query, id)
+	err = recordActivity(tx, task.ID, opts.ActorID, ActivityStatusChanged, map[string]any{"from": from, "to": to})
 	if err != nil {
-		return &Task{}, fmt.Errorf("failed to update task status: %w", err)
+		return nil, err
+	}
+	err = notifyWatchers(tx, task.ID, opts.ActorID, NotificationStatusChanged, map[string]any{"status": to})
+	if err != nil {
+		return nil, err
 	}
-
 	return task, nil
 }
 
+// UpdateTask applies the patch and records the fields that changed, with
+// their old and new values, in the activity feed. Users newly mentioned in
+// the description are notified.
+func (s *Storage) UpdateTask(id int, patch TaskPatch, actorID int) (*Task, error) {
+	changed := false
+	err := s.withTx(func(tx *sql.Tx) error {
+		task, err := scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NULL FOR UPDATE", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", id, err)
+		}
+
+		changes := map[string]map[string]any{}
+		if patch.Name != nil && *patch.Name != task.Name {
+			changes["name"] = map[string]any{"from": task.Name, "to": *patch.Name}
+			task.Name = *patch.Name
+		}
+		if patch.Description != nil && *patch.Description != task.Description {
+			changes["description"] = map[string]any{"from": task.Description, "to": *patch.Description}
+			task.Description = *patch.Description
+		}
+		if patch.Priority != nil && *patch.Priority != task.Priority {
+			changes["priority"] = map[string]any{"from": task.Priority, "to": *patch.Priority}
+			task.Priority = *patch.Priority
+		}
+		dueAt := task.DueAt
+		if patch.DueAt != nil {
+			dueAt = patch.DueAt
+		}
+		if patch.ClearDueAt {
+			dueAt = nil
+		}
+		if !sameTime(dueAt, task.DueAt) {
+			changes["due_at"] = map[string]any{"from": task.DueAt, "to": dueAt}
+			task.DueAt = dueAt
+		}
+		points := task.Points
+		if patch.Points != nil {
+			points = patch.Points
+		}
+		if patch.ClearPoints {
+			points = nil
+		}
+		if !samePoints(points, task.Points) {
+			changes["points"] = map[string]any{"from": task.Points, "to": points}
+			task.Points = points
+		}
+		fieldChanges, err := setTaskFields(tx, task, patch.CustomFields)
+		if err != nil {
+			return err
+		}
+		for name, change := range fieldChanges {
+			changes[name] = change
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		changed = true
+
+		_, err = tx.Exec("UPDATE tasks SET name = ?, description = ?, priority = ?, dueAt = ?, points = ? WHERE id = ?",
+			task.Name, task.Description, task.Priority, task.DueAt, task.Points, id)
+		if err != nil {
+			return fmt.Errorf("failed to update task %d: %w", id, err)
+		}
+		if _, ok := changes["description"]; ok {
+			author := int64(actorID)
+			err := recordMentions(tx, Mention{TaskID: task.ID, AuthorID: &author}, task.Description)
+			if err != nil {
+				return err
+			}
+		}
+		return recordActivity(tx, task.ID, int64(actorID), ActivityTaskEdited, map[string]any{"changes": changes})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		s.reindexTasks(int64(id))
+	}
+	return s.GetTask(id)
+}
+
+func samePoints(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// checkStatusChange enforces the rules of moving the task to status.
+func checkStatusChange(tx *sql.Tx, task *Task, status string, opts StatusUpdate) error {
+	if task.ProjectID != nil && status != task.Status {
+		if err := checkWIPLimit(tx, *task.ProjectID, status, 1); err != nil {
+			return err
+		}
+	}
+
+	if status == "IN_PROGRESS" {
+		var blockers int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM task_dependencies d JOIN tasks b ON b.id = d.blockerID
+			WHERE d.blockedID = ? AND b.status != 'DONE' AND b.deletedAt IS NULL`, task.ID).Scan(&blockers)
+		if err != nil {
+			return fmt.Errorf("failed to check blockers of task %d: %w", task.ID, err)
+		}
+		if blockers > 0 {
+			return ErrTaskBlocked
+		}
+	}
+
+	if status != "DONE" || opts.Force {
+		return nil
+	}
+
+	var open int
+	err := tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE parentID = ? AND status != 'DONE' AND deletedAt IS NULL", task.ID).Scan(&open)
+	if err != nil {
+		return fmt.Errorf("failed to check subtasks of task %d: %w", task.ID, err)
+	}
+	if open > 0 {
+		return ErrOpenSubtasks
+	}
+
+	if task.Checklist != nil && task.Checklist.Done < task.Checklist.Total && task.WorkspaceID != nil {
+		var blocks bool
+		err := tx.QueryRow("SELECT checklistBlocksDone FROM workspaces WHERE id = ?", *task.WorkspaceID).Scan(&blocks)
+		if err != nil {
+			return fmt.Errorf("failed to check workspace of task %d: %w", task.ID, err)
+		}
+		if blocks {
+			return ErrOpenChecklist
+		}
+	}
+	return nil
+}
+
 func (s *Storage) GetTasksAssignedToUser(id int) ([]*Task, error) {
-	query := "SELECT id, name, status, assignedToID, createdAt FROM tasks WHERE assignedToID = ?"
+	query := "SELECT " + taskColumns + ` FROM tasks t WHERE t.deletedAt IS NULL AND t.archivedAt IS NULL
+		AND EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.taskID = t.id AND ta.userID = ?)`
 
 	rows, err := s.db.Query(query, id)
 	if err != nil {
@@ -125,16 +784,9 @@ func (s *Storage) GetTasksAssignedToUser(id int) ([]*Task, error) {
 	}
 	defer rows.Close()
 
-	var tasks []*Task
-	for rows.Next() {
-		var task Task
-		if err := rows.Scan(&task.ID, &task.Name, &task.Status, &task.AssignedToID, &task.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan task row: %w", err)
-		}
-		tasks = append(tasks, &task)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(tasks) == 0 {
@@ -143,3 +795,34 @@ func (s *Storage) GetTasksAssignedToUser(id int) ([]*Task, error) {
 
 	return tasks, nil
 }
+
+// QueryTasks returns the tasks matching the query among those the user can
+// see.
+func (s *Storage) QueryTasks(q *query.Query, userID int) ([]*Task, error) {
+	filter, err := query.Compile(q, query.Env{UserID: int64(userID), Now: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived tasks are left out unless the query asks about them.
+	where := "t.deletedAt IS NULL AND " + visibleTaskScope + " AND "
+	if !q.Mentions("is", "archived") {
+		where += "t.archivedAt IS NULL AND "
+	}
+
+	args := append([]any{userID, userID, userID}, filter.Args...)
+	rows, err := s.db.Query("SELECT "+taskColumns+" FROM tasks t WHERE "+where+"("+filter.Where+") ORDER BY "+filter.OrderBy, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
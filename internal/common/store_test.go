@@ -1,12 +1,129 @@
 package common
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/query"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
 
+var taskColumnNames = []string{"id", "name", "description", "status", "priority", "dueAt", "assignedToID",
+	"workspaceID", "parentID", "position", "createdAt", "assignees", "labels", "subtasks", "subtasksDone",
+	"checklistItems", "checklistDone", "doneAt", "archivedAt", "deletedAt", "deletedByID",
+	"projectID", "number", "projectKey", "milestoneID", "points", "customFields", "duplicateOfID"}
+
+func taskRows(tasks ...*Task) *sqlmock.Rows {
+	rows := sqlmock.NewRows(taskColumnNames)
+	for _, t := range tasks {
+		var dueAt, workspaceID, parentID, assignees, labels, doneAt, archivedAt, deletedAt, deletedByID any
+		var projectID, number, projectKey, milestoneID, points, customFields, duplicateOfID any
+		if t.DueAt != nil {
+			dueAt = *t.DueAt
+		}
+		if t.WorkspaceID != nil {
+			workspaceID = *t.WorkspaceID
+		}
+		if t.ParentID != nil {
+			parentID = *t.ParentID
+		}
+		if t.DoneAt != nil {
+			doneAt = *t.DoneAt
+		}
+		if t.ArchivedAt != nil {
+			archivedAt = *t.ArchivedAt
+		}
+		if t.DeletedAt != nil {
+			deletedAt = *t.DeletedAt
+		}
+		if t.DeletedByID != nil {
+			deletedByID = *t.DeletedByID
+		}
+		if t.ProjectID != nil {
+			projectID = *t.ProjectID
+		}
+		if t.Number != nil {
+			number = *t.Number
+			projectKey, _, _ = strings.Cut(t.Key, "-")
+		}
+		if t.MilestoneID != nil {
+			milestoneID = *t.MilestoneID
+		}
+		if t.Points != nil {
+			points = *t.Points
+		}
+		subtasks, subtasksDone := 0, 0
+		if t.Progress != nil {
+			subtasks, subtasksDone = t.Progress.Total, t.Progress.Done
+		}
+		items, itemsDone := 0, 0
+		if t.Checklist != nil {
+			items, itemsDone = t.Checklist.Total, t.Checklist.Done
+		}
+		if t.AssigneeIDs != nil {
+			assignees, _ = json.Marshal(t.AssigneeIDs)
+		}
+		if t.Labels != nil {
+			labels, _ = json.Marshal(t.Labels)
+		}
+		if t.CustomFields != nil {
+			customFields = fieldValuesJSON(t.CustomFields)
+		}
+		if t.DuplicateOfID != nil {
+			duplicateOfID = *t.DuplicateOfID
+		}
+		rows.AddRow(t.ID, t.Name, t.Description, t.Status, t.Priority, dueAt, t.AssignedToID, workspaceID, parentID,
+			t.Position, t.CreatedAt, assignees, labels, subtasks, subtasksDone, items, itemsDone,
+			doneAt, archivedAt, deletedAt, deletedByID, projectID, number, projectKey,
+			milestoneID, points, customFields, duplicateOfID)
+	}
+	return rows
+}
+
+// fieldValuesJSON encodes custom field values the way taskColumns
+// aggregates them.
+func fieldValuesJSON(values map[string]any) []byte {
+	var rows []map[string]any
+	for name, value := range values {
+		switch v := value.(type) {
+		case []string:
+			for _, option := range v {
+				rows = append(rows, map[string]any{"name": name, "type": FieldMultiSelect, "text": option})
+			}
+		case float64:
+			rows = append(rows, map[string]any{"name": name, "type": FieldNumber, "number": v})
+		case int64:
+			rows = append(rows, map[string]any{"name": name, "type": FieldUser, "user": v})
+		default:
+			rows = append(rows, map[string]any{"name": name, "type": FieldText, "text": v})
+		}
+	}
+	data, _ := json.Marshal(rows)
+	return data
+}
+
+func taskInsertArgs(t *Task) []driver.Value {
+	var boardRank driver.Value
+	if t.ProjectID != nil {
+		boardRank = sqlmock.AnyArg()
+	}
+	return []driver.Value{t.Name, t.Description, t.Status, t.Priority, t.DueAt, t.AssignedToID, t.WorkspaceID,
+		t.ParentID, t.Position, t.ProjectID, t.Number, boardRank, t.Points}
+}
+
+func expectWatchers(mock sqlmock.Sqlmock, taskID int64, userIDs ...int64) {
+	rows := sqlmock.NewRows([]string{"userID"})
+	for _, id := range userIDs {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT userID FROM task_watchers WHERE taskID = ?").
+		WithArgs(taskID).
+		WillReturnRows(rows)
+}
+
 func TestCreateUser(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
@@ -73,17 +190,31 @@ func TestCreateTask(t *testing.T) {
 
 	task := &Task{
 		Name:         "Sample Task",
+		Description:  "Sample description",
 		Status:       "TODO",
+		Priority:     "P2",
 		AssignedToID: 1,
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO tasks").
-		WithArgs(task.Name, task.Status, task.AssignedToID).
+		WithArgs(taskInsertArgs(task)...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskCreated, []byte(`{"parent_id":null}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO task_search").
+		WithArgs(int64(1), task.Name, task.Description, "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	createdTask, err := store.CreateTask(task)
+	createdTask, err := store.CreateTask(task, 3)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), createdTask.ID)
+	assert.Equal(t, []int64{1}, createdTask.AssigneeIDs)
 	assert.NotZero(t, createdTask.CreatedAt)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -102,10 +233,9 @@ func TestGetTask(t *testing.T) {
 		CreatedAt:    time.Now(),
 	}
 
-	mock.ExpectQuery("SELECT id, name, status, assignedToID, createdAt FROM tasks WHERE id = ?").
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "assignedToID", "createdAt"}).
-			AddRow(mockTask.ID, mockTask.Name, mockTask.Status, mockTask.AssignedToID, mockTask.CreatedAt))
+		WillReturnRows(taskRows(mockTask))
 
 	task, err := store.GetTask(1)
 	assert.NoError(t, err)
@@ -127,16 +257,25 @@ func TestUpdateTaskStatusByID(t *testing.T) {
 		CreatedAt:    time.Now(),
 	}
 
-	mock.ExpectQuery("SELECT id, name, status, assignedToID, createdAt FROM tasks WHERE id = ?").
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "assignedToID", "createdAt"}).
-			AddRow(mockTask.ID, mockTask.Name, mockTask.Status, mockTask.AssignedToID, mockTask.CreatedAt))
+		WillReturnRows(taskRows(mockTask))
 
-	mock.ExpectExec("UPDATE tasks").
+	mock.ExpectQuery("SELECT COUNT(.+) FROM task_dependencies").
 		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"blockers"}).AddRow(0))
+
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\? WHERE id = ?").
+		WithArgs("IN_PROGRESS", nil, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityStatusChanged, []byte(`{"from":"TODO","to":"IN_PROGRESS"}`)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectWatchers(mock, 1)
+	mock.ExpectCommit()
 
-	updatedTask, err := store.UpdateTaskStatusByID(1)
+	updatedTask, err := store.UpdateTaskStatusByID(1, StatusUpdate{ActorID: 3})
 	assert.NoError(t, err)
 	assert.Equal(t, "IN_PROGRESS", updatedTask.Status)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -153,17 +292,48 @@ func TestGetTasksAssignedToUser(t *testing.T) {
 		{ID: 2, Name: "Task 2", Status: "IN_PROGRESS", AssignedToID: 1, CreatedAt: time.Now()},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "name", "status", "assignedToID", "createdAt"})
-	for _, task := range mockTasks {
-		rows.AddRow(task.ID, task.Name, task.Status, task.AssignedToID, task.CreatedAt)
-	}
-
-	mock.ExpectQuery("SELECT id, name, status, assignedToID, createdAt FROM tasks WHERE assignedToID = ?").
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.deletedAt IS NULL AND t.archivedAt IS NULL\\s+AND EXISTS \\(SELECT 1 FROM task_assignees ta WHERE ta.taskID = t.id AND ta.userID = \\?\\)").
 		WithArgs(1).
-		WillReturnRows(rows)
+		WillReturnRows(taskRows(mockTasks...))
 
 	tasks, err := store.GetTasksAssignedToUser(1)
 	assert.NoError(t, err)
 	assert.Equal(t, mockTasks, tasks)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestQueryTasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mockTask := &Task{ID: 1, Name: "Task 1", Status: "IN_PROGRESS", Priority: "P1", AssignedToID: 3, CreatedAt: time.Now()}
+
+	mock.ExpectQuery(`SELECT (.+) FROM tasks t WHERE t.deletedAt IS NULL AND \(t.workspaceID IN \(SELECT workspaceID FROM workspace_members WHERE userID = \?\)\s+OR t.workspaceID IS NULL AND (.+)\) AND t.archivedAt IS NULL AND \(\(t.status = \?\) AND \(EXISTS \(SELECT 1 FROM task_assignees u WHERE u.taskID = t.id AND u.userID IN \(\?\)\)\)\) ORDER BY t.id ASC`).
+		WithArgs(3, 3, 3, "IN_PROGRESS", int64(3)).
+		WillReturnRows(taskRows(mockTask))
+
+	q, err := query.Parse("status:IN_PROGRESS assignee:me")
+	assert.NoError(t, err)
+
+	tasks, err := store.QueryTasks(q, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Task{mockTask}, tasks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryTasksRejectsUnknownField(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	q, err := query.Parse("colour:red")
+	assert.NoError(t, err)
+
+	_, err = store.QueryTasks(q, 3)
+	var queryErr *query.Error
+	assert.ErrorAs(t, err, &queryErr)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,208 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// lockTask locks the task's row for the rest of the transaction.
+func lockTask(tx *sql.Tx, id int64) error {
+	var locked int64
+	err := tx.QueryRow("SELECT id FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock task %d: %w", id, err)
+	}
+	return nil
+}
+
+// taskDepth returns the level of the task in its hierarchy, 1 for a task
+// without a parent.
+func (s *Storage) taskDepth(q querier, id int64) (int, error) {
+	var depth int
+	err := q.QueryRow(`
+		WITH RECURSIVE ancestors (id, parentID, depth) AS (
+			SELECT id, parentID, 1 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, t.parentID, a.depth + 1 FROM tasks t JOIN ancestors a ON t.id = a.parentID
+			WHERE a.depth <= ?
+		)
+		SELECT MAX(depth) FROM ancestors`, id, s.maxDepth).Scan(&depth)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get depth of task %d: %w", id, err)
+	}
+	return depth, nil
+}
+
+// subtreeHeight returns how many levels the task and its subtasks span,
+// and whether probeID is among them.
+func (s *Storage) subtreeHeight(q querier, id, probeID int64) (int, bool, error) {
+	var height, found int
+	err := q.QueryRow(`
+		WITH RECURSIVE subtree (id, depth) AS (
+			SELECT id, 1 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, st.depth + 1 FROM tasks t JOIN subtree st ON t.parentID = st.id
+			WHERE st.depth <= ?
+		)
+		SELECT MAX(depth), COALESCE(SUM(id = ?), 0) FROM subtree`, id, s.maxDepth, probeID).Scan(&height, &found)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get subtasks of task %d: %w", id, err)
+	}
+	return height, found > 0, nil
+}
+
+func nextPosition(tx *sql.Tx, parentID int64) (int, error) {
+	var position int
+	err := tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM tasks WHERE parentID = ?", parentID).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get subtasks of task %d: %w", parentID, err)
+	}
+	return position, nil
+}
+
+func (s *Storage) insertSubtask(tx *sql.Tx, task *Task) error {
+	if err := lockTask(tx, *task.ParentID); err != nil {
+		return err
+	}
+
+	depth, err := s.taskDepth(tx, *task.ParentID)
+	if err != nil {
+		return err
+	}
+	if depth+1 > s.maxDepth {
+		return ErrTaskTooDeep
+	}
+
+	task.Position, err = nextPosition(tx, *task.ParentID)
+	if err != nil {
+		return err
+	}
+	return insertTask(tx, task)
+}
+
+// GetSubtasks returns the direct subtasks of the task in their order.
+func (s *Storage) GetSubtasks(parentID int) ([]*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+" FROM tasks t WHERE t.parentID = ? AND t.deletedAt IS NULL ORDER BY t.position, t.id", parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks of task %d: %w", parentID, err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
+
+// SetTaskParent moves the task, with its subtasks, under a new parent, or
+// to the top level when parentID is nil. Moves that would make the task
+// its own ancestor return ErrTaskCycle, and moves that would make the
+// hierarchy deeper than allowed return ErrTaskTooDeep.
+func (s *Storage) SetTaskParent(id int, parentID *int64, actorID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var oldParentID *int64
+		err := tx.QueryRow("SELECT parentID FROM tasks WHERE id = ? AND deletedAt IS NULL FOR UPDATE", id).Scan(&oldParentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock task %d: %w", id, err)
+		}
+
+		position := 0
+		if parentID != nil {
+			if *parentID == int64(id) {
+				return ErrTaskCycle
+			}
+			if err := lockTask(tx, *parentID); err != nil {
+				return err
+			}
+
+			height, contains, err := s.subtreeHeight(tx, int64(id), *parentID)
+			if err != nil {
+				return err
+			}
+			if contains {
+				return ErrTaskCycle
+			}
+
+			depth, err := s.taskDepth(tx, *parentID)
+			if err != nil {
+				return err
+			}
+			if depth+height > s.maxDepth {
+				return ErrTaskTooDeep
+			}
+
+			position, err = nextPosition(tx, *parentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec("UPDATE tasks SET parentID = ?, position = ? WHERE id = ?", parentID, position, id)
+		if err != nil {
+			return fmt.Errorf("failed to move task %d: %w", id, err)
+		}
+		if sameParent(oldParentID, parentID) {
+			return nil
+		}
+		return recordActivity(tx, int64(id), int64(actorID), ActivityParentChanged, map[string]any{"from": oldParentID, "to": parentID})
+	})
+}
+
+func sameParent(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ReorderSubtasks sets the order of the task's subtasks. ids must list
+// every subtask exactly once.
+func (s *Storage) ReorderSubtasks(parentID int, ids []int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id FROM tasks WHERE parentID = ? AND deletedAt IS NULL FOR UPDATE", parentID)
+		if err != nil {
+			return fmt.Errorf("failed to get subtasks of task %d: %w", parentID, err)
+		}
+		current := make(map[int64]bool)
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan task row: %w", err)
+			}
+			current[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over rows: %w", err)
+		}
+
+		if len(ids) != len(current) {
+			return ErrInvalidOrder
+		}
+		for _, id := range ids {
+			if !current[id] {
+				return ErrInvalidOrder
+			}
+			delete(current, id)
+		}
+
+		for i, id := range ids {
+			if _, err := tx.Exec("UPDATE tasks SET position = ? WHERE id = ?", i+1, id); err != nil {
+				return fmt.Errorf("failed to reorder subtasks of task %d: %w", parentID, err)
+			}
+		}
+		return nil
+	})
+}
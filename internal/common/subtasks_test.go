@@ -0,0 +1,236 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateSubtask(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 3
+	parentID := int64(7)
+	task := &Task{Name: "Write tests", Status: "TODO", Priority: "P2", AssignedToID: 1, ParentID: &parentID}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("WITH RECURSIVE ancestors").
+		WithArgs(parentID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"depth"}).AddRow(2))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(position\\), 0\\) \\+ 1 FROM tasks WHERE parentID = ?").
+		WithArgs(parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(4))
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Write tests", "", "TODO", "P2", nil, int64(1), nil, &parentID, 4, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(12), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(12), int64(3), ActivityTaskCreated, []byte(`{"parent_id":7}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO task_search").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	created, err := store.CreateTask(task, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, created.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateSubtaskTooDeep(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 3
+	parentID := int64(7)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(parentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("WITH RECURSIVE ancestors").
+		WithArgs(parentID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"depth"}).AddRow(3))
+	mock.ExpectRollback()
+
+	_, err := store.CreateTask(&Task{Name: "Too deep", ParentID: &parentID}, 3)
+	assert.ErrorIs(t, err, ErrTaskTooDeep)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTaskParentRejectsCycle(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 5
+	newParent := int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID"}).AddRow(nil))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	// Task 9 is a grandchild of task 2.
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(2), 5, newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"height", "found"}).AddRow(3, 1))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.SetTaskParent(2, &newParent, 3), ErrTaskCycle)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	self := int64(2)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID"}).AddRow(nil))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.SetTaskParent(2, &self, 3), ErrTaskCycle)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTaskParentCountsSubtreeDepth(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 4
+	newParent := int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID"}).AddRow(nil))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(2), 4, newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"height", "found"}).AddRow(2, 0))
+	mock.ExpectQuery("WITH RECURSIVE ancestors").
+		WithArgs(newParent, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"depth"}).AddRow(3))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.SetTaskParent(2, &newParent, 3), ErrTaskTooDeep)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetTaskParentRecordsActivity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 5
+	newParent := int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID"}).AddRow(4))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery("WITH RECURSIVE subtree").
+		WithArgs(int64(2), 5, newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"height", "found"}).AddRow(1, 0))
+	mock.ExpectQuery("WITH RECURSIVE ancestors").
+		WithArgs(newParent, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"depth"}).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(position\\), 0\\) \\+ 1 FROM tasks WHERE parentID = \\?").
+		WithArgs(newParent).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(3))
+	mock.ExpectExec("UPDATE tasks SET parentID = \\?, position = \\? WHERE id = \\?").
+		WithArgs(&newParent, 3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(2), int64(3), ActivityParentChanged, []byte(`{"from":4,"to":9}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.SetTaskParent(2, &newParent, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderSubtasksRequiresEverySubtask(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE parentID = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.ReorderSubtasks(1, []int64{3, 3}), ErrInvalidOrder)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE parentID = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectExec("UPDATE tasks SET position = \\? WHERE id = ?").WithArgs(1, int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE tasks SET position = \\? WHERE id = ?").WithArgs(2, int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.ReorderSubtasks(1, []int64{3, 2}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDoneBlockedByOpenSubtasks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	task := &Task{ID: 1, Name: "Release", Status: "IN_TESTING", AssignedToID: 1, CreatedAt: time.Now(),
+		Progress: &Progress{Done: 1, Total: 2}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE parentID = \\? AND status != 'DONE'").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"open"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := store.UpdateTaskStatusByID(1, StatusUpdate{})
+	assert.ErrorIs(t, err, ErrOpenSubtasks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, doneAt = \\? WHERE id = ?").
+		WithArgs("DONE", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityStatusChanged, []byte(`{"from":"IN_TESTING","to":"DONE"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectWatchers(mock, 1, 4)
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs(int64(4), NotificationStatusChanged, int64(1), int64(3), []byte(`{"status":"DONE"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	updated, err := store.UpdateTaskStatusByID(1, StatusUpdate{ActorID: 3, Force: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "DONE", updated.Status)
+	assert.Equal(t, &Progress{Done: 1, Total: 2, Percent: 50}, updated.Progress)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateTeam creates the team and makes its creator the team lead.
+func (s *Storage) CreateTeam(team *Team, creatorID int) (*Team, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("INSERT INTO teams (name) VALUES (?)", team.Name)
+		if err != nil {
+			return fmt.Errorf("failed to create team: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO team_members (teamID, userID, role) VALUES (?, ?, ?)", id, creatorID, TeamRoleLead)
+		if err != nil {
+			return fmt.Errorf("failed to add team lead: %w", err)
+		}
+
+		team.ID = id
+		team.CreatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *Storage) GetTeamsForUser(userID int) ([]*Team, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.name, t.createdAt FROM teams t
+		JOIN team_members m ON m.teamID = t.id
+		WHERE m.userID = ? ORDER BY t.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams of user with id %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	teams := []*Team{}
+	for rows.Next() {
+		var team Team
+		if err := rows.Scan(&team.ID, &team.Name, &team.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan team row: %w", err)
+		}
+		teams = append(teams, &team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return teams, nil
+}
+
+func (s *Storage) GetTeamMember(teamID, userID int) (*TeamMember, error) {
+	var m TeamMember
+	err := s.db.QueryRow("SELECT teamID, userID, role FROM team_members WHERE teamID = ? AND userID = ?", teamID, userID).
+		Scan(&m.TeamID, &m.UserID, &m.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *Storage) GetTeamMembers(teamID int) ([]*TeamMember, error) {
+	rows, err := s.db.Query("SELECT teamID, userID, role FROM team_members WHERE teamID = ? ORDER BY userID", teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members of team with id %d: %w", teamID, err)
+	}
+	defer rows.Close()
+
+	members := []*TeamMember{}
+	for rows.Next() {
+		var m TeamMember
+		if err := rows.Scan(&m.TeamID, &m.UserID, &m.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan team member row: %w", err)
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return members, nil
+}
+
+// AddTeamMember adds the user to the team, or changes their role when they
+// already are a member.
+func (s *Storage) AddTeamMember(member *TeamMember) error {
+	_, err := s.db.Exec(`
+		INSERT INTO team_members (teamID, userID, role) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`,
+		member.TeamID, member.UserID, member.Role)
+	if err != nil {
+		return fmt.Errorf("failed to add member to team with id %d: %w", member.TeamID, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateTeamAddsCreatorAsLead(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO teams \\(name\\) VALUES \\(\\?\\)").
+		WithArgs("Platform").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO team_members \\(teamID, userID, role\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(int64(2), 3, TeamRoleLead).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	team, err := store.CreateTeam(&Team{Name: "Platform"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), team.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTeamsForUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT t.id, t.name, t.createdAt FROM teams t\\s+JOIN team_members m ON m.teamID = t.id\\s+WHERE m.userID = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "createdAt"}).
+			AddRow(2, "Platform", time.Now()).
+			AddRow(5, "Support", time.Now()))
+
+	teams, err := store.GetTeamsForUser(3)
+	assert.NoError(t, err)
+	assert.Len(t, teams, 2)
+	assert.Equal(t, "Support", teams[1].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTeamMemberOfOtherTeam(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT teamID, userID, role FROM team_members WHERE teamID = \\? AND userID = \\?").
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"teamID", "userID", "role"}))
+
+	_, err := store.GetTeamMember(2, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddTeamMemberUpdatesRole(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("INSERT INTO team_members \\(teamID, userID, role\\) VALUES \\(\\?, \\?, \\?\\)\\s+ON DUPLICATE KEY UPDATE role = VALUES\\(role\\)").
+		WithArgs(int64(2), int64(4), TeamRoleLead).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := store.AddTeamMember(&TeamMember{TeamID: 2, UserID: 4, Role: TeamRoleLead})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
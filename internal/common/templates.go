@@ -0,0 +1,161 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const templateColumns = "id, workspaceID, name, body, createdByID, createdAt"
+
+func scanTemplate(row rowScanner) (*Template, error) {
+	var t Template
+	var body []byte
+	if err := row.Scan(&t.ID, &t.WorkspaceID, &t.Name, &body, &t.CreatedByID, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &t.Task); err != nil {
+		return nil, fmt.Errorf("failed to decode template %d: %w", t.ID, err)
+	}
+	return &t, nil
+}
+
+func (s *Storage) CreateTemplate(t *Template) (*Template, error) {
+	body, err := json.Marshal(t.Task)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec("INSERT INTO templates (workspaceID, name, body, createdByID) VALUES (?, ?, ?, ?)",
+		t.WorkspaceID, t.Name, body, t.CreatedByID)
+	if isDuplicateKey(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTemplate(int(id))
+}
+
+func (s *Storage) GetTemplate(id int) (*Template, error) {
+	t, err := scanTemplate(s.db.QueryRow("SELECT "+templateColumns+" FROM templates WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template %d: %w", id, err)
+	}
+	return t, nil
+}
+
+func (s *Storage) GetTemplates(workspaceID int) ([]*Template, error) {
+	rows, err := s.db.Query("SELECT "+templateColumns+" FROM templates WHERE workspaceID = ? ORDER BY name", workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates of workspace %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	templates := []*Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template row: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return templates, nil
+}
+
+func (s *Storage) UpdateTemplate(t *Template) error {
+	body, err := json.Marshal(t.Task)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE templates SET name = ?, body = ? WHERE id = ?", t.Name, body, t.ID)
+	if isDuplicateKey(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update template %d: %w", t.ID, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteTemplate(id int) error {
+	res, err := s.db.Exec("DELETE FROM templates WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template %d: %w", id, err)
+	}
+	return requireAffected(res)
+}
+
+// CreateTaskTree creates the task with its labels, checklist and subtasks in
+// one transaction and returns the top task. Labels that are not in the
+// task's workspace are skipped.
+func (s *Storage) CreateTaskTree(tree *TaskTree, actorID int) (*Task, error) {
+	if tree.Height() > s.maxDepth {
+		return nil, ErrTaskTooDeep
+	}
+
+	var created []*Task
+	err := s.withTx(func(tx *sql.Tx) error {
+		created = created[:0]
+		if err := insertTaskTree(tx, tree, &created); err != nil {
+			return err
+		}
+		for _, task := range created {
+			err := recordActivity(tx, task.ID, int64(actorID), ActivityTaskCreated, map[string]any{"parent_id": task.ParentID})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexTasks(created)
+	return s.GetTask(int(tree.Task.ID))
+}
+
+func insertTaskTree(tx *sql.Tx, tree *TaskTree, created *[]*Task) error {
+	if err := insertTask(tx, tree.Task); err != nil {
+		return err
+	}
+	*created = append(*created, tree.Task)
+	id := tree.Task.ID
+
+	for _, labelID := range tree.LabelIDs {
+		_, err := tx.Exec(`INSERT IGNORE INTO task_labels (taskID, labelID)
+			SELECT ?, id FROM labels WHERE id = ? AND workspaceID = ?`, id, labelID, tree.Task.WorkspaceID)
+		if err != nil {
+			return fmt.Errorf("failed to add label %d to task %d: %w", labelID, id, err)
+		}
+	}
+	for i, text := range tree.Checklist {
+		_, err := tx.Exec("INSERT INTO checklist_items (taskID, text, done, position) VALUES (?, ?, ?, ?)",
+			id, text, false, i+1)
+		if err != nil {
+			return fmt.Errorf("failed to add checklist item to task %d: %w", id, err)
+		}
+	}
+	for i, sub := range tree.Subtasks {
+		sub.Task.ParentID = &id
+		sub.Task.WorkspaceID = tree.Task.WorkspaceID
+		sub.Task.ProjectID = tree.Task.ProjectID
+		sub.Task.Position = i + 1
+		if err := insertTaskTree(tx, sub, created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateTaskTree(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	workspaceID := int64(2)
+	tree := &TaskTree{
+		Task:      &Task{Name: "Release 1.4", Status: "TODO", Priority: "P1", AssignedToID: 1, WorkspaceID: &workspaceID},
+		LabelIDs:  []int64{3},
+		Checklist: []string{"Tag 1.4"},
+		Subtasks: []*TaskTree{
+			{Task: &Task{Name: "Changelog", Status: "TODO", Priority: "P2", AssignedToID: 1}},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Release 1.4", "", "TODO", "P1", nil, int64(1), int64(2), nil, 0, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(10), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels \\(taskID, labelID\\) SELECT \\?, id FROM labels WHERE id = \\? AND workspaceID = ?").
+		WithArgs(int64(10), int64(3), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO checklist_items").
+		WithArgs(int64(10), "Tag 1.4", false, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs("Changelog", "", "TODO", "P2", nil, int64(1), int64(2), int64(10), 1, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectExec("INSERT INTO task_assignees").
+		WithArgs(int64(11), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(10), int64(3), ActivityTaskCreated, []byte(`{"parent_id":null}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(11), int64(3), ActivityTaskCreated, []byte(`{"parent_id":10}`)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO task_search").
+		WithArgs(int64(10), "Release 1.4", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_search").
+		WithArgs(int64(11), "Changelog", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = ?").
+		WithArgs(10).
+		WillReturnRows(taskRows(&Task{ID: 10, Name: "Release 1.4", Status: "TODO", Priority: "P1", AssignedToID: 1,
+			WorkspaceID: &workspaceID, CreatedAt: time.Now(), Progress: &Progress{Total: 1}, Checklist: &Progress{Total: 1}}))
+
+	task, err := store.CreateTaskTree(tree, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), task.ID)
+	assert.Equal(t, &Progress{Total: 1}, task.Progress)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateTaskTreeTooDeep(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	store.maxDepth = 2
+	tree := &TaskTree{Task: &Task{Name: "1"}, Subtasks: []*TaskTree{
+		{Task: &Task{Name: "2"}, Subtasks: []*TaskTree{{Task: &Task{Name: "3"}}}},
+	}}
+
+	_, err := store.CreateTaskTree(tree, 3)
+	assert.ErrorIs(t, err, ErrTaskTooDeep)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,253 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// trashedSubtree returns the task and those of its subtasks whose deletedAt
+// is the given one; nil matches the tasks that are not in the trash.
+func trashedSubtree(tx *sql.Tx, id int64, deletedAt *time.Time) ([]int64, error) {
+	rows, err := tx.Query(`
+		WITH RECURSIVE subtree (id, deletedAt) AS (
+			SELECT id, deletedAt FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, t.deletedAt FROM tasks t JOIN subtree st ON t.parentID = st.id
+		)
+		SELECT id FROM subtree WHERE deletedAt <=> ?`, id, deletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks of task %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteTask moves the task and its subtasks to the trash. Trashed tasks
+// are left out of every other query until they are restored or purged.
+func (s *Storage) DeleteTask(id, actorID int) error {
+	var ids []int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		ids, err = trashTask(tx, int64(id), int64(actorID))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.removeFromIndex(ids)
+	return nil
+}
+
+// trashTask moves the task and its subtasks to the trash within tx and
+// returns their ids.
+func trashTask(tx *sql.Tx, id, actorID int64) ([]int64, error) {
+	if err := lockTask(tx, id); err != nil {
+		return nil, err
+	}
+
+	ids, err := trashedSubtree(tx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every task of the subtree gets the same deletedAt, which is how
+	// RestoreTask tells them from subtasks that were deleted earlier.
+	now := time.Now().UTC().Truncate(time.Second)
+	args := append([]any{now, actorID}, int64Args(ids)...)
+	_, err = tx.Exec("UPDATE tasks SET deletedAt = ?, deletedByID = ? WHERE id IN ("+placeholders(len(ids))+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete task %d: %w", id, err)
+	}
+	err = recordActivity(tx, id, actorID, ActivityTaskDeleted, map[string]any{"task_ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// removeFromIndex drops the tasks' search documents. Like reindexTasks it
+// only logs failures; search results skip tasks that are no longer live.
+func (s *Storage) removeFromIndex(ids []int64) {
+	for _, id := range ids {
+		if err := s.index.Remove(id); err != nil {
+			log.Printf("failed to remove task %d from the search index: %v", id, err)
+		}
+	}
+}
+
+// GetTrashedTask returns the task if it is in the trash.
+func (s *Storage) GetTrashedTask(id int) (*Task, error) {
+	task, err := scanTask(s.db.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ? AND t.deletedAt IS NOT NULL", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task %d: %w", id, err)
+	}
+	return task, nil
+}
+
+// RestoreTask takes the task out of the trash together with the subtasks
+// that were deleted with it. A subtask cannot be restored while its parent
+// is in the trash.
+func (s *Storage) RestoreTask(id, actorID int) (*Task, error) {
+	var ids []int64
+	err := s.withTx(func(tx *sql.Tx) error {
+		var parentID sql.NullInt64
+		var deletedAt time.Time
+		err := tx.QueryRow("SELECT parentID, deletedAt FROM tasks WHERE id = ? AND deletedAt IS NOT NULL FOR UPDATE", id).
+			Scan(&parentID, &deletedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task %d: %w", id, err)
+		}
+
+		if parentID.Valid {
+			if err := lockTask(tx, parentID.Int64); errors.Is(err, ErrNotFound) {
+				return ErrParentTrashed
+			} else if err != nil {
+				return err
+			}
+		}
+
+		ids, err = trashedSubtree(tx, int64(id), &deletedAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE tasks SET deletedAt = NULL, deletedByID = NULL WHERE id IN ("+placeholders(len(ids))+")",
+			int64Args(ids)...)
+		if err != nil {
+			return fmt.Errorf("failed to restore task %d: %w", id, err)
+		}
+		return recordActivity(tx, int64(id), int64(actorID), ActivityTaskRestored, map[string]any{"task_ids": ids})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.reindexTasks(ids...)
+	return s.GetTask(id)
+}
+
+// GetTrash returns the tasks in the trash that the user deleted, is
+// assigned to or can see through a workspace, most recently deleted
+// first. Subtasks deleted together with their parent are not listed on
+// their own.
+func (s *Storage) GetTrash(userID int) ([]*Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		WHERE t.deletedAt IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM tasks p WHERE p.id = t.parentID AND p.deletedAt = t.deletedAt)
+			AND (t.deletedByID = ?
+				OR EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.taskID = t.id AND ta.userID = ?)
+				OR t.workspaceID IN (SELECT workspaceID FROM workspace_members WHERE userID = ?))
+		ORDER BY t.deletedAt DESC, t.id DESC`, userID, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*Task{}
+	}
+	return tasks, nil
+}
+
+// PurgeTrash permanently deletes up to limit tasks that were moved to the
+// trash before the given time. Their subtasks, comments, attachments and
+// the rest of their records go with them. It returns how many tasks it
+// purged and the storage keys of the purged attachments, which the caller
+// removes from blob storage.
+func (s *Storage) PurgeTrash(before time.Time, limit int) (int, []string, error) {
+	var purged int
+	var keys []string
+	err := s.withTx(func(tx *sql.Tx) error {
+		purged, keys = 0, nil
+
+		rows, err := tx.Query("SELECT id FROM tasks WHERE deletedAt < ? ORDER BY deletedAt, id LIMIT ? FOR UPDATE SKIP LOCKED",
+			before, limit)
+		if err != nil {
+			return fmt.Errorf("failed to get trashed tasks: %w", err)
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan task row: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over rows: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if keys, err = purgeTasks(tx, ids); err != nil {
+			return err
+		}
+		purged = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return purged, keys, nil
+}
+
+// purgeTasks deletes the tasks for good within tx and returns the storage
+// keys of their attachments. The subtasks are deleted by the cascade, so
+// their attachments are collected too.
+func purgeTasks(tx *sql.Tx, ids []int64) ([]string, error) {
+	rows, err := tx.Query(`
+		WITH RECURSIVE subtree (id) AS (
+			SELECT id FROM tasks WHERE id IN (`+placeholders(len(ids))+`)
+			UNION ALL
+			SELECT t.id FROM tasks t JOIN subtree st ON t.parentID = st.id
+		)
+		SELECT DISTINCT a.storageKey FROM attachments a JOIN subtree st ON st.id = a.taskID`, int64Args(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments of purged tasks: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM tasks WHERE id IN ("+placeholders(len(ids))+")", int64Args(ids)...); err != nil {
+		return nil, fmt.Errorf("failed to purge tasks: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDeleteTaskTrashesSubtree(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) SELECT id FROM subtree WHERE deletedAt <=> \\?").
+		WithArgs(int64(1), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec("UPDATE tasks SET deletedAt = \\?, deletedByID = \\? WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(sqlmock.AnyArg(), 3, int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskDeleted, []byte(`{"task_ids":[1,2]}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("DELETE FROM task_search WHERE taskID = ?").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_search WHERE taskID = ?").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, store.DeleteTask(1, 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreTaskUnderTrashedParent(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, deletedAt FROM tasks WHERE id = \\? AND deletedAt IS NOT NULL FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "deletedAt"}).AddRow(1, time.Now()))
+	mock.ExpectQuery("SELECT id FROM tasks WHERE id = \\? AND deletedAt IS NULL FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	_, err := store.RestoreTask(2, 3)
+	assert.ErrorIs(t, err, ErrParentTrashed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreTaskRestoresSubtasksDeletedWithIt(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStoreWithIndex(db, search.NewMemoryIndex())
+	deletedAt := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	task := &Task{ID: 1, Name: "Release 1.4", Status: "TODO", Priority: "P2", AssignedToID: 3, CreatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT parentID, deletedAt FROM tasks WHERE id = \\? AND deletedAt IS NOT NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"parentID", "deletedAt"}).AddRow(nil, deletedAt))
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) SELECT id FROM subtree WHERE deletedAt <=> \\?").
+		WithArgs(int64(1), deletedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec("UPDATE tasks SET deletedAt = NULL, deletedByID = NULL WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO activity").
+		WithArgs(int64(1), int64(3), ActivityTaskRestored, []byte(`{"task_ids":[1,2]}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectReindex(mock, task)
+	expectReindex(mock, &Task{ID: 2, Name: "Changelog", Status: "TODO", Priority: "P2", AssignedToID: 3})
+	mock.ExpectQuery("SELECT (.+) FROM tasks t WHERE t.id = \\? AND t.deletedAt IS NULL").
+		WithArgs(1).
+		WillReturnRows(taskRows(task))
+
+	got, err := store.RestoreTask(1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), got.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	before := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tasks WHERE deletedAt < \\? ORDER BY deletedAt, id LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(7))
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) SELECT DISTINCT a.storageKey FROM attachments a").
+		WithArgs(int64(4), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"storageKey"}).AddRow("ab/cdef"))
+	mock.ExpectExec("DELETE FROM tasks WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(int64(4), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	purged, keys, err := store.PurgeTrash(before, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, []string{"ab/cdef"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,24 +1,577 @@
 package common
 
-import "time"
+import (
+	"encoding/json"
+	"github.com/pkacprzak5/TaskManagementSystem/internal/search"
+	"time"
+)
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// Task can have several assignees. AssignedToID is the primary one and is
+// always among AssigneeIDs. Tasks in a project also have a Number within
+// the project and a Key such as API-123. A task closed as a duplicate
+// links to the task it was merged into through DuplicateOfID.
 type Task struct {
-	ID           int64     `json:"id" :"id"`
-	Name         string    `json:"name" :"name"`
-	Status       string    `json:"status" :"status"`
-	AssignedToID int64     `json:"assigned_to_id" :"assigned_to_id"`
-	CreatedAt    time.Time `json:"created_at" :"created_at"`
+	ID            int64          `json:"id"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	Status        string         `json:"status"`
+	Priority      string         `json:"priority"`
+	DueAt         *time.Time     `json:"due_at,omitempty"`
+	AssignedToID  int64          `json:"assigned_to_id"`
+	AssigneeIDs   []int64        `json:"assignee_ids"`
+	WorkspaceID   *int64         `json:"workspace_id,omitempty"`
+	ProjectID     *int64         `json:"project_id,omitempty"`
+	Number        *int64         `json:"number,omitempty"`
+	Key           string         `json:"key,omitempty"`
+	MilestoneID   *int64         `json:"milestone_id,omitempty"`
+	Points        *int           `json:"points,omitempty"`
+	DuplicateOfID *int64         `json:"duplicate_of_id,omitempty"`
+	ParentID      *int64         `json:"parent_id,omitempty"`
+	Position      int            `json:"position"`
+	Labels        []Label        `json:"labels,omitempty"`
+	CustomFields  map[string]any `json:"custom_fields,omitempty"`
+	Progress      *Progress      `json:"progress,omitempty"`
+	Checklist     *Progress      `json:"checklist,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	DoneAt        *time.Time     `json:"done_at,omitempty"`
+	ArchivedAt    *time.Time     `json:"archived_at,omitempty"`
+	DeletedAt     *time.Time     `json:"deleted_at,omitempty"`
+	DeletedByID   *int64         `json:"deleted_by_id,omitempty"`
+}
+
+// Progress rolls up a task's direct subtasks, or its checklist items. It is
+// only set on tasks that have some.
+type Progress struct {
+	Done    int `json:"done"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// ChecklistItem is a small to-do inside a task that does not need to be a
+// subtask of its own.
+type ChecklistItem struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	Text      string    `json:"text"`
+	Done      bool      `json:"done"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Dependency means BlockedID cannot start until BlockerID is DONE.
+type Dependency struct {
+	BlockerID int64 `json:"blocker_id"`
+	BlockedID int64 `json:"blocked_id"`
+}
+
+// DependencyGraph is everything that blocks a task, directly or through
+// other tasks. Blockers are in topological order: every task comes after
+// all tasks blocking it. Blocking lists the tasks the task directly blocks.
+type DependencyGraph struct {
+	TaskID   int64        `json:"task_id"`
+	Blockers []*Task      `json:"blockers"`
+	Edges    []Dependency `json:"edges"`
+	Blocking []*Task      `json:"blocking"`
+}
+
+// Recurrence repeats a task. The task's name, description, priority,
+// assignee and workspace are kept as the template of every occurrence.
+// TaskID is the current occurrence; the scheduler creates the next one once
+// it is DONE or past due, due at the next time RRULE gives after StartAt.
+type Recurrence struct {
+	ID           int64      `json:"id"`
+	TaskID       int64      `json:"task_id"`
+	Rule         string     `json:"rule"`
+	StartAt      time.Time  `json:"start_at"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Priority     string     `json:"priority"`
+	AssignedToID int64      `json:"assigned_to_id"`
+	WorkspaceID  *int64     `json:"workspace_id,omitempty"`
+	NextAt       *time.Time `json:"next_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+const (
+	JobReminder   = "reminder"
+	JobEscalation = "escalation"
+)
+
+// Job is a unit of background work kept in the database, so it survives
+// restarts. Enqueueing a job whose DedupeKey is already taken does nothing.
+type Job struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	RunAt     time.Time       `json:"run_at"`
+	Attempts  int             `json:"attempts"`
+	DedupeKey string          `json:"dedupe_key"`
+}
+
+// Worklog is time a user spent on a task, logged by hand or with a timer.
+// A running timer has no Seconds yet.
+type Worklog struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	UserID    int64      `json:"user_id"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Seconds   int64      `json:"seconds"`
+	Running   bool       `json:"running"`
+	Date      string     `json:"date"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+const (
+	TimeReportByTask = "task"
+	TimeReportByUser = "user"
+	TimeReportByTeam = "team"
+)
+
+// TimeReportFilter selects the logged time a report sums up: work done
+// between From and To, both dates inclusive, that ViewerID may see.
+type TimeReportFilter struct {
+	ViewerID int
+	From     time.Time
+	To       time.Time
+	GroupBy  string
+}
+
+// TimeReportRow is the time logged on one task, by one user or by one team.
+type TimeReportRow struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
+
+// TaskPatch lists the fields of a task to change; nil fields are kept.
+// ClearDueAt removes the due date and ClearPoints the estimate.
+// CustomFields sets the named custom fields to normalized values, see
+// NormalizeFieldValue, and a nil value clears a field.
+type TaskPatch struct {
+	Name         *string
+	Description  *string
+	Priority     *string
+	DueAt        *time.Time
+	ClearDueAt   bool
+	Points       *int
+	ClearPoints  bool
+	CustomFields map[string]any
+}
+
+// StatusUpdate holds the options of a status change. ActorID is the user
+// making the change. Force moves a task to DONE even while it has open
+// subtasks. To, when set, is the status the caller expects the task to
+// move to; tasks that would move anywhere else are left alone.
+type StatusUpdate struct {
+	ActorID int64
+	Force   bool
+	To      string
+}
+
+const (
+	BulkTransition = "transition"
+	BulkReassign   = "reassign"
+	BulkAddLabel   = "add_label"
+	BulkDelete     = "delete"
+	BulkMove       = "move"
+)
+
+// BulkAction is one change applied to every task of a batch. Status is
+// the status transitioned tasks must move to, UserID the new assignee,
+// LabelID the label to add, WorkspaceID and ProjectID where tasks move
+// to, and StatusMap the statuses moved tasks change; each is only used by
+// its action.
+type BulkAction struct {
+	Action      string
+	ActorID     int64
+	Force       bool
+	Status      string
+	UserID      int64
+	LabelID     int64
+	WorkspaceID int64
+	ProjectID   *int64
+	StatusMap   map[string]string
 }
 
 type User struct {
-	ID        int64     `json:"id" :"id"`
-	FirstName string    `json:"first_name" :"first_name"`
-	LastName  string    `json:"last_name" :"last_name"`
-	Email     string    `json:"email" :"email"`
-	Password  string    `json:"password" :"password"`
-	CreatedAt time.Time `json:"created_at" :"created_at"`
+	ID        int64     `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Password  string    `json:"password"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResult struct {
+	Task       *Task              `json:"task"`
+	Score      float64            `json:"score"`
+	Highlights []search.Highlight `json:"highlights"`
+}
+
+const (
+	TeamRoleMember = "MEMBER"
+	TeamRoleLead   = "LEAD"
+)
+
+type Team struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TeamMember struct {
+	TeamID int64  `json:"team_id"`
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// View is a saved task query. Views with a TeamID are shared with every
+// member of that team, Pinned is specific to the user who loaded the view.
+type View struct {
+	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"owner_id"`
+	TeamID    *int64    `json:"team_id,omitempty"`
+	Name      string    `json:"name"`
+	Filter    string    `json:"filter"`
+	Sort      string    `json:"sort"`
+	Columns   []string  `json:"columns"`
+	Pinned    bool      `json:"pinned"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	WorkspaceRoleMember = "MEMBER"
+	WorkspaceRoleAdmin  = "ADMIN"
+)
+
+// Workspace settings apply to every task in the workspace.
+// ChecklistBlocksDone keeps tasks with open checklist items from becoming
+// DONE.
+type Workspace struct {
+	ID                  int64     `json:"id"`
+	Name                string    `json:"name"`
+	ChecklistBlocksDone bool      `json:"checklist_blocks_done"`
+	AutoArchiveDays     int       `json:"auto_archive_days"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int64  `json:"workspace_id"`
+	UserID      int64  `json:"user_id"`
+	Role        string `json:"role"`
+}
+
+// Label belongs to a workspace and can be attached to any task of that
+// workspace. Tasks reference labels by id, so renaming a label is safe.
+type Label struct {
+	ID          int64  `json:"id"`
+	WorkspaceID int64  `json:"workspace_id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+}
+
+// Project groups tasks of a workspace. Key, such as API, is unique across
+// workspaces and prefixes the numbers of the project's tasks.
+type Project struct {
+	ID          int64          `json:"id"`
+	WorkspaceID int64          `json:"workspace_id"`
+	Key         string         `json:"key"`
+	Name        string         `json:"name"`
+	WIPLimits   map[string]int `json:"wip_limits,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Statuses lists the task statuses in workflow order, which is also the
+// order of the columns of a project board.
+var Statuses = []string{"TODO", "IN_PROGRESS", "IN_TESTING", "DONE"}
+
+// BoardMove places a task in a column of its project board. Status is the
+// column, and the task goes right after the task AfterID, or to the top of
+// the column when AfterID is nil.
+type BoardMove struct {
+	ActorID int64
+	Status  string
+	AfterID *int64
+}
+
+const (
+	SprintPlanned   = "PLANNED"
+	SprintActive    = "ACTIVE"
+	SprintCompleted = "COMPLETED"
+)
+
+// Sprint is a time box of a project. It is planned, then started, then
+// completed; tasks are added to it while it is planned or active, and a
+// task is in at most one active sprint. StartDate and EndDate are dates
+// such as 2025-03-03.
+type Sprint struct {
+	ID          int64      `json:"id"`
+	ProjectID   int64      `json:"project_id"`
+	Name        string     `json:"name"`
+	Goal        string     `json:"goal"`
+	StartDate   string     `json:"start_date"`
+	EndDate     string     `json:"end_date"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+const (
+	FieldText         = "text"
+	FieldNumber       = "number"
+	FieldDate         = "date"
+	FieldSingleSelect = "single_select"
+	FieldMultiSelect  = "multi_select"
+	FieldUser         = "user"
+)
+
+// FieldTypes are the types a custom field can have.
+var FieldTypes = []string{FieldText, FieldNumber, FieldDate, FieldSingleSelect, FieldMultiSelect, FieldUser}
+
+// CustomField is a field a project adds to its tasks. Options are the
+// allowed values of select fields. Tasks show their values by field Name
+// in custom_fields, and queries refer to them as cf.<name>.
+type CustomField struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	MilestoneOpen   = "OPEN"
+	MilestoneClosed = "CLOSED"
+)
+
+// Milestone is a release of a project that tasks of the project are linked
+// to. TargetDate is a date such as 2025-06-30. ReleaseNotes are written
+// when the milestone is closed.
+type Milestone struct {
+	ID           int64              `json:"id"`
+	ProjectID    int64              `json:"project_id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	TargetDate   string             `json:"target_date"`
+	Status       string             `json:"status"`
+	ClosedAt     *time.Time         `json:"closed_at,omitempty"`
+	ReleaseNotes string             `json:"release_notes,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Progress     *MilestoneProgress `json:"progress,omitempty"`
+}
+
+// MilestoneProgress counts the tasks of a milestone and their points.
+// Percent is by points when the tasks have points, by tasks otherwise, and
+// Throughput is how much of the same unit was finished per week over the
+// last four weeks. ProjectedCompletion is the date the remaining work
+// would be done at that pace; it is unset while nothing is being finished.
+type MilestoneProgress struct {
+	TasksDone           int     `json:"tasks_done"`
+	TasksTotal          int     `json:"tasks_total"`
+	PointsDone          int     `json:"points_done"`
+	PointsTotal         int     `json:"points_total"`
+	Percent             int     `json:"percent"`
+	Throughput          float64 `json:"throughput_per_week"`
+	ProjectedCompletion string  `json:"projected_completion,omitempty"`
+}
+
+// ReleaseNoteGroup lists the finished tasks of a milestone with one label.
+// Tasks without labels are grouped under an empty Label.
+type ReleaseNoteGroup struct {
+	Label string        `json:"label"`
+	Tasks []ReleaseNote `json:"tasks"`
+}
+
+type ReleaseNote struct {
+	ID   int64  `json:"id"`
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name"`
+}
+
+// Template describes a task tree that is created again and again, such as
+// the tasks of a release. Names, descriptions and checklist items may use
+// variables like {{version}}, filled in when the template is instantiated.
+type Template struct {
+	ID          int64        `json:"id"`
+	WorkspaceID int64        `json:"workspace_id"`
+	Name        string       `json:"name"`
+	Task        TemplateTask `json:"task"`
+	CreatedByID int64        `json:"created_by_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// TemplateTask is a task of a template. DueOffset is a duration such as
+// "-72h" relative to the due date given on instantiation.
+type TemplateTask struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	DueOffset   string         `json:"due_offset,omitempty"`
+	LabelIDs    []int64        `json:"label_ids,omitempty"`
+	Checklist   []string       `json:"checklist,omitempty"`
+	Subtasks    []TemplateTask `json:"subtasks,omitempty"`
+}
+
+// TaskTree is a task to create together with its labels, checklist and
+// subtasks.
+type TaskTree struct {
+	Task      *Task
+	LabelIDs  []int64
+	Checklist []string
+	Subtasks  []*TaskTree
+}
+
+// Height returns how many levels the tree spans.
+func (t *TaskTree) Height() int {
+	height := 0
+	for _, sub := range t.Subtasks {
+		height = max(height, sub.Height())
+	}
+	return height + 1
+}
+
+// CloneOptions selects what CloneTask copies besides the task itself.
+// CopyAttachment stores a copy of the attachment's content and returns its
+// storage key; without it attachments are not copied.
+type CloneOptions struct {
+	ActorID        int64
+	Name           string
+	Subtasks       bool
+	Checklist      bool
+	Labels         bool
+	CopyAttachment func(a *Attachment) (string, error)
+}
+
+// Comment is a Markdown message on a task. Replies always point at a
+// top-level comment, so threads are one level deep. Deleted comments keep
+// their place in the thread with an empty body.
+type Comment struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	AuthorID  int64      `json:"author_id"`
+	ParentID  *int64     `json:"parent_id,omitempty"`
+	Body      string     `json:"body"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	Replies   []*Comment `json:"replies,omitempty"`
+}
+
+// CommentRevision is a previous body of an edited or deleted comment.
+type CommentRevision struct {
+	ID         int64     `json:"id"`
+	CommentID  int64     `json:"comment_id"`
+	Body       string    `json:"body"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+const (
+	ActivityTaskCreated     = "task.created"
+	ActivityStatusChanged   = "status.changed"
+	ActivityTaskEdited      = "task.edited"
+	ActivityAssigneeAdded   = "assignee.added"
+	ActivityAssigneeRemoved = "assignee.removed"
+	ActivityLabelAdded      = "label.added"
+	ActivityLabelRemoved    = "label.removed"
+	ActivityTaskDeleted     = "task.deleted"
+	ActivityTaskRestored    = "task.restored"
+	ActivityTaskArchived    = "task.archived"
+	ActivityTaskUnarchived  = "task.unarchived"
+	ActivityTaskMoved       = "task.moved"
+	ActivityParentChanged   = "parent.changed"
+	ActivityTaskCloned      = "task.cloned"
+	ActivityTaskMerged      = "task.merged"
+	ActivityDuplicateMerged = "duplicate.merged"
+	ActivitySprintAdded     = "sprint.added"
+	ActivitySprintRemoved   = "sprint.removed"
+
+	ActivityWatcherAdded   = "watcher.added"
+	ActivityWatcherRemoved = "watcher.removed"
+
+	ActivityRecurrenceSet     = "recurrence.set"
+	ActivityRecurrenceRemoved = "recurrence.removed"
+
+	ActivityDependencyAdded   = "dependency.added"
+	ActivityDependencyRemoved = "dependency.removed"
+
+	ActivityChecklistAdded     = "checklist.added"
+	ActivityChecklistEdited    = "checklist.edited"
+	ActivityChecklistReordered = "checklist.reordered"
+	ActivityChecklistRemoved   = "checklist.removed"
+
+	ActivityCommentCreated = "comment.created"
+	ActivityCommentEdited  = "comment.edited"
+	ActivityCommentDeleted = "comment.deleted"
+
+	ActivityAttachmentAdded   = "attachment.added"
+	ActivityAttachmentDeleted = "attachment.deleted"
+)
+
+// Activity is an immutable entry of a task's activity feed. Details of
+// changes hold the values before and after, as {"from": ..., "to": ...}.
+// ActorID is nil for automatic changes.
+type Activity struct {
+	ID        int64           `json:"id"`
+	TaskID    int64           `json:"task_id"`
+	ActorID   *int64          `json:"actor_id,omitempty"`
+	Action    string          `json:"action"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+const (
+	MentionSourceTask    = "TASK"
+	MentionSourceComment = "COMMENT"
+)
+
+// Mention records that a user was @mentioned in a task description or in a
+// comment. CommentID is set for mentions in comments.
+type Mention struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	TaskID    int64     `json:"task_id"`
+	CommentID *int64    `json:"comment_id,omitempty"`
+	AuthorID  *int64    `json:"author_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	NotificationMention       = "mention"
+	NotificationStatusChanged = "status_changed"
+	NotificationComment       = "comment"
+	NotificationReminder      = "reminder"
+	NotificationOverdue       = "overdue"
+)
+
+type Notification struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	Type      string          `json:"type"`
+	TaskID    int64           `json:"task_id"`
+	ActorID   *int64          `json:"actor_id,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Attachment is the metadata of a file attached to a task. The content is
+// kept in blob storage under StorageKey. URL is a signed, expiring download
+// link filled in by the API.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	TaskID      int64     `json:"task_id"`
+	UploaderID  int64     `json:"uploader_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	URL         string    `json:"url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
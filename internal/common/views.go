@@ -0,0 +1,133 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const viewColumns = `v.id, v.ownerID, v.teamID, v.name, v.filter, v.sortBy, v.columns, v.createdAt, v.updatedAt,
+	p.userID IS NOT NULL`
+
+func scanView(row rowScanner) (*View, error) {
+	var v View
+	var teamID sql.NullInt64
+	var columns string
+	err := row.Scan(&v.ID, &v.OwnerID, &teamID, &v.Name, &v.Filter, &v.Sort, &columns, &v.CreatedAt, &v.UpdatedAt, &v.Pinned)
+	if err != nil {
+		return nil, err
+	}
+	if teamID.Valid {
+		v.TeamID = &teamID.Int64
+	}
+	v.Columns = []string{}
+	if columns != "" {
+		v.Columns = strings.Split(columns, ",")
+	}
+	return &v, nil
+}
+
+func (s *Storage) CreateView(v *View) (*View, error) {
+	res, err := s.db.Exec("INSERT INTO views (ownerID, teamID, name, filter, sortBy, columns) VALUES (?, ?, ?, ?, ?, ?)",
+		v.OwnerID, v.TeamID, v.Name, v.Filter, v.Sort, strings.Join(v.Columns, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create view: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	v.ID = id
+	v.CreatedAt = time.Now()
+	v.UpdatedAt = v.CreatedAt
+	return v, nil
+}
+
+// GetView loads the view with Pinned set for the given user. It does not
+// check whether that user may see the view.
+func (s *Storage) GetView(id, userID int) (*View, error) {
+	query := "SELECT " + viewColumns + " FROM views v LEFT JOIN view_pins p ON p.viewID = v.id AND p.userID = ? WHERE v.id = ?"
+	v, err := scanView(s.db.QueryRow(query, userID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetViewsForUser returns the user's own views and the views shared with
+// their teams, pinned views first.
+func (s *Storage) GetViewsForUser(userID int) ([]*View, error) {
+	query := "SELECT " + viewColumns + ` FROM views v
+		LEFT JOIN view_pins p ON p.viewID = v.id AND p.userID = ?
+		WHERE v.ownerID = ? OR v.teamID IN (SELECT teamID FROM team_members WHERE userID = ?)
+		ORDER BY p.userID IS NULL, v.name, v.id`
+
+	rows, err := s.db.Query(query, userID, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get views of user with id %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	views := []*View{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan view row: %w", err)
+		}
+		views = append(views, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return views, nil
+}
+
+func (s *Storage) UpdateView(v *View) error {
+	_, err := s.db.Exec(`
+		UPDATE views SET teamID = ?, name = ?, filter = ?, sortBy = ?, columns = ?, updatedAt = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		v.TeamID, v.Name, v.Filter, v.Sort, strings.Join(v.Columns, ","), v.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update view with id %d: %w", v.ID, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteView(id int) error {
+	res, err := s.db.Exec("DELETE FROM views WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete view with id %d: %w", id, err)
+	}
+	return requireAffected(res)
+}
+
+func (s *Storage) SetViewPinned(viewID, userID int, pinned bool) error {
+	var err error
+	if pinned {
+		_, err = s.db.Exec("INSERT IGNORE INTO view_pins (viewID, userID) VALUES (?, ?)", viewID, userID)
+	} else {
+		_, err = s.db.Exec("DELETE FROM view_pins WHERE viewID = ? AND userID = ?", viewID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to change pin of view with id %d: %w", viewID, err)
+	}
+	return nil
+}
+
+// requireAffected turns an UPDATE or DELETE that matched no rows into
+// ErrNotFound.
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCreateTeamAddsLead(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO teams").
+		WithArgs("Backend").
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectExec("INSERT INTO team_members").
+		WithArgs(int64(4), 7, TeamRoleLead).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	team, err := store.CreateTeam(&Team{Name: "Backend"}, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), team.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateView(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	teamID := int64(2)
+	view := &View{
+		OwnerID: 1,
+		TeamID:  &teamID,
+		Name:    "My open P1s",
+		Filter:  "assignee:me priority<=P1 -status:DONE",
+		Sort:    "due",
+		Columns: []string{"name", "due_at"},
+	}
+
+	mock.ExpectExec("INSERT INTO views").
+		WithArgs(int64(1), &teamID, view.Name, view.Filter, view.Sort, "name,due_at").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+
+	created, err := store.CreateView(view)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), created.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetViewsForUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	now := time.Now()
+	columns := []string{"id", "ownerID", "teamID", "name", "filter", "sortBy", "columns", "createdAt", "updatedAt", "pinned"}
+	mock.ExpectQuery("SELECT (.+) FROM views v LEFT JOIN view_pins p (.+) WHERE v.ownerID = \\? OR v.teamID IN").
+		WithArgs(1, 1, 1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 2, 5, "Needs review", "status:IN_TESTING", "", "", now, now, true).
+			AddRow(1, 1, nil, "Mine", "assignee:me", "-priority", "name,status", now, now, false))
+
+	views, err := store.GetViewsForUser(1)
+	assert.NoError(t, err)
+	assert.Len(t, views, 2)
+	assert.True(t, views[0].Pinned)
+	assert.Equal(t, int64(5), *views[0].TeamID)
+	assert.Equal(t, []string{}, views[0].Columns)
+	assert.Nil(t, views[1].TeamID)
+	assert.Equal(t, []string{"name", "status"}, views[1].Columns)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMissingView(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("DELETE FROM views WHERE id = ?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, store.DeleteView(3), ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
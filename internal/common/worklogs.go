@@ -0,0 +1,187 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const worklogColumns = "w.id, w.taskID, w.userID, w.startedAt, w.seconds, w.workDate, COALESCE(w.note, ''), w.createdAt"
+
+func scanWorklog(row rowScanner) (*Worklog, error) {
+	var w Worklog
+	var startedAt sql.NullTime
+	var seconds sql.NullInt64
+	var date time.Time
+	if err := row.Scan(&w.ID, &w.TaskID, &w.UserID, &startedAt, &seconds, &date, &w.Note, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		w.StartedAt = &startedAt.Time
+	}
+	w.Seconds = seconds.Int64
+	w.Running = !seconds.Valid
+	w.Date = date.Format(time.DateOnly)
+	return &w, nil
+}
+
+// StartTimer starts a timer for the user on the task. A user has at most
+// one running timer, which the unique runningUserID column enforces;
+// starting a second one returns ErrTimerRunning.
+func (s *Storage) StartTimer(taskID, userID int, now time.Time) (*Worklog, error) {
+	res, err := s.db.Exec(`INSERT INTO worklogs (taskID, userID, startedAt, workDate, runningUserID)
+		VALUES (?, ?, ?, ?, ?)`, taskID, userID, now, now.Format(time.DateOnly), userID)
+	if isDuplicateKey(err) {
+		return nil, ErrTimerRunning
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start timer on task %d: %w", taskID, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWorklog(int(id))
+}
+
+// StopTimer stops the user's running timer and logs the time since it
+// started.
+func (s *Storage) StopTimer(userID int, now time.Time) (*Worklog, error) {
+	var w *Worklog
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		w, err = scanWorklog(tx.QueryRow("SELECT "+worklogColumns+" FROM worklogs w WHERE w.runningUserID = ? FOR UPDATE", userID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get timer of user %d: %w", userID, err)
+		}
+
+		w.Seconds = max(int64(now.Sub(*w.StartedAt)/time.Second), 0)
+		w.Running = false
+		_, err = tx.Exec("UPDATE worklogs SET seconds = ?, runningUserID = NULL WHERE id = ?", w.Seconds, w.ID)
+		if err != nil {
+			return fmt.Errorf("failed to stop timer %d: %w", w.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *Storage) GetRunningTimer(userID int) (*Worklog, error) {
+	w, err := scanWorklog(s.db.QueryRow("SELECT "+worklogColumns+" FROM worklogs w WHERE w.runningUserID = ?", userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timer of user %d: %w", userID, err)
+	}
+	return w, nil
+}
+
+// CreateWorklog logs time spent without a timer.
+func (s *Storage) CreateWorklog(w *Worklog) (*Worklog, error) {
+	res, err := s.db.Exec("INSERT INTO worklogs (taskID, userID, seconds, workDate, note) VALUES (?, ?, ?, ?, ?)",
+		w.TaskID, w.UserID, w.Seconds, w.Date, w.Note)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log time on task %d: %w", w.TaskID, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWorklog(int(id))
+}
+
+func (s *Storage) GetWorklog(id int) (*Worklog, error) {
+	w, err := scanWorklog(s.db.QueryRow("SELECT "+worklogColumns+" FROM worklogs w WHERE w.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worklog %d: %w", id, err)
+	}
+	return w, nil
+}
+
+// GetWorklogs returns the time logged on the task, oldest first.
+func (s *Storage) GetWorklogs(taskID int) ([]*Worklog, error) {
+	rows, err := s.db.Query("SELECT "+worklogColumns+" FROM worklogs w WHERE w.taskID = ? ORDER BY w.workDate, w.id", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worklogs of task %d: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	worklogs := []*Worklog{}
+	for rows.Next() {
+		w, err := scanWorklog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan worklog row: %w", err)
+		}
+		worklogs = append(worklogs, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return worklogs, nil
+}
+
+func (s *Storage) DeleteWorklog(id int) error {
+	res, err := s.db.Exec("DELETE FROM worklogs WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete worklog %d: %w", id, err)
+	}
+	return requireAffected(res)
+}
+
+// timeReportGroups maps a TimeReportFilter.GroupBy to the id and name it
+// groups by and the joins they need.
+var timeReportGroups = map[string]struct{ id, name, join string }{
+	TimeReportByTask: {id: "t.id", name: "t.name"},
+	TimeReportByUser: {id: "u.id", name: "CONCAT(u.firstName, ' ', u.lastName)", join: "JOIN users u ON u.id = w.userID"},
+	TimeReportByTeam: {id: "tm.id", name: "tm.name",
+		join: "JOIN team_members m ON m.userID = w.userID JOIN teams tm ON tm.id = m.teamID"},
+}
+
+// GetTimeReport sums up finished worklogs by task, user or team. The viewer
+// sees their own time and the time logged on tasks of their workspaces.
+// Time of a user in several teams counts for each of them.
+func (s *Storage) GetTimeReport(filter TimeReportFilter) ([]*TimeReportRow, error) {
+	group, ok := timeReportGroups[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown time report grouping %q", filter.GroupBy)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+group.id+`, `+group.name+`, SUM(w.seconds)
+		FROM worklogs w JOIN tasks t ON t.id = w.taskID `+group.join+`
+		WHERE w.seconds IS NOT NULL AND t.deletedAt IS NULL AND w.workDate BETWEEN ? AND ?
+			AND (w.userID = ? OR t.workspaceID IN (SELECT workspaceID FROM workspace_members WHERE userID = ?))
+		GROUP BY `+group.id+`, `+group.name+`
+		ORDER BY `+group.id,
+		filter.From.Format(time.DateOnly), filter.To.Format(time.DateOnly), filter.ViewerID, filter.ViewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time report: %w", err)
+	}
+	defer rows.Close()
+
+	report := []*TimeReportRow{}
+	for rows.Next() {
+		var row TimeReportRow
+		if err := rows.Scan(&row.ID, &row.Name, &row.Seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan time report row: %w", err)
+		}
+		report = append(report, &row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var worklogColumnNames = []string{"id", "taskID", "userID", "startedAt", "seconds", "workDate", "note", "createdAt"}
+
+func TestStartTimerWhileRunning(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO worklogs").
+		WithArgs(1, 3, now, "2025-01-08", 3).
+		WillReturnError(&mysql.MySQLError{Number: mysqlDuplicateEntry})
+
+	_, err := store.StartTimer(1, 3, now)
+	assert.ErrorIs(t, err, ErrTimerRunning)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStopTimer(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+	started := time.Date(2025, 1, 8, 10, 15, 0, 0, time.UTC)
+	now := started.Add(95*time.Minute + 30*time.Second)
+	date := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM worklogs w WHERE w.runningUserID = \\? FOR UPDATE").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(worklogColumnNames).AddRow(7, 1, 3, started, nil, date, "", started))
+	mock.ExpectExec("UPDATE worklogs SET seconds = \\?, runningUserID = NULL WHERE id = ?").
+		WithArgs(int64(5730), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	worklog, err := store.StopTimer(3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5730), worklog.Seconds)
+	assert.False(t, worklog.Running)
+	assert.Equal(t, "2025-01-08", worklog.Date)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTimeReportByTeam(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT tm.id, tm.name, SUM\\(w.seconds\\) FROM worklogs w JOIN tasks t ON t.id = w.taskID JOIN team_members m").
+		WithArgs("2025-01-01", "2025-01-31", 3, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "seconds"}).AddRow(2, "Backend", 7200))
+
+	report, err := store.GetTimeReport(TimeReportFilter{
+		ViewerID: 3,
+		From:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+		GroupBy:  TimeReportByTeam,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*TimeReportRow{{ID: 2, Name: "Backend", Seconds: 7200}}, report)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateWorkspace creates the workspace and makes its creator an admin.
+func (s *Storage) CreateWorkspace(ws *Workspace, creatorID int) (*Workspace, error) {
+	err := s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("INSERT INTO workspaces (name, checklistBlocksDone, autoArchiveDays) VALUES (?, ?, ?)",
+			ws.Name, ws.ChecklistBlocksDone, ws.AutoArchiveDays)
+		if err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO workspace_members (workspaceID, userID, role) VALUES (?, ?, ?)",
+			id, creatorID, WorkspaceRoleAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to add workspace admin: %w", err)
+		}
+
+		ws.ID = id
+		ws.CreatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (s *Storage) GetWorkspace(id int) (*Workspace, error) {
+	var ws Workspace
+	err := s.db.QueryRow("SELECT id, name, checklistBlocksDone, autoArchiveDays, createdAt FROM workspaces WHERE id = ?", id).
+		Scan(&ws.ID, &ws.Name, &ws.ChecklistBlocksDone, &ws.AutoArchiveDays, &ws.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace with id %d: %w", id, err)
+	}
+	return &ws, nil
+}
+
+func (s *Storage) GetWorkspacesForUser(userID int) ([]*Workspace, error) {
+	rows, err := s.db.Query(`
+		SELECT w.id, w.name, w.checklistBlocksDone, w.autoArchiveDays, w.createdAt FROM workspaces w
+		JOIN workspace_members m ON m.workspaceID = w.id
+		WHERE m.userID = ? ORDER BY w.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspaces of user with id %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	workspaces := []*Workspace{}
+	for rows.Next() {
+		var ws Workspace
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.ChecklistBlocksDone, &ws.AutoArchiveDays, &ws.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace row: %w", err)
+		}
+		workspaces = append(workspaces, &ws)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return workspaces, nil
+}
+
+// UpdateWorkspace saves the name and settings of the workspace.
+func (s *Storage) UpdateWorkspace(ws *Workspace) error {
+	res, err := s.db.Exec("UPDATE workspaces SET name = ?, checklistBlocksDone = ?, autoArchiveDays = ? WHERE id = ?",
+		ws.Name, ws.ChecklistBlocksDone, ws.AutoArchiveDays, ws.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace with id %d: %w", ws.ID, err)
+	}
+	return requireAffected(res)
+}
+
+func (s *Storage) GetWorkspaceMember(workspaceID, userID int) (*WorkspaceMember, error) {
+	var m WorkspaceMember
+	err := s.db.QueryRow("SELECT workspaceID, userID, role FROM workspace_members WHERE workspaceID = ? AND userID = ?",
+		workspaceID, userID).Scan(&m.WorkspaceID, &m.UserID, &m.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *Storage) GetWorkspaceMembers(workspaceID int) ([]*WorkspaceMember, error) {
+	rows, err := s.db.Query("SELECT workspaceID, userID, role FROM workspace_members WHERE workspaceID = ? ORDER BY userID",
+		workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members of workspace with id %d: %w", workspaceID, err)
+	}
+	defer rows.Close()
+
+	members := []*WorkspaceMember{}
+	for rows.Next() {
+		var m WorkspaceMember
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member row: %w", err)
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return members, nil
+}
+
+// AddWorkspaceMember adds the user to the workspace, or changes their role
+// when they already are a member.
+func (s *Storage) AddWorkspaceMember(member *WorkspaceMember) error {
+	_, err := s.db.Exec(`
+		INSERT INTO workspace_members (workspaceID, userID, role) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`,
+		member.WorkspaceID, member.UserID, member.Role)
+	if err != nil {
+		return fmt.Errorf("failed to add member to workspace with id %d: %w", member.WorkspaceID, err)
+	}
+	return nil
+}
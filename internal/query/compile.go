@@ -0,0 +1,405 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Env carries what a query needs to be evaluated for a particular caller,
+// such as who "me" is and what "7d" is relative to.
+type Env struct {
+	UserID int64
+	Now    time.Time
+}
+
+// SQL holds WHERE and ORDER BY fragments over the tasks table aliased as
+// `t`. User input only ever reaches the database through Args, which holds
+// the arguments of Where followed by those of OrderBy.
+type SQL struct {
+	Where   string
+	Args    []any
+	OrderBy string
+}
+
+type fieldCompiler func(c Clause, env Env) (string, []any, error)
+
+var fields = map[string]fieldCompiler{
+	"":         compileText,
+	"name":     compileText,
+	"status":   compileStatus,
+	"assignee": compileUsers("assignee", "task_assignees"),
+	"watcher":  compileUsers("watcher", "task_watchers"),
+	"priority": compilePriority,
+	"due":      compileTime("t.dueAt"),
+	"created":  compileTime("t.createdAt"),
+	"label":    compileLabel,
+	"project":  compileProject,
+	"is":       compileIs,
+}
+
+var statuses = []string{"TODO", "IN_PROGRESS", "IN_TESTING", "DONE"}
+var priorities = []string{"P0", "P1", "P2", "P3"}
+
+// states are the values `is:` accepts.
+var states = map[string]string{
+	"archived": "t.archivedAt IS NOT NULL",
+}
+
+// customFieldPrefix starts the names of project custom fields, such as
+// cf.severity, in filters and sort orders.
+const customFieldPrefix = "cf."
+
+// Compile validates every clause and renders the query as SQL.
+func Compile(q *Query, env Env) (SQL, error) {
+	orderBy, orderArgs := compileSort(q.Sort)
+	if len(q.Clauses) == 0 {
+		return SQL{Where: "TRUE", Args: orderArgs, OrderBy: orderBy}, nil
+	}
+
+	var parts []string
+	var args []any
+	for _, c := range q.Clauses {
+		compile, ok := fields[c.Field]
+		if strings.HasPrefix(c.Field, customFieldPrefix) && len(c.Field) > len(customFieldPrefix) {
+			compile, ok = compileCustomField, true
+		}
+		if !ok {
+			return SQL{}, c.errorf("unknown field %q", c.Field)
+		}
+		where, clauseArgs, err := compile(c, env)
+		if err != nil {
+			return SQL{}, err
+		}
+		if c.Negate {
+			// NULL columns never satisfy a comparison, so a negated clause
+			// must treat them as false rather than unknown.
+			where = "NOT COALESCE((" + where + "), FALSE)"
+		} else {
+			where = "(" + where + ")"
+		}
+		parts = append(parts, where)
+		args = append(args, clauseArgs...)
+	}
+	return SQL{Where: strings.Join(parts, " AND "), Args: append(args, orderArgs...), OrderBy: orderBy}, nil
+}
+
+func (c Clause) errorf(format string, args ...any) *Error {
+	return errorAt(c.Pos-1, format, args...)
+}
+
+func (c Clause) requireOps(ops ...Op) error {
+	for _, op := range ops {
+		if c.Op == op {
+			return nil
+		}
+	}
+	name := c.Field
+	if name == "" {
+		name = "text"
+	}
+	return c.errorf("operator '%s' is not supported for %s", c.Op, name)
+}
+
+func anyOf(column string, values []any) string {
+	if len(values) == 1 {
+		return column + " = ?"
+	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")"
+}
+
+func negateIf(c Clause, where string) string {
+	if c.Op == OpNotEqual {
+		return "NOT (" + where + ")"
+	}
+	return where
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func compileText(c Clause, env Env) (string, []any, error) {
+	if err := c.requireOps(OpEqual); err != nil {
+		return "", nil, err
+	}
+	var parts []string
+	var args []any
+	for _, v := range c.Values {
+		parts = append(parts, "t.name LIKE ?")
+		args = append(args, "%"+likeEscaper.Replace(v)+"%")
+	}
+	return strings.Join(parts, " OR "), args, nil
+}
+
+func compileStatus(c Clause, env Env) (string, []any, error) {
+	if err := c.requireOps(OpEqual, OpNotEqual); err != nil {
+		return "", nil, err
+	}
+	var args []any
+	for _, v := range c.Values {
+		status, ok := oneOf(v, statuses)
+		if !ok {
+			return "", nil, c.errorf("unknown status %q, expected one of %s", v, strings.Join(statuses, ", "))
+		}
+		args = append(args, status)
+	}
+	return negateIf(c, anyOf("t.status", args)), args, nil
+}
+
+// compileUsers matches tasks linked to any of the given users through a
+// join table such as task_assignees.
+func compileUsers(field, table string) fieldCompiler {
+	return func(c Clause, env Env) (string, []any, error) {
+		if err := c.requireOps(OpEqual, OpNotEqual); err != nil {
+			return "", nil, err
+		}
+		var parts []string
+		var args []any
+		for _, v := range c.Values {
+			switch {
+			case strings.EqualFold(v, "me"):
+				parts = append(parts, "?")
+				args = append(args, env.UserID)
+			case strings.Contains(v, "@"):
+				parts = append(parts, "(SELECT id FROM users WHERE email = ?)")
+				args = append(args, v)
+			default:
+				id, err := strconv.ParseInt(v, 10, 64)
+				if err != nil || id <= 0 {
+					return "", nil, c.errorf("%s must be 'me', a user id or an email, got %q", field, v)
+				}
+				parts = append(parts, "?")
+				args = append(args, id)
+			}
+		}
+		where := "EXISTS (SELECT 1 FROM " + table + " u WHERE u.taskID = t.id AND u.userID IN (" +
+			strings.Join(parts, ", ") + "))"
+		return negateIf(c, where), args, nil
+	}
+}
+
+func compileLabel(c Clause, env Env) (string, []any, error) {
+	if err := c.requireOps(OpEqual); err != nil {
+		return "", nil, err
+	}
+	var args []any
+	for _, v := range c.Values {
+		args = append(args, v)
+	}
+	where := "EXISTS (SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.labelID WHERE tl.taskID = t.id AND " +
+		anyOf("l.name", args) + ")"
+	return where, args, nil
+}
+
+func compileProject(c Clause, env Env) (string, []any, error) {
+	if err := c.requireOps(OpEqual); err != nil {
+		return "", nil, err
+	}
+	var args []any
+	for _, v := range c.Values {
+		args = append(args, strings.ToUpper(v))
+	}
+	where := "EXISTS (SELECT 1 FROM projects p WHERE p.id = t.projectID AND " + anyOf("p.projectKey", args) + ")"
+	return where, args, nil
+}
+
+func compileIs(c Clause, env Env) (string, []any, error) {
+	if err := c.requireOps(OpEqual); err != nil {
+		return "", nil, err
+	}
+	var parts []string
+	for _, v := range c.Values {
+		where, ok := states[strings.ToLower(v)]
+		if !ok {
+			return "", nil, c.errorf("unknown state %q, expected archived", v)
+		}
+		parts = append(parts, where)
+	}
+	return strings.Join(parts, " OR "), nil, nil
+}
+
+func compilePriority(c Clause, env Env) (string, []any, error) {
+	var args []any
+	for _, v := range c.Values {
+		priority, ok := oneOf(v, priorities)
+		if !ok {
+			return "", nil, c.errorf("unknown priority %q, expected one of %s", v, strings.Join(priorities, ", "))
+		}
+		args = append(args, priority)
+	}
+
+	switch c.Op {
+	case OpEqual, OpNotEqual:
+		return negateIf(c, anyOf("t.priority", args)), args, nil
+	default:
+		// P0 is the most urgent, so comparing the labels as strings keeps
+		// "<=P1" meaning "P1 or more urgent".
+		return "t.priority " + string(c.Op) + " ?", args, nil
+	}
+}
+
+var relativeTime = regexp.MustCompile(`^([+-]?)(\d+)([hdw])$`)
+
+// parseTime accepts "today", a date such as 2024-05-01 or an offset from now
+// such as 7d, -12h or 2w. Dates and "today" cover a whole day.
+func parseTime(value string, now time.Time) (time.Time, bool, error) {
+	if strings.EqualFold(value, "today") {
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), true, nil
+	}
+
+	if m := relativeTime.FindStringSubmatch(strings.ToLower(value)); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		unit := map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[3]]
+		offset := time.Duration(n) * unit
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return now.Add(offset), false, nil
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return day, true, nil
+}
+
+func compileTime(column string) fieldCompiler {
+	return func(c Clause, env Env) (string, []any, error) {
+		if len(c.Values) == 1 && strings.EqualFold(c.Values[0], "none") {
+			switch c.Op {
+			case OpEqual:
+				return column + " IS NULL", nil, nil
+			case OpNotEqual:
+				return column + " IS NOT NULL", nil, nil
+			}
+		}
+
+		var parts []string
+		var args []any
+		for _, v := range c.Values {
+			at, wholeDay, err := parseTime(v, env.Now)
+			if err != nil {
+				return "", nil, c.errorf("invalid %s value %q, expected a date (2006-01-02), 'today', 'none' or an offset like 7d", c.Field, v)
+			}
+
+			from, to := at, at
+			if wholeDay {
+				to = at.AddDate(0, 0, 1)
+			}
+
+			switch c.Op {
+			case OpEqual, OpNotEqual:
+				if !wholeDay {
+					y, m, d := at.Date()
+					from = time.Date(y, m, d, 0, 0, 0, 0, at.Location())
+					to = from.AddDate(0, 0, 1)
+				}
+				parts = append(parts, "("+column+" >= ? AND "+column+" < ?)")
+				args = append(args, from, to)
+			case OpLess:
+				parts = append(parts, column+" < ?")
+				args = append(args, from)
+			case OpLessEqual:
+				if wholeDay {
+					parts = append(parts, column+" < ?")
+				} else {
+					parts = append(parts, column+" <= ?")
+				}
+				args = append(args, to)
+			case OpGreater:
+				if wholeDay {
+					parts = append(parts, column+" >= ?")
+				} else {
+					parts = append(parts, column+" > ?")
+				}
+				args = append(args, to)
+			case OpGreaterEqual:
+				parts = append(parts, column+" >= ?")
+				args = append(args, from)
+			}
+		}
+		return negateIf(c, strings.Join(parts, " OR ")), args, nil
+	}
+}
+
+// fieldValues selects the values of the named custom field on the task.
+// Each value fills one of the textValue, numberValue, dateValue and userID
+// columns, depending on the type of the field; multi-select fields have a
+// row per chosen option.
+const fieldValues = "FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ?"
+
+// compileCustomField matches tasks by the value of a project custom field.
+// The type of the field is not known here, so a value is compared with
+// every column it can be read as: "5" matches the text 5, the number 5 and
+// the user with id 5.
+func compileCustomField(c Clause, env Env) (string, []any, error) {
+	name := strings.TrimPrefix(c.Field, customFieldPrefix)
+	if len(c.Values) == 1 && strings.EqualFold(c.Values[0], "none") {
+		switch c.Op {
+		case OpEqual:
+			return "NOT EXISTS (SELECT 1 " + fieldValues + ")", []any{name}, nil
+		case OpNotEqual:
+			return "EXISTS (SELECT 1 " + fieldValues + ")", []any{name}, nil
+		}
+	}
+
+	var parts []string
+	args := []any{name}
+	for _, v := range c.Values {
+		switch c.Op {
+		case OpEqual, OpNotEqual:
+			parts = append(parts, "fv.textValue = ?")
+			args = append(args, v)
+			if number, err := strconv.ParseFloat(v, 64); err == nil {
+				parts = append(parts, "fv.numberValue = ?")
+				args = append(args, number)
+			}
+			if day, err := time.Parse(time.DateOnly, v); err == nil {
+				parts = append(parts, "fv.dateValue = ?")
+				args = append(args, day.Format(time.DateOnly))
+			}
+			switch {
+			case strings.EqualFold(v, "me"):
+				parts = append(parts, "fv.userID = ?")
+				args = append(args, env.UserID)
+			case strings.Contains(v, "@"):
+				parts = append(parts, "fv.userID = (SELECT id FROM users WHERE email = ?)")
+				args = append(args, v)
+			default:
+				if id, err := strconv.ParseInt(v, 10, 64); err == nil && id > 0 {
+					parts = append(parts, "fv.userID = ?")
+					args = append(args, id)
+				}
+			}
+		default:
+			// Only numbers and dates have an order.
+			if number, err := strconv.ParseFloat(v, 64); err == nil {
+				parts = append(parts, "fv.numberValue "+string(c.Op)+" ?")
+				args = append(args, number)
+				continue
+			}
+			at, _, err := parseTime(v, env.Now)
+			if err != nil {
+				return "", nil, c.errorf("%s%s needs a number or a date such as 2006-01-02, 'today' or 7d, got %q", c.Field, c.Op, v)
+			}
+			parts = append(parts, "fv.dateValue "+string(c.Op)+" ?")
+			args = append(args, at.Format(time.DateOnly))
+		}
+	}
+	where := "EXISTS (SELECT 1 " + fieldValues + " AND (" + strings.Join(parts, " OR ") + "))"
+	return negateIf(c, where), args, nil
+}
+
+func oneOf(value string, allowed []string) (string, bool) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return a, true
+		}
+	}
+	return "", false
+}
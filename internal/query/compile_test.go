@@ -0,0 +1,131 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func compile(t *testing.T, input string, env Env) (SQL, error) {
+	q, err := Parse(input)
+	assert.NoError(t, err)
+	return Compile(q, env)
+}
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	env := Env{UserID: 42, Now: now}
+
+	tests := []struct {
+		input string
+		where string
+		args  []any
+	}{
+		{
+			input: "",
+			where: "TRUE",
+		},
+		{
+			input: "status:IN_PROGRESS assignee:me priority<=P1",
+			where: "(t.status = ?) AND (EXISTS (SELECT 1 FROM task_assignees u WHERE u.taskID = t.id AND u.userID IN (?))) AND (t.priority <= ?)",
+			args:  []any{"IN_PROGRESS", int64(42), "P1"},
+		},
+		{
+			input: "status:todo,done -assignee:jane@example.com",
+			where: "(t.status IN (?, ?)) AND NOT COALESCE((EXISTS (SELECT 1 FROM task_assignees u WHERE u.taskID = t.id AND u.userID IN ((SELECT id FROM users WHERE email = ?)))), FALSE)",
+			args:  []any{"TODO", "DONE", "jane@example.com"},
+		},
+		{
+			input: "watcher:me,7",
+			where: "(EXISTS (SELECT 1 FROM task_watchers u WHERE u.taskID = t.id AND u.userID IN (?, ?)))",
+			args:  []any{int64(42), int64(7)},
+		},
+		{
+			input: "due<7d",
+			where: "(t.dueAt < ?)",
+			args:  []any{now.Add(7 * 24 * time.Hour)},
+		},
+		{
+			input: "due<=2024-05-01 due:none",
+			where: "(t.dueAt < ?) AND (t.dueAt IS NULL)",
+			args:  []any{time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			input: "created:today",
+			where: "((t.createdAt >= ? AND t.createdAt < ?))",
+			args:  []any{time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			input: `label:"backend" -label:wontfix,duplicate`,
+			where: "(EXISTS (SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.labelID WHERE tl.taskID = t.id AND l.name = ?)) AND " +
+				"NOT COALESCE((EXISTS (SELECT 1 FROM task_labels tl JOIN labels l ON l.id = tl.labelID WHERE tl.taskID = t.id AND l.name IN (?, ?))), FALSE)",
+			args: []any{"backend", "wontfix", "duplicate"},
+		},
+		{
+			input: "project:api,Web",
+			where: "(EXISTS (SELECT 1 FROM projects p WHERE p.id = t.projectID AND p.projectKey IN (?, ?)))",
+			args:  []any{"API", "WEB"},
+		},
+		{
+			input: "is:archived -is:ARCHIVED",
+			where: "(t.archivedAt IS NOT NULL) AND NOT COALESCE((t.archivedAt IS NOT NULL), FALSE)",
+		},
+		{
+			input: "cf.severity:high,2 -cf.customer:none",
+			where: "(EXISTS (SELECT 1 FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ? AND " +
+				"(fv.textValue = ? OR fv.textValue = ? OR fv.numberValue = ? OR fv.userID = ?))) AND " +
+				"NOT COALESCE((NOT EXISTS (SELECT 1 FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ?)), FALSE)",
+			args: []any{"severity", "high", "2", 2.0, int64(2), "customer"},
+		},
+		{
+			input: "cf.estimate>=3.5 cf.released<=2024-05-01",
+			where: "(EXISTS (SELECT 1 FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ? AND (fv.numberValue >= ?))) AND " +
+				"(EXISTS (SELECT 1 FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ? AND (fv.dateValue <= ?)))",
+			args: []any{"estimate", 3.5, "released", "2024-05-01"},
+		},
+		{
+			input: `"100%_done"`,
+			where: "(t.name LIKE ?)",
+			args:  []any{`%100\%\_done%`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sql, err := compile(t, tt.input, env)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.where, sql.Where)
+			assert.Equal(t, tt.args, sql.Args)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	env := Env{UserID: 1, Now: time.Now()}
+
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{input: "status:TODO colour:red", pos: 13, msg: `unknown field "colour"`},
+		{input: "status:BLOCKED", pos: 1, msg: `unknown status "BLOCKED", expected one of TODO, IN_PROGRESS, IN_TESTING, DONE`},
+		{input: "status<TODO", pos: 1, msg: "operator '<' is not supported for status"},
+		{input: "  priority:P9", pos: 3, msg: `unknown priority "P9", expected one of P0, P1, P2, P3`},
+		{input: "due<soon", pos: 1, msg: `invalid due value "soon", expected a date (2006-01-02), 'today', 'none' or an offset like 7d`},
+		{input: "assignee:someone", pos: 1, msg: `assignee must be 'me', a user id or an email, got "someone"`},
+		{input: "is:open", pos: 1, msg: `unknown state "open", expected archived`},
+		{input: "cf.severity>high", pos: 1, msg: `cf.severity> needs a number or a date such as 2006-01-02, 'today' or 7d, got "high"`},
+		{input: "cf.:x", pos: 1, msg: `unknown field "cf."`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := compile(t, tt.input, env)
+			var queryErr *Error
+			assert.ErrorAs(t, err, &queryErr)
+			assert.Equal(t, tt.pos, queryErr.Pos)
+			assert.Equal(t, tt.msg, queryErr.Msg)
+		})
+	}
+}
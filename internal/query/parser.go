@@ -0,0 +1,238 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Op string
+
+const (
+	OpEqual        Op = ":"
+	OpNotEqual     Op = "!="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+)
+
+// Query is a parsed task filter. All clauses must hold for a task to match.
+// Matching tasks are returned in Sort order, by id when Sort is empty.
+type Query struct {
+	Clauses []Clause
+	Sort    []Order
+}
+
+// Mentions reports whether a clause, negated or not, is field:value.
+func (q *Query) Mentions(field, value string) bool {
+	for _, c := range q.Clauses {
+		if c.Field != field || c.Op != OpEqual {
+			continue
+		}
+		for _, v := range c.Values {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Clause is a single condition such as `priority<=P1` or `-label:wontfix`.
+// Clauses without a field are free text matched against the task name.
+// A ':' clause may list several comma separated values, any of which match.
+type Clause struct {
+	Pos    int
+	Negate bool
+	Field  string
+	Op     Op
+	Values []string
+}
+
+// Error points at the offending position of the query, counted in
+// characters from 1.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse turns input like `status:IN_PROGRESS assignee:me due<7d` into a
+// Query. It only checks the syntax, fields and values are validated by
+// Compile.
+func Parse(input string) (*Query, error) {
+	p := &parser{input: []rune(input)}
+
+	q := &Query{}
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		clause, err := p.clause()
+		if err != nil {
+			return nil, err
+		}
+		q.Clauses = append(q.Clauses, clause)
+	}
+	return q, nil
+}
+
+type parser struct {
+	input []rune
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() rune {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) clause() (Clause, error) {
+	c := Clause{Pos: p.pos + 1}
+
+	if p.peek() == '-' {
+		c.Negate = true
+		p.pos++
+		if p.done() || unicode.IsSpace(p.peek()) {
+			return c, errorAt(p.pos, "expected a filter after '-'")
+		}
+	}
+
+	if p.peek() == '"' {
+		text, err := p.quoted()
+		if err != nil {
+			return c, err
+		}
+		c.Op = OpEqual
+		c.Values = []string{text}
+		return c, nil
+	}
+
+	start := p.pos
+	word := p.word()
+	op, ok := p.operator()
+	if !ok {
+		if word == "" {
+			return c, errorAt(p.pos, "unexpected character %q", p.peek())
+		}
+		c.Op = OpEqual
+		c.Values = []string{word}
+		return c, nil
+	}
+
+	if word == "" {
+		return c, errorAt(start, "expected a field name before '%s'", op)
+	}
+	c.Field = strings.ToLower(word)
+	c.Op = op
+
+	for {
+		valuePos := p.pos
+		var value string
+		var err error
+		if p.peek() == '"' {
+			value, err = p.quoted()
+			if err != nil {
+				return c, err
+			}
+		} else {
+			value = p.word()
+			if value == "" {
+				return c, errorAt(valuePos, "expected a value after '%s%s'", c.Field, op)
+			}
+		}
+		c.Values = append(c.Values, value)
+
+		if p.peek() != ',' {
+			break
+		}
+		if op != OpEqual {
+			return c, errorAt(p.pos, "only ':' accepts a list of values")
+		}
+		p.pos++
+	}
+
+	if !p.done() && !unicode.IsSpace(p.peek()) {
+		return c, errorAt(p.pos, "unexpected character %q", p.peek())
+	}
+	return c, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.@+/", r)
+}
+
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() && isWordRune(p.peek()) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *parser) operator() (Op, bool) {
+	switch p.peek() {
+	case ':':
+		p.pos++
+		return OpEqual, true
+	case '!':
+		if p.pos+1 < len(p.input) && p.input[p.pos+1] == '=' {
+			p.pos += 2
+			return OpNotEqual, true
+		}
+	case '<', '>':
+		op := string(p.peek())
+		p.pos++
+		if p.peek() == '=' {
+			op += "="
+			p.pos++
+		}
+		return Op(op), true
+	}
+	return "", false
+}
+
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for !p.done() {
+		r := p.peek()
+		p.pos++
+		switch r {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.done() {
+				return "", errorAt(p.pos, "unfinished escape sequence")
+			}
+			b.WriteRune(p.peek())
+			p.pos++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", errorAt(start, "unterminated quoted string")
+}
@@ -0,0 +1,52 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`status:IN_PROGRESS assignee:me priority<=P1 due<7d label:"backend" -label:wontfix login`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Clause{
+		{Pos: 1, Field: "status", Op: OpEqual, Values: []string{"IN_PROGRESS"}},
+		{Pos: 20, Field: "assignee", Op: OpEqual, Values: []string{"me"}},
+		{Pos: 32, Field: "priority", Op: OpLessEqual, Values: []string{"P1"}},
+		{Pos: 45, Field: "due", Op: OpLess, Values: []string{"7d"}},
+		{Pos: 52, Field: "label", Op: OpEqual, Values: []string{"backend"}},
+		{Pos: 68, Negate: true, Field: "label", Op: OpEqual, Values: []string{"wontfix"}},
+		{Pos: 83, Op: OpEqual, Values: []string{"login"}},
+	}, q.Clauses)
+}
+
+func TestParseListsAndQuotes(t *testing.T) {
+	q, err := Parse(`status:TODO,IN_TESTING "fix \"quoted\" bug"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TODO", "IN_TESTING"}, q.Clauses[0].Values)
+	assert.Equal(t, []string{`fix "quoted" bug`}, q.Clauses[1].Values)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{input: `status:`, pos: 8, msg: "expected a value after 'status:'"},
+		{input: `priority<=P1,P2`, pos: 13, msg: "only ':' accepts a list of values"},
+		{input: `label:"backend`, pos: 7, msg: "unterminated quoted string"},
+		{input: `due<7d )`, pos: 8, msg: `unexpected character ')'`},
+		{input: `:TODO`, pos: 1, msg: "expected a field name before ':'"},
+		{input: `status:TODO - x`, pos: 14, msg: "expected a filter after '-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var queryErr *Error
+			assert.ErrorAs(t, err, &queryErr)
+			assert.Equal(t, tt.pos, queryErr.Pos)
+			assert.Equal(t, tt.msg, queryErr.Msg)
+		})
+	}
+}
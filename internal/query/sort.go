@@ -0,0 +1,89 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Order is one sort key, for example "-priority" sorts by priority with the
+// least urgent tasks first.
+type Order struct {
+	Field      string
+	Descending bool
+}
+
+var sortColumns = map[string]string{
+	"id":       "t.id",
+	"name":     "t.name",
+	"status":   "t.status",
+	"priority": "t.priority",
+	"due":      "t.dueAt",
+	"created":  "t.createdAt",
+}
+
+// ParseSort reads a comma separated list of fields, each optionally prefixed
+// with '-' for descending order.
+func ParseSort(input string) ([]Order, error) {
+	var orders []Order
+	pos := 0
+	for _, part := range strings.Split(input, ",") {
+		field := strings.TrimSpace(part)
+		if field == "" {
+			if strings.TrimSpace(input) == "" {
+				return nil, nil
+			}
+			return nil, errorAt(pos, "empty sort field")
+		}
+
+		o := Order{Field: strings.ToLower(field)}
+		if strings.HasPrefix(o.Field, "-") {
+			o.Descending = true
+			o.Field = o.Field[1:]
+		}
+		_, ok := sortColumns[o.Field]
+		if strings.HasPrefix(o.Field, customFieldPrefix) && len(o.Field) > len(customFieldPrefix) {
+			ok = true
+		}
+		if !ok {
+			return nil, errorAt(pos, "cannot sort by %q", o.Field)
+		}
+		orders = append(orders, o)
+		pos += len(part) + 1
+	}
+	return orders, nil
+}
+
+// fieldSortColumns are the columns a custom field value can be in. Tasks
+// are sorted by each in turn, so values of the same type stay together.
+var fieldSortColumns = []string{"fv.numberValue", "fv.dateValue", "fv.textValue", "fv.userID"}
+
+func compileSort(orders []Order) (string, []any) {
+	var parts []string
+	var args []any
+	for _, o := range orders {
+		direction := "ASC"
+		if o.Descending {
+			direction = "DESC"
+		}
+		columns := []string{sortColumns[o.Field]}
+		if name, ok := strings.CutPrefix(o.Field, customFieldPrefix); ok {
+			// Multi-select fields sort by their first option, or by
+			// their last one in descending order.
+			aggregate := "MIN"
+			if o.Descending {
+				aggregate = "MAX"
+			}
+			columns = columns[:0]
+			for _, column := range fieldSortColumns {
+				columns = append(columns, "(SELECT "+aggregate+"("+column+") "+fieldValues+")")
+				args = append(args, name, name)
+			}
+		}
+		for _, column := range columns {
+			// Tasks without a value for the column always go last.
+			parts = append(parts, fmt.Sprintf("%s IS NULL, %s %s", column, column, direction))
+		}
+	}
+	parts = append(parts, "t.id ASC")
+	return strings.Join(parts, ", "), args
+}
@@ -0,0 +1,44 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	orders, err := ParseSort("-priority, due")
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{{Field: "priority", Descending: true}, {Field: "due"}}, orders)
+	orderBy, args := compileSort(orders)
+	assert.Equal(t, "t.priority IS NULL, t.priority DESC, t.dueAt IS NULL, t.dueAt ASC, t.id ASC", orderBy)
+	assert.Nil(t, args)
+
+	orders, err = ParseSort("")
+	assert.NoError(t, err)
+	assert.Nil(t, orders)
+	orderBy, _ = compileSort(orders)
+	assert.Equal(t, "t.id ASC", orderBy)
+
+	_, err = ParseSort("due,password")
+	var queryErr *Error
+	assert.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, 5, queryErr.Pos)
+	assert.Equal(t, `cannot sort by "password"`, queryErr.Msg)
+}
+
+func TestSortByCustomField(t *testing.T) {
+	orders, err := ParseSort("-cf.Severity")
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{{Field: "cf.severity", Descending: true}}, orders)
+
+	orderBy, args := compileSort(orders)
+	value := "(SELECT MAX(fv.numberValue) FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ?)"
+	assert.True(t, strings.HasPrefix(orderBy, value+" IS NULL, "+value+" DESC, "))
+	assert.True(t, strings.HasSuffix(orderBy, "fv.userID) FROM task_field_values fv JOIN custom_fields cf ON cf.id = fv.fieldID WHERE fv.taskID = t.id AND cf.name = ?) DESC, t.id ASC"))
+	assert.Len(t, args, 8)
+	assert.Equal(t, "severity", args[0])
+
+	_, err = ParseSort("cf.")
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+// Package rank implements lexicographic ranks: strings whose byte order is
+// the order of the items they rank. A rank between any two others can
+// always be found, so an item is moved by changing its own rank only.
+//
+// A rank is a six digit base-36 integer part followed by optional
+// fraction digits, none of which ends in 0. Items appended at either end
+// step the integer part; items placed between two others get the midpoint
+// of their neighbours, which adds fraction digits once the integer parts
+// are adjacent.
+package rank
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// width is the length of the integer part of a rank.
+const width = 6
+
+// step is the gap left between ranks appended at either end.
+const step = 36 * 36
+
+// limit is one past the largest integer part.
+const limit = 36 * 36 * 36 * 36 * 36 * 36
+
+var ErrInvalid = errors.New("invalid rank")
+var ErrOrder = errors.New("ranks are out of order")
+
+// Between returns a rank that sorts after before and ahead of after. An
+// empty before means the start of the list and an empty after its end, so
+// Between("", "") is the rank of the first item of an empty list.
+func Between(before, after string) (string, error) {
+	if before != "" && !valid(before) || after != "" && !valid(after) {
+		return "", ErrInvalid
+	}
+	if before != "" && after != "" && before >= after {
+		return "", ErrOrder
+	}
+
+	switch {
+	case before == "" && after == "":
+		return format(limit / 2), nil
+	case after == "":
+		if n := integer(before) + step; n < limit {
+			return format(n), nil
+		}
+	case before == "":
+		if n := integer(after) - step; n > 0 {
+			return format(n), nil
+		}
+	}
+	return pad(midpoint(before, after)), nil
+}
+
+func valid(r string) bool {
+	if len(r) < width {
+		return false
+	}
+	for i := 0; i < len(r); i++ {
+		if strings.IndexByte(digits, r[i]) < 0 {
+			return false
+		}
+	}
+	return len(r) == width || r[len(r)-1] != '0'
+}
+
+func integer(r string) int64 {
+	n, _ := strconv.ParseInt(r[:width], 36, 64)
+	return n
+}
+
+func format(n int64) string {
+	s := strconv.FormatInt(n, 36)
+	return strings.Repeat("0", width-len(s)) + s
+}
+
+// pad extends a midpoint shorter than the integer part with zeros, which
+// does not change where it sorts.
+func pad(r string) string {
+	if len(r) < width {
+		return r + strings.Repeat("0", width-len(r))
+	}
+	return r
+}
+
+// midpoint returns a digit string strictly between a and b, reading a
+// missing digit of a as 0 and an empty b as the end of the list. The
+// result never ends in 0, so there is always room after it.
+func midpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:])
+		}
+	}
+
+	da := strings.IndexByte(digits, digitAt(a, 0))
+	db := len(digits)
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db)/2])
+	}
+	// The first digit of b sorts before b, unless the rest of b is only
+	// the zeros that pad would add back.
+	if len(strings.TrimRight(b, "0")) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(suffix(a, 1), "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return '0'
+}
+
+func suffix(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
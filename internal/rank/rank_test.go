@@ -0,0 +1,80 @@
+package rank
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		before, after string
+		want          string
+	}{
+		{"", "", "i00000"},
+		{"i00000", "", "i00100"},
+		{"", "i00000", "hzzz00"},
+		{"i00000", "i00100", "i000i0"},
+		{"i00000", "i00001", "i00000i"},
+		{"i00000i", "i00001", "i00000r"},
+		{"i00000", "i00000i", "i000009"},
+		{"zzzzzz", "", "zzzzzzi"},
+		{"", "000001", "000000i"},
+	}
+	for _, test := range tests {
+		got, err := Between(test.before, test.after)
+		assert.NoError(t, err)
+		assert.Equal(t, test.want, got, "between %q and %q", test.before, test.after)
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	_, err := Between("i00001", "i00000")
+	assert.ErrorIs(t, err, ErrOrder)
+	_, err = Between("i00000", "i00000")
+	assert.ErrorIs(t, err, ErrOrder)
+	_, err = Between("i0000", "")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Between("", "I00000")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Between("i000000", "")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+// TestBetweenKeepsOrder places items at random positions in a list and
+// checks that the ranks always sort in list order.
+func TestBetweenKeepsOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var ranks []string
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(ranks) + 1)
+		var before, after string
+		if at > 0 {
+			before = ranks[at-1]
+		}
+		if at < len(ranks) {
+			after = ranks[at]
+		}
+		r, err := Between(before, after)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, valid(r), "rank %q", r)
+		ranks = slices.Insert(ranks, at, r)
+	}
+	assert.True(t, slices.IsSorted(ranks))
+	assert.Len(t, slices.Compact(slices.Clone(ranks)), len(ranks))
+}
+
+func TestBetweenSameGap(t *testing.T) {
+	before, after := "i00000", "i00001"
+	for i := 0; i < 100; i++ {
+		r, err := Between(before, after)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, before < r && r < after, "%q between %q and %q", r, before, after)
+		after = r
+	}
+}
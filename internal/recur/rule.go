@@ -0,0 +1,283 @@
+// Package recur implements the subset of RFC 5545 recurrence rules used by
+// recurring tasks: FREQ, INTERVAL, BYDAY (weekly rules), BYMONTHDAY
+// (monthly rules), COUNT and UNTIL.
+package recur
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how many periods Next walks through before it gives up
+// on a rule that no longer produces occurrences.
+const maxPeriods = 100000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule. The series it describes starts at the
+// start time passed to Next, which is always its first occurrence.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      time.Time
+}
+
+// Error describes why a rule was rejected.
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return "invalid recurrence rule: " + e.Msg
+}
+
+func errorf(format string, args ...any) *Error {
+	return &Error{Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse reads a rule such as `FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH`. An
+// optional `RRULE:` prefix is accepted.
+func Parse(input string) (*Rule, error) {
+	input = strings.TrimPrefix(strings.TrimSpace(input), "RRULE:")
+	if input == "" {
+		return nil, errorf("rule is empty")
+	}
+
+	r := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(input, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return nil, errorf("expected NAME=VALUE, got %q", part)
+		}
+		if seen[name] {
+			return nil, errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			if !slices.Contains([]Frequency{Daily, Weekly, Monthly, Yearly}, r.Freq) {
+				err = errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(name, value)
+		case "COUNT":
+			r.Count, err = positive(name, value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		default:
+			err = errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, errorf("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, errorf("COUNT and UNTIL cannot be combined")
+	case len(r.ByDay) > 0 && r.Freq != Weekly:
+		return nil, errorf("BYDAY is only supported with FREQ=WEEKLY")
+	case len(r.ByMonthDay) > 0 && r.Freq != Monthly:
+		return nil, errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return r, nil
+}
+
+func positive(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errorf("%s must be a positive number", name)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if len(value) == len("20060102") {
+				// A date includes the whole day.
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errorf("UNTIL must look like 20250131 or 20250131T170000Z")
+}
+
+func parseByDay(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range strings.Split(value, ",") {
+		day, ok := weekdays[name]
+		if !ok {
+			return nil, errorf("unknown BYDAY value %q", name)
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	// Weeks start on Monday.
+	slices.SortFunc(days, func(a, b time.Weekday) int {
+		return (int(a)+6)%7 - (int(b)+6)%7
+	})
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, part := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(part)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, errorf("BYMONTHDAY values must be between 1 and 31 or -31 and -1")
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// String formats the rule the way Parse reads it.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			names[i] = strings.ToUpper(day.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence of the series starting at start that
+// is strictly after after. It returns false once COUNT or UNTIL end the
+// series.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.occurrences(start, period) {
+			if t.Before(start) {
+				continue
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return time.Time{}, false
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// occurrences returns the candidate times of the given period of the
+// series, in order. Candidates before start are filtered by Next.
+func (r *Rule) occurrences(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+	hour, min, sec := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, start.Nanosecond(), start.Location())
+	}
+
+	switch r.Freq {
+	case Daily:
+		return []time.Time{start.AddDate(0, 0, step)}
+
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*step)}
+		}
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*step)
+		times := make([]time.Time, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			times = append(times, monday.AddDate(0, 0, (int(day)+6)%7))
+		}
+		return times
+
+	case Monthly:
+		first := at(start.Year(), start.Month()+time.Month(step), 1)
+		year, month := first.Year(), first.Month()
+		last := at(year, month+1, 0).Day()
+
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		var times []time.Time
+		for _, day := range days {
+			if day < 0 {
+				day = last + day + 1
+			}
+			// Months without the day are skipped, as in RFC 5545.
+			if day >= 1 && day <= last {
+				times = append(times, at(year, month, day))
+			}
+		}
+		slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+		return slices.CompactFunc(times, func(a, b time.Time) bool { return a.Equal(b) })
+
+	case Yearly:
+		t := at(start.Year()+step, start.Month(), start.Day())
+		if t.Month() != start.Month() {
+			// February 29th outside leap years.
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
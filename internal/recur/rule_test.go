@@ -0,0 +1,102 @@
+package recur
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,MO;COUNT=10")
+	assert.NoError(t, err)
+	assert.Equal(t, Weekly, r.Freq)
+	assert.Equal(t, 2, r.Interval)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, r.ByDay)
+	assert.Equal(t, 10, r.Count)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10", r.String())
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{input: "", msg: "rule is empty"},
+		{input: "INTERVAL=2", msg: "FREQ is required"},
+		{input: "FREQ=HOURLY", msg: `unsupported FREQ "HOURLY"`},
+		{input: "FREQ=DAILY;INTERVAL=0", msg: "INTERVAL must be a positive number"},
+		{input: "FREQ=DAILY;FREQ=WEEKLY", msg: "FREQ is given more than once"},
+		{input: "FREQ=DAILY;BYDAY=MO", msg: "BYDAY is only supported with FREQ=WEEKLY"},
+		{input: "FREQ=WEEKLY;BYDAY=XX", msg: `unknown BYDAY value "XX"`},
+		{input: "FREQ=MONTHLY;BYMONTHDAY=32", msg: "BYMONTHDAY values must be between 1 and 31 or -31 and -1"},
+		{input: "FREQ=DAILY;COUNT=3;UNTIL=20250101", msg: "COUNT and UNTIL cannot be combined"},
+		{input: "FREQ=DAILY;BYHOUR=9", msg: "unsupported part BYHOUR"},
+		{input: "FREQ", msg: `expected NAME=VALUE, got "FREQ"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var ruleErr *Error
+			if assert.ErrorAs(t, err, &ruleErr) {
+				assert.Equal(t, tt.msg, ruleErr.Msg)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		after string
+		want  string
+	}{
+		{name: "daily", rule: "FREQ=DAILY", start: "2025-01-06 09:00", after: "2025-01-06 09:00", want: "2025-01-07 09:00"},
+		{name: "every third day", rule: "FREQ=DAILY;INTERVAL=3", start: "2025-01-06 09:00", after: "2025-01-10 12:00", want: "2025-01-12 09:00"},
+		{name: "weekly on start day", rule: "FREQ=WEEKLY", start: "2025-01-08 09:00", after: "2025-01-08 09:00", want: "2025-01-15 09:00"},
+		{name: "weekly by day", rule: "FREQ=WEEKLY;BYDAY=MO,FR", start: "2025-01-08 09:00", after: "2025-01-08 09:00", want: "2025-01-10 09:00"},
+		{name: "biweekly wraps week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", start: "2025-01-08 09:00", after: "2025-01-10 09:00", want: "2025-01-20 09:00"},
+		{name: "monthly skips short months", rule: "FREQ=MONTHLY", start: "2025-01-31 09:00", after: "2025-01-31 09:00", want: "2025-03-31 09:00"},
+		{name: "last day of month", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", start: "2025-01-31 09:00", after: "2025-01-31 09:00", want: "2025-02-28 09:00"},
+		{name: "yearly leap day", rule: "FREQ=YEARLY", start: "2024-02-29 09:00", after: "2024-02-29 09:00", want: "2028-02-29 09:00"},
+		{name: "missed occurrences are skipped", rule: "FREQ=WEEKLY", start: "2025-01-06 09:00", after: "2025-02-01 00:00", want: "2025-02-03 09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			assert.NoError(t, err)
+			next, ok := r.Next(date(tt.start), date(tt.after))
+			assert.True(t, ok)
+			assert.Equal(t, date(tt.want), next)
+		})
+	}
+}
+
+func TestNextEndsSeries(t *testing.T) {
+	r, _ := Parse("FREQ=DAILY;COUNT=3")
+	start := date("2025-01-06 09:00")
+
+	next, ok := r.Next(start, date("2025-01-07 09:00"))
+	assert.True(t, ok)
+	assert.Equal(t, date("2025-01-08 09:00"), next)
+	_, ok = r.Next(start, next)
+	assert.False(t, ok)
+
+	r, _ = Parse("FREQ=WEEKLY;UNTIL=20250120")
+	next, ok = r.Next(start, date("2025-01-13 09:00"))
+	assert.True(t, ok)
+	assert.Equal(t, date("2025-01-20 09:00"), next)
+	_, ok = r.Next(start, next)
+	assert.False(t, ok)
+}
@@ -0,0 +1,53 @@
+package search
+
+import "sync"
+
+// MemoryIndex is an in-process Index meant for tests and local development.
+type MemoryIndex struct {
+	mu   sync.RWMutex
+	docs map[int64]Document
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: make(map[int64]Document)}
+}
+
+func (m *MemoryIndex) Index(doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs[doc.TaskID] = doc
+	return nil
+}
+
+func (m *MemoryIndex) Remove(taskID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.docs, taskID)
+	return nil
+}
+
+func (m *MemoryIndex) Search(q Query, limit int) ([]Hit, error) {
+	if q.empty() {
+		return nil, ErrEmptyQuery
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []Hit
+	for _, doc := range m.docs {
+		score := Score(doc, q)
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, Hit{TaskID: doc.TaskID, Score: score, Highlights: Highlights(doc, q)})
+	}
+
+	sortHits(hits)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
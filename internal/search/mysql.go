@@ -0,0 +1,110 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// commentSeparator is the ASCII record separator, which never shows up in
+// comment bodies typed by users.
+const commentSeparator = "\x1e"
+
+// MySQLIndex stores documents in the task_search table and relies on its
+// FULLTEXT indexes for matching. Scores and snippets are computed the same
+// way as in MemoryIndex so both implementations rank alike.
+type MySQLIndex struct {
+	db *sql.DB
+}
+
+func NewMySQLIndex(db *sql.DB) *MySQLIndex {
+	return &MySQLIndex{db: db}
+}
+
+func (m *MySQLIndex) Index(doc Document) error {
+	_, err := m.db.Exec(`
+		INSERT INTO task_search (taskID, name, description, comments) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE name = VALUES(name), description = VALUES(description), comments = VALUES(comments)`,
+		doc.TaskID, doc.Name, doc.Description, strings.Join(doc.Comments, commentSeparator))
+	if err != nil {
+		return fmt.Errorf("failed to index task %d: %w", doc.TaskID, err)
+	}
+	return nil
+}
+
+func (m *MySQLIndex) Remove(taskID int64) error {
+	_, err := m.db.Exec("DELETE FROM task_search WHERE taskID = ?", taskID)
+	if err != nil {
+		return fmt.Errorf("failed to remove task %d from index: %w", taskID, err)
+	}
+	return nil
+}
+
+func (m *MySQLIndex) Search(q Query, limit int) ([]Hit, error) {
+	if q.empty() {
+		return nil, ErrEmptyQuery
+	}
+
+	query := `
+		SELECT taskID, name, description, comments
+		FROM task_search
+		WHERE MATCH(name, description, comments) AGAINST (? IN BOOLEAN MODE)
+		ORDER BY MATCH(name, description, comments) AGAINST (? IN BOOLEAN MODE) DESC
+		LIMIT ?`
+
+	// FULLTEXT relevance only narrows the candidates, the final order comes
+	// from Score, so fetch more rows than requested.
+	if limit <= 0 {
+		limit = 20
+	}
+	boolean := BooleanMode(q)
+	rows, err := m.db.Query(query, boolean, boolean, limit*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []Hit
+	for rows.Next() {
+		var doc Document
+		var comments string
+		if err := rows.Scan(&doc.TaskID, &doc.Name, &doc.Description, &comments); err != nil {
+			return nil, fmt.Errorf("failed to scan search row: %w", err)
+		}
+		if comments != "" {
+			doc.Comments = strings.Split(comments, commentSeparator)
+		}
+
+		score := Score(doc, q)
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, Hit{TaskID: doc.TaskID, Score: score, Highlights: Highlights(doc, q)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	sortHits(hits)
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// BooleanMode renders the query as a MySQL boolean mode expression. Terms
+// only ever contain letters and digits, so no user input can inject
+// operators.
+func BooleanMode(q Query) string {
+	var parts []string
+	for _, t := range q.Terms {
+		parts = append(parts, "+"+t)
+	}
+	for _, p := range q.Prefixes {
+		parts = append(parts, "+"+p+"*")
+	}
+	for _, p := range q.Phrases {
+		parts = append(parts, `+"`+strings.Join(p, " ")+`"`)
+	}
+	return strings.Join(parts, " ")
+}
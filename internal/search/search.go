@@ -0,0 +1,291 @@
+package search
+
+import (
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Document is the searchable projection of a task.
+type Document struct {
+	TaskID      int64
+	Name        string
+	Description string
+	Comments    []string
+}
+
+// Highlight is a short fragment of a matched field with the matching terms
+// wrapped in <mark> tags. Everything outside the tags is HTML-escaped.
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+type Hit struct {
+	TaskID     int64       `json:"task_id"`
+	Score      float64     `json:"score"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Index keeps documents searchable. Implementations must treat Index as an
+// upsert so the Store can call it after every task write.
+type Index interface {
+	Index(doc Document) error
+	Remove(taskID int64) error
+	Search(q Query, limit int) ([]Hit, error)
+}
+
+// Query is a parsed search box input. Every term, prefix and phrase must
+// match somewhere in the document.
+type Query struct {
+	Terms    []string
+	Prefixes []string
+	Phrases  [][]string
+}
+
+// ParseQuery understands plain words, "quoted phrases" and prefix terms
+// ending with '*'.
+func ParseQuery(input string) (Query, error) {
+	var q Query
+
+	for len(input) > 0 {
+		input = strings.TrimLeftFunc(input, unicode.IsSpace)
+		if input == "" {
+			break
+		}
+
+		if input[0] == '"' {
+			end := strings.IndexByte(input[1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, input = input[1:], ""
+			} else {
+				phrase, input = input[1:end+1], input[end+2:]
+			}
+			words := tokens(phrase)
+			switch len(words) {
+			case 0:
+			case 1:
+				q.Terms = append(q.Terms, words[0].text)
+			default:
+				texts := make([]string, len(words))
+				for i, w := range words {
+					texts[i] = w.text
+				}
+				q.Phrases = append(q.Phrases, texts)
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(input, unicode.IsSpace)
+		var word string
+		if end < 0 {
+			word, input = input, ""
+		} else {
+			word, input = input[:end], input[end:]
+		}
+
+		prefix := strings.HasSuffix(word, "*")
+		words := tokens(word)
+		for i, w := range words {
+			if prefix && i == len(words)-1 {
+				q.Prefixes = append(q.Prefixes, w.text)
+			} else {
+				q.Terms = append(q.Terms, w.text)
+			}
+		}
+	}
+
+	if q.empty() {
+		return Query{}, ErrEmptyQuery
+	}
+	return q, nil
+}
+
+func (q Query) empty() bool {
+	return len(q.Terms) == 0 && len(q.Prefixes) == 0 && len(q.Phrases) == 0
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+func tokens(text string) []token {
+	var out []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			out = append(out, token{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, token{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return out
+}
+
+// matches reports, for every token of a field, whether it is part of a match
+// and how many times each query element matched.
+func (q Query) matches(words []token) ([]bool, int, bool) {
+	marked := make([]bool, len(words))
+	count := 0
+	all := true
+
+	for _, term := range q.Terms {
+		n := 0
+		for i, w := range words {
+			if w.text == term {
+				marked[i] = true
+				n++
+			}
+		}
+		count += n
+		all = all && n > 0
+	}
+
+	for _, prefix := range q.Prefixes {
+		n := 0
+		for i, w := range words {
+			if strings.HasPrefix(w.text, prefix) {
+				marked[i] = true
+				n++
+			}
+		}
+		count += n
+		all = all && n > 0
+	}
+
+	for _, phrase := range q.Phrases {
+		n := 0
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			found := true
+			for j, p := range phrase {
+				if words[i+j].text != p {
+					found = false
+					break
+				}
+			}
+			if found {
+				for j := range phrase {
+					marked[i+j] = true
+				}
+				n++
+			}
+		}
+		count += n
+		all = all && n > 0
+	}
+
+	return marked, count, all
+}
+
+type field struct {
+	name   string
+	weight float64
+	texts  []string
+}
+
+func (d Document) fields() []field {
+	return []field{
+		{name: "name", weight: 3, texts: []string{d.Name}},
+		{name: "description", weight: 2, texts: []string{d.Description}},
+		{name: "comments", weight: 1, texts: d.Comments},
+	}
+}
+
+// Score ranks a document against the query. Matches in the name weigh more
+// than matches in the description, which weigh more than comments. A zero
+// score means the document does not satisfy every element of the query.
+func Score(doc Document, q Query) float64 {
+	var score float64
+	var joined []token
+	for _, f := range doc.fields() {
+		for _, text := range f.texts {
+			words := tokens(text)
+			_, n, _ := q.matches(words)
+			score += f.weight * float64(n)
+			joined = append(joined, words...)
+			joined = append(joined, token{})
+		}
+	}
+
+	// Phrases must not span two fields, the empty separator token above
+	// makes sure of that.
+	if _, _, all := q.matches(joined); !all {
+		return 0
+	}
+	return score
+}
+
+const snippetContext = 8
+
+// Highlights returns one snippet per matching field, in field weight order.
+func Highlights(doc Document, q Query) []Highlight {
+	var out []Highlight
+	for _, f := range doc.fields() {
+		for _, text := range f.texts {
+			words := tokens(text)
+			marked, n, _ := q.matches(words)
+			if n == 0 {
+				continue
+			}
+			out = append(out, Highlight{Field: f.name, Snippet: snippet(text, words, marked)})
+			break
+		}
+	}
+	return out
+}
+
+func snippet(text string, words []token, marked []bool) string {
+	first := 0
+	for i, m := range marked {
+		if m {
+			first = i
+			break
+		}
+	}
+
+	from := max(first-snippetContext, 0)
+	to := min(first+snippetContext, len(words)-1)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := words[from].start
+	for i := from; i <= to; i++ {
+		w := words[i]
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		if marked[i] {
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(text[w.start:w.end]))
+			b.WriteString("</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[w.start:w.end]))
+		}
+		pos = w.end
+	}
+	if to < len(words)-1 {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func sortHits(hits []Hit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].TaskID < hits[j].TaskID
+	})
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`login "session timeout" auth* Bug`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"login", "bug"}, q.Terms)
+	assert.Equal(t, []string{"auth"}, q.Prefixes)
+	assert.Equal(t, [][]string{{"session", "timeout"}}, q.Phrases)
+
+	_, err = ParseQuery(`  "" * `)
+	assert.ErrorIs(t, err, ErrEmptyQuery)
+}
+
+func TestBooleanMode(t *testing.T) {
+	q, _ := ParseQuery(`login "session timeout" auth*`)
+	assert.Equal(t, `+login +auth* +"session timeout"`, BooleanMode(q))
+
+	q, _ = ParseQuery(`+evil -"injection" (attempt)`)
+	assert.Equal(t, `+evil +injection +attempt`, BooleanMode(q))
+}
+
+func TestMemoryIndexRanksByField(t *testing.T) {
+	index := NewMemoryIndex()
+	assert.NoError(t, index.Index(Document{TaskID: 1, Name: "Update docs", Description: "Mention the login flow"}))
+	assert.NoError(t, index.Index(Document{TaskID: 2, Name: "Fix login page"}))
+	assert.NoError(t, index.Index(Document{TaskID: 3, Name: "Refactor", Comments: []string{"login is slow"}}))
+	assert.NoError(t, index.Index(Document{TaskID: 4, Name: "Unrelated"}))
+
+	q, _ := ParseQuery("login")
+	hits, err := index.Search(q, 10)
+	assert.NoError(t, err)
+
+	var ids []int64
+	for _, hit := range hits {
+		ids = append(ids, hit.TaskID)
+	}
+	assert.Equal(t, []int64{2, 1, 3}, ids)
+}
+
+func TestMemoryIndexPhrasesAndPrefixes(t *testing.T) {
+	index := NewMemoryIndex()
+	index.Index(Document{TaskID: 1, Name: "Session timeout on login"})
+	index.Index(Document{TaskID: 2, Name: "Timeout of the session"})
+
+	q, _ := ParseQuery(`"session timeout"`)
+	hits, _ := index.Search(q, 10)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, int64(1), hits[0].TaskID)
+
+	q, _ = ParseQuery(`sess* timeout`)
+	hits, _ = index.Search(q, 10)
+	assert.Len(t, hits, 2)
+
+	index.Remove(1)
+	q, _ = ParseQuery(`"session timeout"`)
+	hits, _ = index.Search(q, 10)
+	assert.Empty(t, hits)
+}
+
+func TestPhraseDoesNotSpanFields(t *testing.T) {
+	doc := Document{TaskID: 1, Name: "Session", Description: "timeout"}
+	q, _ := ParseQuery(`"session timeout"`)
+	assert.Zero(t, Score(doc, q))
+}
+
+func TestHighlights(t *testing.T) {
+	doc := Document{
+		TaskID:      1,
+		Name:        "Fix <login> page",
+		Description: "one two three four five six seven eight nine ten eleven login twelve",
+	}
+	q, _ := ParseQuery("login")
+
+	highlights := Highlights(doc, q)
+	assert.Equal(t, []Highlight{
+		{Field: "name", Snippet: "Fix &lt;<mark>login</mark>&gt; page"},
+		{Field: "description", Snippet: "…four five six seven eight nine ten eleven <mark>login</mark> twelve"},
+	}, highlights)
+}